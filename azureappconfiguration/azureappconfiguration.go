@@ -17,17 +17,19 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"maps"
 	"net"
 	"net/http"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/Azure/AppConfiguration-GoProvider/azureappconfiguration/featureflags"
 	"github.com/Azure/AppConfiguration-GoProvider/azureappconfiguration/internal/jsonc"
 	"github.com/Azure/AppConfiguration-GoProvider/azureappconfiguration/internal/refresh"
 	"github.com/Azure/AppConfiguration-GoProvider/azureappconfiguration/internal/tracing"
@@ -35,22 +37,41 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/data/azappconfig/v2"
 	decoder "github.com/go-viper/mapstructure/v2"
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/sync/errgroup"
 )
 
 // An AzureAppConfiguration is a configuration provider that stores and manages settings sourced from Azure App Configuration.
 type AzureAppConfiguration struct {
-	// Settings loaded from Azure App Configuration
-	keyValues    map[string]any
-	featureFlags map[string]any
+	// Settings loaded from Azure App Configuration, which are served while a load or refresh replaces them.
+	// Each load or refresh builds new maps and slices and publishes them with settingsMu locked, so they are never modified once published.
+	settingsMu        sync.RWMutex // guards the loaded settings and the schema violations below
+	keyValues         map[string]any
+	featureFlags      map[string]any
+	featureFlagsByID  map[string]featureflags.FeatureFlag
+	featureFlagIDs    []string            // feature flag IDs in order of first occurrence
+	featureFlagLabels map[string]string   // labels of the settings which feature flags were loaded from, by feature ID
+	secretKeys        map[string]struct{} // keys resolved from Key Vault references or tagged as sensitive
+
+	// Schema violations of the loaded feature flags
+	featureFlagDiagnostics []FeatureFlagDiagnostic
+
+	// Values set at runtime which take precedence over the loaded settings
+	overrides overrideLayer
+
+	// Hierarchical defaults of the application which the loaded settings take precedence over
+	defaults map[string]any
 
 	// Settings configured from Options
 	kvSelectors          []Selector
 	ffEnabled            bool
 	ffSelectors          []Selector
+	ffValidationPolicy   FeatureFlagValidationPolicy
 	trimPrefixes         []string
 	watchedSettings      []WatchedSetting
 	loadBalancingEnabled bool
+	replicaSelector      ReplicaSelector
+	operationTimeouts    OperationTimeouts
 
 	// Settings used for refresh scenarios
 	sentinelETags          map[WatchedSetting]*azcore.ETag
@@ -62,16 +83,39 @@ type AzureAppConfiguration struct {
 	secretRefreshTimer     refresh.Condition
 	ffRefreshTimer         refresh.Condition
 	onRefreshSuccess       []func()
+	onRefreshSuccessMu     sync.Mutex
+	tracingMu              sync.RWMutex // guards tracingOptions and lastSuccessfulEndpoint
 	tracingOptions         tracing.Options
 	lastSuccessfulEndpoint string
+	lastUsedEndpoint       atomic.Pointer[string]
 
 	// Clients talking to Azure App Configuration/Azure Key Vault service
 	clientManager clientManager
 	resolver      *keyVaultReferenceResolver
 
+	// Evaluator of the loaded feature flags
+	evaluator *featureflags.Evaluator
+
+	// OpenTelemetry instrumentation, which is nil if not configured
+	telemetry *telemetry
+
+	// Logger of the internal messages, which is nil if the default logger of the slog package is used
+	logger *slog.Logger
+
 	refreshInProgress atomic.Bool
+
+	// Signals the completion of the first load started by LoadAsync, which is nil if the instance is created by Load
+	ready          chan struct{}
+	initialLoadErr error
 }
 
+// closedChannel is returned by Ready for an instance which has completed its first load in Load
+var closedChannel = func() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
+
 // Load initializes a new AzureAppConfiguration instance and loads the configuration data from
 // Azure App Configuration service.
 //
@@ -84,6 +128,62 @@ type AzureAppConfiguration struct {
 //   - A configured AzureAppConfiguration instance that provides access to the loaded configuration data
 //   - An error if the operation fails, such as authentication errors or connectivity issues
 func Load(ctx context.Context, authentication AuthenticationOptions, options *Options) (*AzureAppConfiguration, error) {
+	azappcfg, err := newAzureAppConfiguration(authentication, options)
+	if err != nil {
+		return nil, err
+	}
+
+	if options == nil {
+		options = &Options{}
+	}
+
+	if err := azappcfg.startup(ctx, options.StartupOptions, azappcfg.load); err != nil {
+		// The instance is not returned, so it can't be closed by the caller
+		_ = azappcfg.Close()
+		return nil, err
+	}
+	// Set the initial load finished flag
+	azappcfg.updateTracingOptions(func(tracingOptions *tracing.Options) {
+		tracingOptions.InitialLoadFinished = true
+	})
+
+	return azappcfg, nil
+}
+
+// LoadAsync initializes a new AzureAppConfiguration instance and starts loading the configuration data from
+// Azure App Configuration service in the background, e.g. so that an HTTP server can start listening for health probes
+// while Azure App Configuration is slow or unavailable.
+//
+// Until the first load succeeds, the instance serves the key-values in StartupOptions.InitialKeyValues, such as
+// the defaults of the application or the data cached from a previous run. The load is retried according to StartupOptions.
+// Once the first load succeeds, the loaded configuration replaces the initial key-values and the callbacks
+// registered with OnRefreshSuccess are executed as for a refresh. The instance is safe to read while the load is in progress. Refresh has no effect until the first load succeeds.
+//
+// Parameters:
+//   - ctx: The context for the background load, which stops retrying when the context is done
+//   - authentication: Authentication options for connecting to the Azure App Configuration service
+//   - options: Configuration options to customize behavior, such as key filters and prefix trimming
+//
+// Returns:
+//   - An AzureAppConfiguration instance whose Ready channel is closed when the first load completes
+//   - An error if the options are invalid
+func LoadAsync(ctx context.Context, authentication AuthenticationOptions, options *Options) (*AzureAppConfiguration, error) {
+	azappcfg, err := newAzureAppConfiguration(authentication, options)
+	if err != nil {
+		return nil, err
+	}
+
+	if options == nil {
+		options = &Options{}
+	}
+
+	azappcfg.startAsync(ctx, options.StartupOptions, azappcfg.load)
+
+	return azappcfg, nil
+}
+
+// newAzureAppConfiguration verifies the options and creates an AzureAppConfiguration instance which has not loaded any data yet
+func newAzureAppConfiguration(authentication AuthenticationOptions, options *Options) (*AzureAppConfiguration, error) {
 	if err := verifyAuthenticationOptions(authentication); err != nil {
 		return nil, err
 	}
@@ -96,18 +196,34 @@ func Load(ctx context.Context, authentication AuthenticationOptions, options *Op
 		options = &Options{}
 	}
 
+	defaults, err := normalizeDefaults(options.Defaults)
+	if err != nil {
+		return nil, fmt.Errorf("invalid defaults: %w", err)
+	}
+
 	clientManager, err := newConfigurationClientManager(authentication, options)
 	if err != nil {
 		return nil, err
 	}
 
+	telemetry, err := newTelemetry(options, clientManager.endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OpenTelemetry instruments: %w", err)
+	}
+	clientManager.telemetry = telemetry
+
 	azappcfg := new(AzureAppConfiguration)
 	azappcfg.tracingOptions = configureTracingOptions(options)
 	azappcfg.keyValues = make(map[string]any)
 	azappcfg.featureFlags = make(map[string]any)
+	azappcfg.defaults = defaults
+	azappcfg.operationTimeouts = options.OperationTimeouts
+	azappcfg.telemetry = telemetry
+	azappcfg.logger = options.Logger
 	azappcfg.kvSelectors = deduplicateSelectors(options.Selectors)
 	azappcfg.ffEnabled = options.FeatureFlagOptions.Enabled
 	azappcfg.loadBalancingEnabled = options.LoadBalancingEnabled
+	azappcfg.replicaSelector = options.ReplicaSelector
 
 	azappcfg.trimPrefixes = options.TrimKeyPrefixes
 	azappcfg.clientManager = clientManager
@@ -134,6 +250,19 @@ func Load(ctx context.Context, authentication AuthenticationOptions, options *Op
 	}
 
 	if azappcfg.ffEnabled {
+		evaluator, err := featureflags.NewEvaluator(&featureflags.EvaluatorOptions{
+			Filters:             options.FeatureFlagOptions.Filters,
+			MissingFilterPolicy: options.FeatureFlagOptions.MissingFilterPolicy,
+			TelemetryPublisher:  options.FeatureFlagOptions.TelemetryPublisher,
+			Logger:              options.Logger,
+		})
+		if err != nil {
+			_ = telemetry.close()
+			return nil, fmt.Errorf("invalid feature flag options: %w", err)
+		}
+
+		azappcfg.evaluator = evaluator
+		azappcfg.ffValidationPolicy = options.FeatureFlagOptions.ValidationPolicy
 		azappcfg.ffSelectors = getFeatureFlagSelectors(deduplicateSelectors(options.FeatureFlagOptions.Selectors))
 		if options.FeatureFlagOptions.RefreshOptions.Enabled {
 			azappcfg.ffRefreshTimer = refresh.NewTimer(options.FeatureFlagOptions.RefreshOptions.Interval)
@@ -141,12 +270,6 @@ func Load(ctx context.Context, authentication AuthenticationOptions, options *Op
 		}
 	}
 
-	if err := azappcfg.startupWithRetry(ctx, options.StartupOptions.Timeout, azappcfg.load); err != nil {
-		return nil, err
-	}
-	// Set the initial load finished flag
-	azappcfg.tracingOptions.InitialLoadFinished = true
-
 	return azappcfg, nil
 }
 
@@ -163,33 +286,12 @@ func Load(ctx context.Context, authentication AuthenticationOptions, options *Op
 // Returns:
 //   - An error if unmarshalling fails due to type conversion issues or invalid configuration
 func (azappcfg *AzureAppConfiguration) Unmarshal(v any, options *ConstructionOptions) error {
-	if options == nil || options.Separator == "" {
-		options = &ConstructionOptions{
-			Separator: defaultSeparator,
-		}
-	} else {
-		err := verifySeparator(options.Separator)
-		if err != nil {
-			return err
-		}
-	}
-
-	config := &decoder.DecoderConfig{
-		Result:           v,
-		WeaklyTypedInput: true,
-		TagName:          "json",
-		DecodeHook: decoder.ComposeDecodeHookFunc(
-			decoder.StringToTimeDurationHookFunc(),
-			decoder.StringToSliceHookFunc(","),
-		),
-	}
-
-	decoder, err := decoder.NewDecoder(config)
+	constructionOptions, err := normalizeConstructionOptions(options)
 	if err != nil {
 		return err
 	}
 
-	return decoder.Decode(azappcfg.constructHierarchicalMap(options.Separator))
+	return decodeHierarchicalMap(azappcfg.constructHierarchicalMap(constructionOptions), v)
 }
 
 // GetBytes returns the configuration as a JSON byte array with hierarchical structure.
@@ -202,18 +304,205 @@ func (azappcfg *AzureAppConfiguration) Unmarshal(v any, options *ConstructionOpt
 //   - A byte array containing the JSON representation of the configuration
 //   - An error if JSON marshalling fails or if an invalid separator is specified
 func (azappcfg *AzureAppConfiguration) GetBytes(options *ConstructionOptions) ([]byte, error) {
-	if options == nil || options.Separator == "" {
-		options = &ConstructionOptions{
-			Separator: defaultSeparator,
-		}
-	} else {
-		err := verifySeparator(options.Separator)
-		if err != nil {
-			return nil, err
-		}
+	constructionOptions, err := normalizeConstructionOptions(options)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(azappcfg.constructHierarchicalMap(constructionOptions))
+}
+
+// IsSecret reports whether the value of the specified key is a secret, either because it was resolved from
+// a Key Vault reference or because the setting is tagged with "sensitive=true".
+//
+// Parameters:
+//   - key: The key of the setting after any configured prefix trimming
+//
+// Returns:
+//   - true if the value of the key should be treated as a secret, false otherwise
+func (azappcfg *AzureAppConfiguration) IsSecret(key string) bool {
+	_, secretKeys := azappcfg.getLoadedKeyValues()
+	_, ok := secretKeys[key]
+	return ok
+}
+
+// GetSource reports which source the value of the specified key is taken from in Unmarshal and GetBytes,
+// e.g. to tell whether a key is missing from Azure App Configuration and its default value is used.
+// Runtime overrides take precedence over the loaded key-values, which take precedence over the defaults.
+//
+// Parameters:
+//   - key: The key of the setting after any configured prefix trimming
+//   - options: Optional parameters for controlling the construction, particularly the key separator the defaults are flattened with
+//
+// Returns:
+//   - The source of the value of the key, or an empty ValueSource if no source has a value for the key
+//   - An error if an invalid separator is specified
+func (azappcfg *AzureAppConfiguration) GetSource(key string, options *ConstructionOptions) (ValueSource, error) {
+	constructionOptions, err := normalizeConstructionOptions(options)
+	if err != nil {
+		return "", err
+	}
+
+	if source := azappcfg.getKeyValueSource(key); source != "" {
+		return source, nil
+	}
+
+	return azappcfg.getDefaultSource(key, constructionOptions.Separator), nil
+}
+
+// FeatureFlags returns the strongly typed feature flags loaded from Azure App Configuration.
+// The feature flags are deduplicated by ID, with later selectors taking precedence, and returned in
+// the order they were first loaded. Feature flags which are not valid JSON or cannot be decoded into
+// the feature flag schema are excluded, other invalid feature flags are handled according to
+// FeatureFlagOptions.ValidationPolicy.
+//
+// Returns:
+//   - The loaded feature flags, or an empty slice if feature flags are not enabled in FeatureFlagOptions
+func (azappcfg *AzureAppConfiguration) FeatureFlags() []featureflags.FeatureFlag {
+	azappcfg.settingsMu.RLock()
+	featureFlagsByID, featureFlagIDs := azappcfg.featureFlagsByID, azappcfg.featureFlagIDs
+	azappcfg.settingsMu.RUnlock()
+
+	result := make([]featureflags.FeatureFlag, 0, len(featureFlagIDs))
+	for _, id := range featureFlagIDs {
+		result = append(result, featureFlagsByID[id])
 	}
 
-	return json.Marshal(azappcfg.constructHierarchicalMap(options.Separator))
+	return result
+}
+
+// FeatureFlagLabel returns the label of the setting which the feature flag with the specified ID was loaded from.
+// When several selectors load a feature flag with the same ID, the feature flag from the last selector takes precedence.
+//
+// Parameters:
+//   - id: The ID of the feature flag
+//
+// Returns:
+//   - The label of the feature flag setting, or an empty string if the setting has no label
+//   - false if no feature flag with the specified ID was loaded
+func (azappcfg *AzureAppConfiguration) FeatureFlagLabel(id string) (string, bool) {
+	azappcfg.settingsMu.RLock()
+	defer azappcfg.settingsMu.RUnlock()
+
+	label, ok := azappcfg.featureFlagLabels[id]
+	return label, ok
+}
+
+// FeatureFlagDiagnostics returns the schema violations found in the feature flags of the latest successful load or refresh.
+//
+// Returns:
+//   - A diagnostic for each invalid feature flag setting, or an empty slice if all feature flags are valid
+func (azappcfg *AzureAppConfiguration) FeatureFlagDiagnostics() []FeatureFlagDiagnostic {
+	azappcfg.settingsMu.RLock()
+	defer azappcfg.settingsMu.RUnlock()
+
+	diagnostics := make([]FeatureFlagDiagnostic, len(azappcfg.featureFlagDiagnostics))
+	copy(diagnostics, azappcfg.featureFlagDiagnostics)
+	return diagnostics
+}
+
+// FeatureFlag returns the strongly typed feature flag with the specified ID.
+//
+// Parameters:
+//   - id: The ID of the feature flag
+//
+// Returns:
+//   - The feature flag with the specified ID
+//   - false if no valid feature flag with the specified ID was loaded
+func (azappcfg *AzureAppConfiguration) FeatureFlag(id string) (featureflags.FeatureFlag, bool) {
+	azappcfg.settingsMu.RLock()
+	defer azappcfg.settingsMu.RUnlock()
+
+	featureFlag, ok := azappcfg.featureFlagsByID[id]
+	return featureFlag, ok
+}
+
+// IsEnabled determines whether the loaded feature flag is enabled for the specified targeting context.
+// The built-in "Microsoft.Targeting", "Microsoft.TimeWindow" and "Microsoft.Percentage" filters are supported,
+// and targeting yields the same result as the other Microsoft feature management libraries.
+//
+// Parameters:
+//   - ctx: The context for the operation.
+//   - featureName: The ID of the feature flag to evaluate
+//   - targetingContext: The user and groups the feature flag is evaluated for
+//
+// Returns:
+//   - true if the feature flag is enabled, false if it is disabled or was not loaded
+//   - An error if a client filter of the feature flag is unknown or has invalid parameters
+func (azappcfg *AzureAppConfiguration) IsEnabled(ctx context.Context, featureName string, targetingContext featureflags.TargetingContext) (bool, error) {
+	return azappcfg.IsEnabledWithAppContext(ctx, featureName, targetingContext)
+}
+
+// IsEnabledWithAppContext determines whether the loaded feature flag is enabled for the specified application context.
+// A feature flag overridden with OverrideFeature is enabled or disabled as overridden, without evaluating its filters.
+// The application context is passed as is to the custom filters registered in FeatureFlagOptions.Filters.
+// The built-in filters use the featureflags.TargetingContext of the application context, if it is
+// a featureflags.TargetingContext or implements featureflags.TargetingContextAccessor.
+//
+// Parameters:
+//   - ctx: The context for the operation.
+//   - featureName: The ID of the feature flag to evaluate
+//   - appContext: The application context the feature flag is evaluated for
+//
+// Returns:
+//   - true if the feature flag is enabled, false if it is disabled or was not loaded
+//   - An error if a client filter of the feature flag is not registered or fails to evaluate
+func (azappcfg *AzureAppConfiguration) IsEnabledWithAppContext(ctx context.Context, featureName string, appContext any) (bool, error) {
+	if enabled, ok := azappcfg.overrides.getFeature(featureName); ok {
+		return enabled, nil
+	}
+
+	featureFlag, ok := azappcfg.FeatureFlag(featureName)
+	if !ok {
+		return false, nil
+	}
+
+	return azappcfg.getEvaluator().IsEnabledWithAppContext(ctx, featureFlag, appContext)
+}
+
+// GetVariant assigns a variant of the loaded feature flag to the specified targeting context.
+// Variants are allocated by user, group and seeded percentile in the same way as the other Microsoft
+// feature management libraries, and the reason of the assignment is reported in Variant.Reason.
+// Use Variant.DecodeConfigurationValue to bind the configuration value of the variant to a struct.
+//
+// Parameters:
+//   - ctx: The context for the operation.
+//   - featureName: The ID of the feature flag to evaluate
+//   - targetingContext: The user and groups the variant is assigned for
+//
+// Returns:
+//   - The assigned variant, or nil if no variant is assigned or the feature flag was not loaded
+//   - An error if a client filter of the feature flag is unknown or has invalid parameters
+func (azappcfg *AzureAppConfiguration) GetVariant(ctx context.Context, featureName string, targetingContext featureflags.TargetingContext) (*featureflags.Variant, error) {
+	return azappcfg.GetVariantWithAppContext(ctx, featureName, targetingContext)
+}
+
+// GetVariantWithAppContext assigns a variant of the loaded feature flag to the specified application context.
+// See IsEnabledWithAppContext for how the application context is used. A feature flag overridden with OverrideFeature
+// is assigned the default variant of its overridden state. Unlike IsEnabledWithAppContext, an overridden feature flag
+// which was not loaded has no variants, so no variant is assigned.
+//
+// Parameters:
+//   - ctx: The context for the operation.
+//   - featureName: The ID of the feature flag to evaluate
+//   - appContext: The application context the variant is assigned for
+//
+// Returns:
+//   - The assigned variant, or nil if no variant is assigned or the feature flag was not loaded
+//   - An error if a client filter of the feature flag is not registered or fails to evaluate
+func (azappcfg *AzureAppConfiguration) GetVariantWithAppContext(ctx context.Context, featureName string, appContext any) (*featureflags.Variant, error) {
+	featureFlag, ok := azappcfg.FeatureFlag(featureName)
+	if !ok {
+		return nil, nil
+	}
+
+	// An overridden feature flag is enabled or disabled regardless of its client filters
+	if enabled, ok := azappcfg.overrides.getFeature(featureName); ok {
+		featureFlag.Enabled = enabled
+		featureFlag.Conditions = nil
+	}
+
+	return azappcfg.getEvaluator().GetVariantWithAppContext(ctx, featureFlag, appContext)
 }
 
 // Refresh manually triggers a refresh of the configuration from Azure App Configuration.
@@ -232,10 +521,20 @@ func (azappcfg *AzureAppConfiguration) GetBytes(options *ConstructionOptions) ([
 // Returns:
 //   - An error if refresh is not configured, or if the refresh operation fails
 func (azappcfg *AzureAppConfiguration) Refresh(ctx context.Context) error {
-	if azappcfg.kvRefreshTimer == nil && azappcfg.secretRefreshTimer == nil && azappcfg.ffRefreshTimer == nil {
+	if !azappcfg.isRefreshConfigured() {
 		return fmt.Errorf("refresh is not configured for key values, Key Vault secrets, or feature flags")
 	}
 
+	// Refresh has no effect until the first load started by LoadAsync succeeds
+	select {
+	case <-azappcfg.Ready():
+		if azappcfg.initialLoadErr != nil {
+			return fmt.Errorf("failed to load configuration on startup: %w", azappcfg.initialLoadErr)
+		}
+	default:
+		return nil
+	}
+
 	// Try to set refreshInProgress to true, returning false if it was already true
 	if !azappcfg.refreshInProgress.CompareAndSwap(false, true) {
 		return nil // Another refresh is already in progress
@@ -244,21 +543,53 @@ func (azappcfg *AzureAppConfiguration) Refresh(ctx context.Context) error {
 	// Reset the flag when we're done
 	defer azappcfg.refreshInProgress.Store(false)
 
+	ctx, cancel := withOptionalTimeout(ctx, azappcfg.operationTimeouts.Refresh)
+	defer cancel()
+
+	ctx, span := azappcfg.telemetry.startSpan(ctx, refreshSpanName)
+	previousKeyValues, _ := azappcfg.getLoadedKeyValues()
+	refreshed, err := azappcfg.refresh(ctx)
+	endSpan(span, err)
+	if err != nil {
+		azappcfg.telemetry.recordRefresh(ctx, outcomeFailed, 0)
+		return err
+	}
+
+	// Only execute callbacks if actual changes were applied
+	if !refreshed {
+		azappcfg.telemetry.recordRefresh(ctx, outcomeUnchanged, 0)
+		return nil
+	}
+
+	currentKeyValues, _ := azappcfg.getLoadedKeyValues()
+	azappcfg.telemetry.recordRefresh(ctx, outcomeChanged, countChangedKeys(previousKeyValues, currentKeyValues))
+	azappcfg.executeRefreshCallbacks()
+
+	return nil
+}
+
+// refresh refreshes the key-values, feature flags and Key Vault secrets whose refresh interval has elapsed,
+// and returns whether any of them changed
+func (azappcfg *AzureAppConfiguration) refresh(ctx context.Context) (bool, error) {
 	var keyValueRefreshed, featureFlagRefreshed bool
 	var err error
-	refreshTask := func(client *azappconfig.Client) error {
+	refreshTask := func(ctx context.Context, client *azappconfig.Client) error {
 		eg, egCtx := errgroup.WithContext(ctx)
 		eg.Go(func() error {
-			if keyValueRefreshed, err = azappcfg.refreshKeyValues(egCtx, azappcfg.newKeyValueRefreshClient(client)); err != nil {
+			refreshed, err := azappcfg.refreshKeyValues(egCtx, azappcfg.newKeyValueRefreshClient(client))
+			if err != nil {
 				return fmt.Errorf("failed to refresh key values: %w", err)
 			}
+			keyValueRefreshed = refreshed
 			return nil
 		})
 
 		eg.Go(func() error {
-			if featureFlagRefreshed, err = azappcfg.refreshFeatureFlags(egCtx, azappcfg.newFeatureFlagRefreshClient(client)); err != nil {
+			refreshed, err := azappcfg.refreshFeatureFlags(egCtx, azappcfg.newFeatureFlagRefreshClient(client))
+			if err != nil {
 				return fmt.Errorf("failed to refresh feature flags: %w", err)
 			}
+			featureFlagRefreshed = refreshed
 			return nil
 		})
 
@@ -266,7 +597,7 @@ func (azappcfg *AzureAppConfiguration) Refresh(ctx context.Context) error {
 	}
 
 	if err := azappcfg.executeFailoverPolicy(ctx, refreshTask); err != nil {
-		return fmt.Errorf("failed to refresh configuration: %w", err)
+		return false, fmt.Errorf("failed to refresh configuration: %w", err)
 	}
 
 	// Attempt to reload Key Vault secrets and check if any values were actually updated
@@ -275,20 +606,11 @@ func (azappcfg *AzureAppConfiguration) Refresh(ctx context.Context) error {
 	if !keyValueRefreshed {
 		secretRefreshed, err = azappcfg.refreshKeyVaultSecrets(ctx)
 		if err != nil {
-			return fmt.Errorf("failed to reload Key Vault secrets: %w", err)
-		}
-	}
-
-	// Only execute callbacks if actual changes were applied
-	if keyValueRefreshed || secretRefreshed || featureFlagRefreshed {
-		for _, callback := range azappcfg.onRefreshSuccess {
-			if callback != nil {
-				callback()
-			}
+			return false, fmt.Errorf("failed to reload Key Vault secrets: %w", err)
 		}
 	}
 
-	return nil
+	return keyValueRefreshed || secretRefreshed || featureFlagRefreshed, nil
 }
 
 // OnRefreshSuccess registers a callback function that will be executed whenever the configuration
@@ -305,17 +627,159 @@ func (azappcfg *AzureAppConfiguration) OnRefreshSuccess(callback func()) {
 		return
 	}
 
+	azappcfg.onRefreshSuccessMu.Lock()
+	defer azappcfg.onRefreshSuccessMu.Unlock()
 	azappcfg.onRefreshSuccess = append(azappcfg.onRefreshSuccess, callback)
 }
 
+// Ready returns a channel which is closed when the first load started by LoadAsync completes, whether it succeeded or not.
+// For an instance created by Load, the returned channel is already closed.
+//
+// Returns:
+//   - A channel which is closed when the first load completes
+func (azappcfg *AzureAppConfiguration) Ready() <-chan struct{} {
+	if azappcfg.ready == nil {
+		return closedChannel
+	}
+
+	return azappcfg.ready
+}
+
+// Err returns the error of the first load started by LoadAsync once the Ready channel is closed.
+//
+// Returns:
+//   - The error which the first load failed with, or nil if the first load succeeded or has not completed yet
+func (azappcfg *AzureAppConfiguration) Err() error {
+	select {
+	case <-azappcfg.Ready():
+		return azappcfg.initialLoadErr
+	default:
+		return nil
+	}
+}
+
+// SetOverride sets the value of a key at runtime without writing to Azure App Configuration, e.g. to change
+// a timeout during an incident or to force a value in tests. The override takes precedence over the loaded
+// key-value with the same key and over the loaded key-values it overlaps, e.g. a key nested in a loaded JSON object,
+// is kept across refreshes and is visible in Unmarshal and GetBytes.
+//
+// Parameters:
+//   - key: The key to override, after any prefixes in TrimKeyPrefixes are trimmed
+//   - value: The value of the key, which can be any value accepted by Unmarshal and GetBytes
+//   - options: Optional parameters of the override, e.g. how long the override is in effect
+func (azappcfg *AzureAppConfiguration) SetOverride(key string, value any, options *OverrideOptions) {
+	azappcfg.overrides.setKeyValue(key, value, options)
+}
+
+// OverrideFeature enables or disables a feature flag at runtime without writing to Azure App Configuration.
+// The overridden feature flag is enabled or disabled regardless of its client filters in IsEnabled and GetVariant,
+// and in the "feature_management" section of Unmarshal and GetBytes. The override is kept across refreshes.
+// A feature flag which was not loaded can be overridden for IsEnabled, but GetVariant assigns no variant to it.
+//
+// Parameters:
+//   - id: The ID of the feature flag to override
+//   - enabled: Whether the feature flag is enabled
+//   - options: Optional parameters of the override, e.g. how long the override is in effect
+func (azappcfg *AzureAppConfiguration) OverrideFeature(id string, enabled bool, options *OverrideOptions) {
+	azappcfg.overrides.setFeature(id, enabled, options)
+}
+
+// ClearOverrides removes all the overrides set with SetOverride and OverrideFeature,
+// so that the configuration loaded from Azure App Configuration is used again.
+func (azappcfg *AzureAppConfiguration) ClearOverrides() {
+	azappcfg.overrides.clear()
+}
+
+// ReplicaStatus returns the status of every known endpoint of the Azure App Configuration store, including the origin
+// endpoint and the replicas discovered via DNS SRV records, and the endpoint which served the most recent load or refresh.
+// It can be used to expose the health of the replicas, e.g. through a debug endpoint.
+//
+// Returns:
+//   - The backoff state, consecutive failures, last success time and last error of each endpoint
+func (azappcfg *AzureAppConfiguration) ReplicaStatus() ReplicaStatus {
+	status := ReplicaStatus{
+		Replicas: make([]ReplicaEndpointStatus, 0),
+	}
+
+	if lastUsedEndpoint := azappcfg.lastUsedEndpoint.Load(); lastUsedEndpoint != nil {
+		status.LastUsedEndpoint = *lastUsedEndpoint
+	}
+
+	if manager, ok := azappcfg.clientManager.(*configurationClientManager); ok {
+		status.Replicas = manager.getReplicaStatus(time.Now())
+	}
+
+	return status
+}
+
+// Close stops the background replica discovery and unregisters the callbacks of the instance from the MeterProvider
+// in the options, so that the metrics of the instance are no longer observed and the instance can be garbage collected
+// while the meter provider is in use. It should be called when the instance is no longer used.
+//
+// Returns:
+//   - An error if the callbacks could not be unregistered from the meter provider
+func (azappcfg *AzureAppConfiguration) Close() error {
+	if manager, ok := azappcfg.clientManager.(*configurationClientManager); ok {
+		manager.close()
+	}
+
+	if err := azappcfg.telemetry.close(); err != nil {
+		return fmt.Errorf("failed to unregister OpenTelemetry callbacks: %w", err)
+	}
+
+	return nil
+}
+
+// startAsync serves the initial key-values of the startup options and starts the first load in the background.
+// The Ready channel is closed after the callbacks registered with OnRefreshSuccess are executed.
+func (azappcfg *AzureAppConfiguration) startAsync(ctx context.Context, options StartupOptions, operation func(context.Context) error) {
+	azappcfg.ready = make(chan struct{})
+	if len(options.InitialKeyValues) > 0 {
+		azappcfg.keyValues = maps.Clone(options.InitialKeyValues)
+	}
+
+	go func() {
+		defer close(azappcfg.ready)
+
+		if err := azappcfg.startup(ctx, options, operation); err != nil {
+			azappcfg.initialLoadErr = err
+			return
+		}
+		// Set the initial load finished flag
+		azappcfg.updateTracingOptions(func(tracingOptions *tracing.Options) {
+			tracingOptions.InitialLoadFinished = true
+		})
+
+		// The loaded configuration replaces the initial key-values as in a refresh
+		azappcfg.executeRefreshCallbacks()
+	}()
+}
+
+// executeRefreshCallbacks executes the callbacks registered with OnRefreshSuccess in the order they were added
+func (azappcfg *AzureAppConfiguration) executeRefreshCallbacks() {
+	azappcfg.onRefreshSuccessMu.Lock()
+	callbacks := slices.Clone(azappcfg.onRefreshSuccess)
+	azappcfg.onRefreshSuccessMu.Unlock()
+
+	for _, callback := range callbacks {
+		callback()
+	}
+}
+
+// isRefreshConfigured checks if refresh is enabled for key values, Key Vault secrets or feature flags
+func (azappcfg *AzureAppConfiguration) isRefreshConfigured() bool {
+	return azappcfg.kvRefreshTimer != nil || azappcfg.secretRefreshTimer != nil || azappcfg.ffRefreshTimer != nil
+}
+
 func (azappcfg *AzureAppConfiguration) load(ctx context.Context) error {
-	loadTask := func(client *azappconfig.Client) error {
+	loadTask := func(ctx context.Context, client *azappconfig.Client) error {
 		eg, egCtx := errgroup.WithContext(ctx)
 		eg.Go(func() error {
 			keyValuesClient := &selectorSettingsClient{
 				selectors:      azappcfg.kvSelectors,
 				client:         client,
-				tracingOptions: azappcfg.tracingOptions,
+				tracingOptions: azappcfg.getTracingOptions(),
+				telemetry:      azappcfg.telemetry,
 			}
 			return azappcfg.loadKeyValues(egCtx, keyValuesClient)
 		})
@@ -325,7 +789,8 @@ func (azappcfg *AzureAppConfiguration) load(ctx context.Context) error {
 				watchedClient := &watchedSettingClient{
 					watchedSettings: azappcfg.watchedSettings,
 					client:          client,
-					tracingOptions:  azappcfg.tracingOptions,
+					tracingOptions:  azappcfg.getTracingOptions(),
+					logger:          azappcfg.logger,
 				}
 				return azappcfg.loadWatchedSettings(egCtx, watchedClient)
 			})
@@ -336,7 +801,8 @@ func (azappcfg *AzureAppConfiguration) load(ctx context.Context) error {
 				ffClient := &selectorSettingsClient{
 					selectors:      azappcfg.ffSelectors,
 					client:         client,
-					tracingOptions: azappcfg.tracingOptions,
+					tracingOptions: azappcfg.getTracingOptions(),
+					telemetry:      azappcfg.telemetry,
 				}
 				return azappcfg.loadFeatureFlags(egCtx, ffClient)
 			})
@@ -376,17 +842,23 @@ func (azappcfg *AzureAppConfiguration) loadKeyValues(ctx context.Context, settin
 		}
 		trimmedKey := azappcfg.trimPrefix(*setting.Key)
 		if len(trimmedKey) == 0 {
-			log.Printf("Key of the setting '%s' is trimmed to the empty string, just ignore it", *setting.Key)
+			getLogger(azappcfg.logger).Warn("Key of the setting is trimmed to the empty string, ignoring it", "key", *setting.Key, "label", getSettingLabel(setting))
 			continue
 		}
 		rawSettings[trimmedKey] = setting
 	}
 
-	var useAIConfiguration, useAIChatCompletionConfiguration bool
+	var useAIConfiguration, useAIChatCompletionConfiguration, useSnapshotReference bool
 	kvSettings := make(map[string]any, len(settingsResponse.settings))
 	keyVaultRefs := make(map[string]string)
 	snapshotRefs := make(map[string]string)
+	secretKeys := make(map[string]struct{})
 	for trimmedKey, setting := range rawSettings {
+		sensitive := isSensitiveSetting(setting)
+		if sensitive {
+			secretKeys[trimmedKey] = struct{}{}
+		}
+
 		if setting.ContentType == nil || setting.Value == nil {
 			kvSettings[trimmedKey] = setting.Value
 			continue
@@ -399,7 +871,7 @@ func (azappcfg *AzureAppConfiguration) loadKeyValues(ctx context.Context, settin
 			keyVaultRefs[trimmedKey] = *setting.Value
 		case snapshotReferenceContentType:
 			snapshotRefs[trimmedKey] = *setting.Value
-			azappcfg.tracingOptions.UseSnapshotReference = true
+			useSnapshotReference = true
 		default:
 			if isJsonContentType(setting.ContentType) {
 				var v any
@@ -407,7 +879,8 @@ func (azappcfg *AzureAppConfiguration) loadKeyValues(ctx context.Context, settin
 					// If the value is not valid JSON, try to remove comments and parse again
 					if err := json.Unmarshal(jsonc.StripComments([]byte(*setting.Value)), &v); err != nil {
 						// If still invalid, log the error and treat it as a plain string
-						log.Printf("Failed to unmarshal JSON value: key=%s, error=%s", *setting.Key, err.Error())
+						getLogger(azappcfg.logger).Warn("Failed to unmarshal JSON value, treating it as a string",
+							"key", *setting.Key, "label", getSettingLabel(setting), "error", jsonErrorMessage(err, sensitive))
 						kvSettings[trimmedKey] = setting.Value
 						continue
 					}
@@ -425,19 +898,24 @@ func (azappcfg *AzureAppConfiguration) loadKeyValues(ctx context.Context, settin
 		}
 	}
 
-	azappcfg.tracingOptions.UseAIConfiguration = useAIConfiguration
-	azappcfg.tracingOptions.UseAIChatCompletionConfiguration = useAIChatCompletionConfiguration
+	azappcfg.updateTracingOptions(func(tracingOptions *tracing.Options) {
+		tracingOptions.UseAIConfiguration = useAIConfiguration
+		tracingOptions.UseAIChatCompletionConfiguration = useAIChatCompletionConfiguration
+		if useSnapshotReference {
+			tracingOptions.UseSnapshotReference = true
+		}
+	})
 
 	if len(snapshotRefs) > 0 {
 		var loadSnapshot snapshotSettingsLoader
 		if client, ok := settingsClient.(*selectorSettingsClient); ok {
 			loadSnapshot = func(ctx context.Context, snapshotName string) ([]azappconfig.Setting, error) {
-				return loadSnapshotSettings(ctx, client.client, snapshotName)
+				return loadSnapshotSettings(ctx, client.client, snapshotName, client.telemetry)
 			}
 		}
 
 		if loadSnapshot != nil {
-			if err := azappcfg.loadSettingsFromSnapshotRefs(ctx, loadSnapshot, snapshotRefs, kvSettings, keyVaultRefs); err != nil {
+			if err := azappcfg.loadSettingsFromSnapshotRefs(ctx, loadSnapshot, snapshotRefs, kvSettings, keyVaultRefs, secretKeys); err != nil {
 				return err
 			}
 		}
@@ -449,14 +927,22 @@ func (azappcfg *AzureAppConfiguration) loadKeyValues(ctx context.Context, settin
 	}
 
 	maps.Copy(kvSettings, secrets)
+	for key := range keyVaultRefs {
+		secretKeys[key] = struct{}{}
+	}
+
+	azappcfg.settingsMu.Lock()
 	azappcfg.keyValues = kvSettings
+	azappcfg.secretKeys = secretKeys
+	azappcfg.settingsMu.Unlock()
+
 	azappcfg.keyVaultRefs = getUnversionedKeyVaultRefs(keyVaultRefs)
 	azappcfg.kvETags = settingsResponse.pageETags
 
 	return nil
 }
 
-func (azappcfg *AzureAppConfiguration) loadSettingsFromSnapshotRefs(ctx context.Context, loadSnapshot snapshotSettingsLoader, snapshotRefs map[string]string, kvSettings map[string]any, keyVaultRefs map[string]string) error {
+func (azappcfg *AzureAppConfiguration) loadSettingsFromSnapshotRefs(ctx context.Context, loadSnapshot snapshotSettingsLoader, snapshotRefs map[string]string, kvSettings map[string]any, keyVaultRefs map[string]string, secretKeys map[string]struct{}) error {
 	var useAIConfiguration, useAIChatCompletionConfiguration bool
 	for key, snapshotRef := range snapshotRefs {
 		// Parse the snapshot reference
@@ -478,10 +964,15 @@ func (azappcfg *AzureAppConfiguration) loadSettingsFromSnapshotRefs(ctx context.
 
 			trimmedKey := azappcfg.trimPrefix(*setting.Key)
 			if len(trimmedKey) == 0 {
-				log.Printf("Key of the setting '%s' is trimmed to the empty string, just ignore it", *setting.Key)
+				getLogger(azappcfg.logger).Warn("Key of the setting is trimmed to the empty string, ignoring it", "key", *setting.Key, "label", getSettingLabel(setting))
 				continue
 			}
 
+			sensitive := isSensitiveSetting(setting)
+			if sensitive {
+				secretKeys[trimmedKey] = struct{}{}
+			}
+
 			if setting.ContentType == nil || setting.Value == nil {
 				kvSettings[trimmedKey] = setting.Value
 				continue
@@ -504,7 +995,8 @@ func (azappcfg *AzureAppConfiguration) loadSettingsFromSnapshotRefs(ctx context.
 					// If the value is not valid JSON, try to remove comments and parse again
 					if err := json.Unmarshal(jsonc.StripComments([]byte(*setting.Value)), &v); err != nil {
 						// If still invalid, log the error and treat it as a plain string
-						log.Printf("Failed to unmarshal JSON value from snapshot: key=%s, error=%s", *setting.Key, err.Error())
+						getLogger(azappcfg.logger).Warn("Failed to unmarshal JSON value from snapshot, treating it as a string",
+							"key", *setting.Key, "label", getSettingLabel(setting), "error", jsonErrorMessage(err, sensitive))
 						kvSettings[trimmedKey] = setting.Value
 						continue
 					}
@@ -522,8 +1014,10 @@ func (azappcfg *AzureAppConfiguration) loadSettingsFromSnapshotRefs(ctx context.
 		}
 	}
 
-	azappcfg.tracingOptions.UseAIConfiguration = useAIConfiguration
-	azappcfg.tracingOptions.UseAIChatCompletionConfiguration = useAIChatCompletionConfiguration
+	azappcfg.updateTracingOptions(func(tracingOptions *tracing.Options) {
+		tracingOptions.UseAIConfiguration = useAIConfiguration
+		tracingOptions.UseAIChatCompletionConfiguration = useAIChatCompletionConfiguration
+	})
 
 	return nil
 }
@@ -543,7 +1037,9 @@ func (azappcfg *AzureAppConfiguration) loadKeyVaultSecrets(ctx context.Context,
 	for key, kvRef := range keyVaultRefs {
 		key, kvRef := key, kvRef
 		eg.Go(func() error {
+			ctx, span := azappcfg.telemetry.startSpan(ctx, resolveSecretSpanName, attribute.String(settingKeyAttributeKey, key))
 			resolvedSecret, err := azappcfg.resolver.resolveSecret(ctx, kvRef)
+			endSpan(span, err)
 			if err != nil {
 				return fmt.Errorf("fail to resolve the Key Vault reference '%s': %s", key, err.Error())
 			}
@@ -570,7 +1066,14 @@ func (azappcfg *AzureAppConfiguration) loadFeatureFlags(ctx context.Context, set
 		return err
 	}
 
+	// Feature flags are deduplicated by feature ID. Settings are returned in selector order, so a flag
+	// from a later selector takes precedence, while the flag keeps the position of its first occurrence.
 	dedupFeatureFlags := make(map[string]any, len(settingsResponse.settings))
+	typedFeatureFlags := make(map[string]featureflags.FeatureFlag, len(settingsResponse.settings))
+	featureFlagLabels := make(map[string]string, len(settingsResponse.settings))
+	orderedIDs := make([]string, 0, len(settingsResponse.settings))
+	seenIDs := make(map[string]struct{}, len(settingsResponse.settings))
+	diagnostics := make([]FeatureFlagDiagnostic, 0)
 	for _, setting := range settingsResponse.settings {
 		// Skip non-feature flag settings
 		if setting.ContentType == nil || *setting.ContentType != featureFlagContentType {
@@ -579,18 +1082,75 @@ func (azappcfg *AzureAppConfiguration) loadFeatureFlags(ctx context.Context, set
 
 		if setting.Key != nil {
 			var v map[string]any
-			if err := json.Unmarshal([]byte(*setting.Value), &v); err != nil {
-				log.Printf("Invalid feature flag setting: key=%s, error=%s, just ignore", *setting.Key, err.Error())
+			jsonErr := json.Unmarshal([]byte(*setting.Value), &v)
+
+			id := getFeatureFlagID(*setting.Key, v)
+			if _, seen := seenIDs[id]; !seen {
+				seenIDs[id] = struct{}{}
+				orderedIDs = append(orderedIDs, id)
+			}
+
+			// A dropped setting does not replace the feature flag with the same ID loaded from an earlier setting
+			if jsonErr != nil {
+				diagnostics = append(diagnostics, newFeatureFlagDiagnostic(setting, "", []string{"invalid JSON: " + jsonErr.Error()}, true))
 				continue
 			}
+
+			var featureFlag featureflags.FeatureFlag
+			var violations []string
+			decodeErr := json.Unmarshal([]byte(*setting.Value), &featureFlag)
+			if decodeErr != nil {
+				featureFlag.ID, _ = v["id"].(string)
+				violations = []string{"does not match the feature flag schema: " + decodeErr.Error()}
+			} else if err := featureFlag.Validate(); err != nil {
+				var validationErr *featureflags.ValidationError
+				if errors.As(err, &validationErr) {
+					violations = validationErr.Violations
+				} else {
+					violations = []string{err.Error()}
+				}
+			}
+
+			if len(violations) > 0 {
+				// A flag which cannot be decoded cannot be evaluated, so it is dropped regardless of the policy
+				dropped := decodeErr != nil || azappcfg.ffValidationPolicy == FeatureFlagValidationPolicyDrop
+				diagnostics = append(diagnostics, newFeatureFlagDiagnostic(setting, featureFlag.ID, violations, dropped))
+				if dropped {
+					continue
+				}
+			}
+
 			azappcfg.updateFeatureFlagTracing(v)
-			dedupFeatureFlags[*setting.Key] = v
+			telemetryMetadata := azappcfg.updateFeatureFlagTelemetryMetadata(v, setting)
+			dedupFeatureFlags[id] = v
+			if setting.Label != nil {
+				featureFlagLabels[id] = *setting.Label
+			} else {
+				featureFlagLabels[id] = ""
+			}
+
+			if featureFlag.Telemetry != nil && telemetryMetadata != nil {
+				featureFlag.Telemetry.Metadata = telemetryMetadata
+			}
+			typedFeatureFlags[id] = featureFlag
+		}
+	}
+
+	if azappcfg.ffValidationPolicy == FeatureFlagValidationPolicyFail && len(diagnostics) > 0 {
+		errs := make([]error, 0, len(diagnostics))
+		for _, diagnostic := range diagnostics {
+			errs = append(errs, diagnostic)
 		}
+		return fmt.Errorf("failed to load feature flags: %w", errors.Join(errs...))
 	}
 
 	featureFlags := make([]any, 0, len(dedupFeatureFlags))
-	for _, v := range dedupFeatureFlags {
-		featureFlags = append(featureFlags, v)
+	featureFlagIDs := make([]string, 0, len(typedFeatureFlags))
+	for _, id := range orderedIDs {
+		if v, ok := dedupFeatureFlags[id]; ok {
+			featureFlags = append(featureFlags, v)
+			featureFlagIDs = append(featureFlagIDs, id)
+		}
 	}
 
 	// "feature_management": {"feature_flags": [{...}, {...}]}
@@ -601,7 +1161,14 @@ func (azappcfg *AzureAppConfiguration) loadFeatureFlags(ctx context.Context, set
 	}
 
 	azappcfg.ffETags = settingsResponse.pageETags
+
+	azappcfg.settingsMu.Lock()
 	azappcfg.featureFlags = ffSettings
+	azappcfg.featureFlagsByID = typedFeatureFlags
+	azappcfg.featureFlagIDs = featureFlagIDs
+	azappcfg.featureFlagLabels = featureFlagLabels
+	azappcfg.featureFlagDiagnostics = diagnostics
+	azappcfg.settingsMu.Unlock()
 
 	return nil
 }
@@ -618,7 +1185,7 @@ func (azappcfg *AzureAppConfiguration) refreshKeyValues(ctx context.Context, ref
 	// Check if any ETags have changed
 	eTagChanged, err := refreshClient.monitor.checkIfETagChanged(ctx)
 	if err != nil {
-		log.Printf("Failed to check if key value settings have changed: %s", err.Error())
+		getLogger(azappcfg.logger).Warn("Failed to check if key value settings have changed", "error", err)
 		return false, err
 	}
 
@@ -674,8 +1241,9 @@ func (azappcfg *AzureAppConfiguration) refreshKeyVaultSecrets(ctx context.Contex
 
 	// Check if any secrets have changed
 	changed := false
+	loadedKeyValues, _ := azappcfg.getLoadedKeyValues()
 	keyValues := make(map[string]any)
-	maps.Copy(keyValues, azappcfg.keyValues)
+	maps.Copy(keyValues, loadedKeyValues)
 	for key, newSecret := range unversionedSecrets {
 		if oldSecret, exists := keyValues[key]; !exists || oldSecret != newSecret {
 			changed = true
@@ -684,7 +1252,9 @@ func (azappcfg *AzureAppConfiguration) refreshKeyVaultSecrets(ctx context.Contex
 	}
 
 	// Reset the timer only after successful refresh
+	azappcfg.settingsMu.Lock()
 	azappcfg.keyValues = keyValues
+	azappcfg.settingsMu.Unlock()
 	azappcfg.secretRefreshTimer.Reset()
 	return changed, nil
 }
@@ -699,7 +1269,7 @@ func (azappcfg *AzureAppConfiguration) refreshFeatureFlags(ctx context.Context,
 	// Check if any ETags have changed
 	eTagChanged, err := refreshClient.monitor.checkIfETagChanged(ctx)
 	if err != nil {
-		log.Printf("Failed to check if feature flag settings have changed: %s", err.Error())
+		getLogger(azappcfg.logger).Warn("Failed to check if feature flag settings have changed", "error", err)
 		return false, err
 	}
 
@@ -717,7 +1287,7 @@ func (azappcfg *AzureAppConfiguration) refreshFeatureFlags(ctx context.Context,
 	})
 
 	if err := eg.Wait(); err != nil {
-		log.Printf("Failed to reload feature flag configuration: %s", err.Error())
+		getLogger(azappcfg.logger).Warn("Failed to reload feature flag configuration", "error", err)
 		// Don't reset the timer if reload failed
 		return false, err
 	}
@@ -727,7 +1297,14 @@ func (azappcfg *AzureAppConfiguration) refreshFeatureFlags(ctx context.Context,
 	return true, nil
 }
 
-func (azappcfg *AzureAppConfiguration) executeFailoverPolicy(ctx context.Context, operation func(*azappconfig.Client) error) error {
+var (
+	errNoClientAvailable = errors.New("no client is available to connect to the target App Configuration store")
+	errAllClientsFailed  = errors.New("failed to get settings from all clients")
+)
+
+// executeFailoverPolicy executes the operation with the clients in turn until it succeeds or fails with an error which is not failoverable.
+// Each attempt is limited by the request timeout, so that a slow endpoint does not consume the whole budget of the caller's context.
+func (azappcfg *AzureAppConfiguration) executeFailoverPolicy(ctx context.Context, operation func(context.Context, *azappconfig.Client) error) error {
 	clients, err := azappcfg.clientManager.getClients(ctx)
 	if err != nil {
 		return err
@@ -735,51 +1312,126 @@ func (azappcfg *AzureAppConfiguration) executeFailoverPolicy(ctx context.Context
 
 	if len(clients) == 0 {
 		azappcfg.clientManager.refreshClients(ctx)
-		return fmt.Errorf("no client is available to connect to the target App Configuration store")
+		return errNoClientAvailable
 	}
-	// If load balancing is enabled, rotate the clients so that the next client to be used is not the last successful one
-	if azappcfg.loadBalancingEnabled && azappcfg.lastSuccessfulEndpoint != "" && len(clients) > 1 {
-		rotateClientsToNextEndpoint(clients, azappcfg.lastSuccessfulEndpoint)
+	// Order the clients with the replica selector if provided. Otherwise, if load balancing is enabled,
+	// rotate the clients so that the next client to be used is not the last successful one
+	if azappcfg.replicaSelector != nil && len(clients) > 1 {
+		clients = selectClients(azappcfg.replicaSelector, clients, time.Now())
+	} else if lastSuccessfulEndpoint := azappcfg.getLastSuccessfulEndpoint(); azappcfg.loadBalancingEnabled && lastSuccessfulEndpoint != "" && len(clients) > 1 {
+		rotateClientsToNextEndpoint(clients, lastSuccessfulEndpoint)
 	}
 
 	if manager, ok := azappcfg.clientManager.(*configurationClientManager); ok {
-		azappcfg.tracingOptions.ReplicaCount = len(manager.dynamicClients)
+		replicaCount := len(manager.replicaClients) + len(manager.getDynamicClients())
+		azappcfg.updateTracingOptions(func(tracingOptions *tracing.Options) {
+			tracingOptions.ReplicaCount = replicaCount
+		})
+		azappcfg.telemetry.recordReplicaCount(ctx, replicaCount)
 	}
 
 	errors := make([]error, 0, len(clients))
-	azappcfg.tracingOptions.IsFailoverRequest = false
+	azappcfg.setFailoverRequest(false)
 	for _, clientWrapper := range clients {
-		if err := operation(clientWrapper.client); err != nil {
-			if isFailoverable(err) {
-				clientWrapper.updateBackoffStatus(false)
+		// Do not try the next client if the caller's context is done
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		probe, ok := clientWrapper.beginRequest(time.Now())
+		if !ok {
+			continue
+		}
+
+		requestCtx, cancel := withOptionalTimeout(ctx, azappcfg.operationTimeouts.Request)
+
+		// Probe the endpoint of a half-open circuit before sending the request
+		if probe {
+			err := clientWrapper.breaker.probe(requestCtx, clientWrapper.client)
+			switch {
+			case isProbeSuccessful(err):
+				clientWrapper.recordProbeResult(nil)
+			case ctx.Err() != nil:
+				// The probe is abandoned by the caller, so the circuit stays half-open
+				cancel()
+				clientWrapper.releaseProbe()
+				return ctx.Err()
+			case isFailoverable(err):
+				cancel()
+				clientWrapper.recordProbeResult(err)
+				errors = append(errors, fmt.Errorf("failed to probe client of %s: %w", clientWrapper.endpoint, err))
+				azappcfg.setFailoverRequest(true)
+				azappcfg.telemetry.recordFailover(ctx, clientWrapper.endpoint)
+				continue
+			default:
+				// The probe is rejected for a reason which does not tell the health of the endpoint, so the circuit stays half-open
+				cancel()
+				clientWrapper.releaseProbe()
+				return fmt.Errorf("failed to probe client of %s: %w", clientWrapper.endpoint, err)
+			}
+		}
+
+		startTime := time.Now()
+		err := operation(requestCtx, clientWrapper.client)
+		cancel()
+		if err != nil {
+			// A request which exceeded the request timeout is failed over, unless the caller's context is done
+			if ctx.Err() == nil && isFailoverable(err) {
+				clientWrapper.recordFailure(err)
 				errors = append(errors, fmt.Errorf("failed to get settings with client of %s: %w", clientWrapper.endpoint, err))
-				azappcfg.tracingOptions.IsFailoverRequest = true
+				azappcfg.setFailoverRequest(true)
+				azappcfg.telemetry.recordFailover(ctx, clientWrapper.endpoint)
 				continue
 			}
 
 			return err
 		}
 
-		clientWrapper.updateBackoffStatus(true)
+		clientWrapper.recordSuccess(time.Since(startTime))
+		azappcfg.lastUsedEndpoint.Store(&clientWrapper.endpoint)
 		// Update the last successful endpoint for load balancing
 		if azappcfg.loadBalancingEnabled {
+			azappcfg.tracingMu.Lock()
 			azappcfg.lastSuccessfulEndpoint = clientWrapper.endpoint
+			azappcfg.tracingMu.Unlock()
 		}
 		return nil
 	}
 
 	// If we reach here, it means all clients failed
 	azappcfg.clientManager.refreshClients(ctx)
-	return fmt.Errorf("failed to get settings from all clients: %v", errors)
+	return fmt.Errorf("%w: %v", errAllClientsFailed, errors)
+}
+
+// startup loads the configuration with the startup retry policy and records the load in the telemetry
+func (azappcfg *AzureAppConfiguration) startup(ctx context.Context, options StartupOptions, operation func(context.Context) error) error {
+	ctx, span := azappcfg.telemetry.startSpan(ctx, loadSpanName)
+	startTime := time.Now()
+	err := azappcfg.startupWithRetry(ctx, options, operation)
+	azappcfg.telemetry.recordLoad(ctx, time.Since(startTime), err)
+	endSpan(span, err)
+
+	return err
 }
 
-// startupWithRetry implements retry logic for startup loading with timeout and exponential backoff
-func (azappcfg *AzureAppConfiguration) startupWithRetry(ctx context.Context, timeout time.Duration, operation func(context.Context) error) error {
+// startupWithRetry implements retry logic for startup loading with timeout and the backoff policy of the startup options
+func (azappcfg *AzureAppConfiguration) startupWithRetry(ctx context.Context, options StartupOptions, operation func(context.Context) error) error {
 	// If no timeout is specified, use the default startup timeout
+	timeout := options.Timeout
 	if timeout <= 0 {
 		timeout = defaultStartupTimeout
 	}
 
+	isRetriable := options.IsRetriable
+	if isRetriable == nil {
+		isRetriable = isRetriableStartupError
+	}
+
+	backoff := options.Backoff
+	if backoff == nil {
+		backoff = getStartupBackoffDuration
+	}
+
 	// Create a context with timeout for the entire startup process
 	startupCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
@@ -797,25 +1449,29 @@ func (azappcfg *AzureAppConfiguration) startupWithRetry(ctx context.Context, tim
 		}
 
 		// Check if the error is retriable
-		if !(isFailoverable(err) ||
-			strings.Contains(err.Error(), "no client is available") ||
-			strings.Contains(err.Error(), "failed to get settings from all clients")) {
+		timeElapsed := time.Since(startTime)
+		if !isRetriable(err) {
+			reportStartupAttempt(options.OnAttemptFailed, StartupAttempt{Attempt: attempt, Elapsed: timeElapsed, Error: err})
 			return fmt.Errorf("load from Azure App Configuration failed with non-retriable error: %w", err)
 		}
 
-		// Calculate backoff duration
-		timeElapsed := time.Since(startTime)
-		backoffDuration := getFixedBackoffDuration(timeElapsed)
-		if backoffDuration == 0 {
-			backoffDuration = calculateBackoffDuration(attempt)
+		if options.FailFast || (options.MaxAttempts > 0 && attempt >= options.MaxAttempts) {
+			reportStartupAttempt(options.OnAttemptFailed, StartupAttempt{Attempt: attempt, Elapsed: timeElapsed, Error: err, Retriable: true})
+			return fmt.Errorf("load from Azure App Configuration failed after %d attempts: %w", attempt, err)
 		}
 
+		// Calculate backoff duration
+		backoffDuration := max(backoff(attempt, timeElapsed), 0)
+
 		// Check if we have enough time left to wait and retry
 		timeRemaining := timeout - timeElapsed
 		if timeRemaining <= backoffDuration {
+			reportStartupAttempt(options.OnAttemptFailed, StartupAttempt{Attempt: attempt, Elapsed: timeElapsed, Error: err, Retriable: true})
 			return fmt.Errorf("load from Azure App Configuration failed after %d attempts within timeout %v: %w", attempt, timeout, err)
 		}
 
+		reportStartupAttempt(options.OnAttemptFailed, StartupAttempt{Attempt: attempt, Elapsed: timeElapsed, Error: err, Retriable: true, NextRetryDelay: backoffDuration})
+
 		// Wait for the backoff duration before retrying
 		select {
 		case <-startupCtx.Done():
@@ -826,6 +1482,40 @@ func (azappcfg *AzureAppConfiguration) startupWithRetry(ctx context.Context, tim
 	}
 }
 
+// isRetriableStartupError checks if the startup load can be retried after the error,
+// which is the case for failoverable errors and when no client could serve the request
+func isRetriableStartupError(err error) bool {
+	return isFailoverable(err) ||
+		errors.Is(err, errNoClientAvailable) ||
+		errors.Is(err, errAllClientsFailed)
+}
+
+// getStartupBackoffDuration returns a fixed backoff duration within the first 10 minutes of the startup,
+// and an exponential backoff duration afterwards
+func getStartupBackoffDuration(attempt int, timeElapsed time.Duration) time.Duration {
+	if backoffDuration := getFixedBackoffDuration(timeElapsed); backoffDuration > 0 {
+		return backoffDuration
+	}
+
+	return calculateBackoffDuration(attempt)
+}
+
+func reportStartupAttempt(callback func(StartupAttempt), attempt StartupAttempt) {
+	if callback != nil {
+		callback(attempt)
+	}
+}
+
+func (azappcfg *AzureAppConfiguration) getEvaluator() *featureflags.Evaluator {
+	if azappcfg.evaluator == nil {
+		// The evaluator with default options only has the built-in filters registered, which never fails
+		evaluator, _ := featureflags.NewEvaluator(nil)
+		return evaluator
+	}
+
+	return azappcfg.evaluator
+}
+
 func (azappcfg *AzureAppConfiguration) trimPrefix(key string) string {
 	result := key
 	for _, prefix := range azappcfg.trimPrefixes {
@@ -884,18 +1574,129 @@ func getFeatureFlagSelectors(selectors []Selector) []Selector {
 	return selectors
 }
 
-// constructHierarchicalMap converts a flat map with delimited keys to a hierarchical structure
-func (azappcfg *AzureAppConfiguration) constructHierarchicalMap(separator string) map[string]any {
-	tree := &tree.Tree{}
-	for k, v := range azappcfg.keyValues {
-		tree.Insert(strings.Split(k, separator), v)
+// constructHierarchicalMap converts a flat map with delimited keys to a hierarchical structure,
+// with the loaded key-values merged on top of the defaults and the key-value overrides merged on top of both
+func (azappcfg *AzureAppConfiguration) constructHierarchicalMap(options ConstructionOptions) map[string]any {
+	keyValues, keyValueOverrides := azappcfg.getKeyValues(options)
+	layers := []map[string]any{
+		flattenDefaults(azappcfg.defaults, options.Separator),
+		keyValues,
+		keyValueOverrides,
 	}
 
-	constructedMap := tree.Build()
-	if azappcfg.ffEnabled {
-		maps.Copy(constructedMap, azappcfg.featureFlags)
+	return buildHierarchicalMap(layers, azappcfg.getFeatureManagementSection(), options)
+}
+
+// getKeyValues returns the loaded key-values and the key-value overrides, which are merged on top of the loaded key-values
+// as a separate layer, with the secrets redacted if requested in the construction options
+func (azappcfg *AzureAppConfiguration) getKeyValues(options ConstructionOptions) (map[string]any, map[string]any) {
+	keyValues, secretKeys := azappcfg.getLoadedKeyValues()
+	keyValueOverrides := azappcfg.overrides.getKeyValues()
+	if !options.RedactSecrets {
+		return keyValues, keyValueOverrides
+	}
+
+	return redactSecrets(keyValues, secretKeys), redactSecrets(keyValueOverrides, secretKeys)
+}
+
+// redactSecrets returns a copy of the key-values whose values of the secret keys are redacted
+func redactSecrets(keyValues map[string]any, secretKeys map[string]struct{}) map[string]any {
+	redacted := make(map[string]any, len(keyValues))
+	for k, v := range keyValues {
+		if _, ok := secretKeys[k]; ok {
+			v = redactedValue
+		}
+		redacted[k] = v
+	}
+
+	return redacted
+}
+
+// getKeyValueSource returns the source of the key among the key-value overrides and the loaded key-values,
+// which is empty if neither has the key
+func (azappcfg *AzureAppConfiguration) getKeyValueSource(key string) ValueSource {
+	if _, ok := azappcfg.overrides.getKeyValues()[key]; ok {
+		return ValueSourceOverride
+	}
+
+	keyValues, _ := azappcfg.getLoadedKeyValues()
+	if _, ok := keyValues[key]; ok {
+		return ValueSourceLoaded
+	}
+
+	return ""
+}
+
+// getDefaultSource returns ValueSourceDefault if the defaults flattened with the separator have the key, or empty otherwise
+func (azappcfg *AzureAppConfiguration) getDefaultSource(key string, separator string) ValueSource {
+	if _, ok := flattenDefaults(azappcfg.defaults, separator)[key]; ok {
+		return ValueSourceDefault
+	}
+
+	return ""
+}
+
+// getLoadedKeyValues returns the key-values and the secret keys published by the latest load or refresh
+func (azappcfg *AzureAppConfiguration) getLoadedKeyValues() (map[string]any, map[string]struct{}) {
+	azappcfg.settingsMu.RLock()
+	defer azappcfg.settingsMu.RUnlock()
+
+	return azappcfg.keyValues, azappcfg.secretKeys
+}
+
+// getFeatureManagementSection returns the "feature_management" section of the loaded feature flags with
+// the feature overrides applied, which is nil if feature flags are not enabled and no feature is overridden
+func (azappcfg *AzureAppConfiguration) getFeatureManagementSection() map[string]any {
+	azappcfg.settingsMu.RLock()
+	featureFlags := azappcfg.featureFlags
+	azappcfg.settingsMu.RUnlock()
+
+	if featureOverrides := azappcfg.overrides.getFeatures(); len(featureOverrides) > 0 {
+		return applyFeatureOverrides(featureFlags, featureOverrides)
+	} else if azappcfg.ffEnabled {
+		return featureFlags
+	}
+
+	return nil
+}
+
+// decodeHierarchicalMap decodes the hierarchical configuration into the value pointed to v
+func decodeHierarchicalMap(hierarchicalMap map[string]any, v any) error {
+	config := &decoder.DecoderConfig{
+		Result:           v,
+		WeaklyTypedInput: true,
+		TagName:          "json",
+		DecodeHook: decoder.ComposeDecodeHookFunc(
+			decoder.StringToTimeDurationHookFunc(),
+			decoder.StringToSliceHookFunc(","),
+		),
+	}
+
+	decoder, err := decoder.NewDecoder(config)
+	if err != nil {
+		return err
 	}
 
+	return decoder.Decode(hierarchicalMap)
+}
+
+// buildHierarchicalMap builds the hierarchical configuration from the key-values and the feature management section
+// buildHierarchicalMap builds the hierarchical structure of the layers of key-values in the order of increasing precedence.
+// Each layer is built on its own and merged on top of the previous layers, so that a key-value overlapping the key-values
+// of a previous layer, e.g. a nested key inside a JSON object, takes precedence regardless of the order of the maps.
+func buildHierarchicalMap(layers []map[string]any, featureManagement map[string]any, options ConstructionOptions) map[string]any {
+	merged := &tree.Tree{}
+	for _, keyValues := range layers {
+		layer := &tree.Tree{}
+		for k, v := range keyValues {
+			layer.Insert(strings.Split(k, options.Separator), v)
+		}
+		merged.Merge(layer)
+	}
+
+	constructedMap := merged.Build()
+	maps.Copy(constructedMap, featureManagement)
+
 	return constructedMap
 }
 
@@ -904,6 +1705,11 @@ func configureTracingOptions(options *Options) tracing.Options {
 		Enabled: true,
 	}
 
+	if options.TracingOptions.Disabled {
+		tracingOption.Enabled = false
+		return tracingOption
+	}
+
 	if value, exist := os.LookupEnv(tracing.EnvVarTracingDisabled); exist {
 		tracingDisabled, _ := strconv.ParseBool(value)
 		if tracingDisabled {
@@ -913,7 +1719,12 @@ func configureTracingOptions(options *Options) tracing.Options {
 	}
 
 	tracingOption.Host = tracing.GetHostType()
+	if options.TracingOptions.HostType != "" {
+		tracingOption.Host = tracing.HostType(options.TracingOptions.HostType)
+	}
+
 	tracingOption.FMVersion = tracing.GetFeatureManagementVersion()
+	tracingOption.CustomTags = tracing.FormatCustomTags(options.TracingOptions.Tags)
 
 	if !(options.KeyVaultOptions.SecretResolver == nil && options.KeyVaultOptions.Credential == nil) {
 		tracingOption.KeyVaultConfigured = true
@@ -950,13 +1761,14 @@ func (azappcfg *AzureAppConfiguration) newKeyValueRefreshClient(client *azappcon
 	if azappcfg.watchAll {
 		monitor = &pageETagsClient{
 			client:         client,
-			tracingOptions: azappcfg.tracingOptions,
+			tracingOptions: azappcfg.getTracingOptions(),
 			pageETags:      azappcfg.kvETags,
 		}
 	} else {
 		monitor = &watchedSettingClient{
 			client:         client,
-			tracingOptions: azappcfg.tracingOptions,
+			tracingOptions: azappcfg.getTracingOptions(),
+			logger:         azappcfg.logger,
 			eTags:          azappcfg.sentinelETags,
 		}
 	}
@@ -965,13 +1777,15 @@ func (azappcfg *AzureAppConfiguration) newKeyValueRefreshClient(client *azappcon
 		loader: &selectorSettingsClient{
 			selectors:      azappcfg.kvSelectors,
 			client:         client,
-			tracingOptions: azappcfg.tracingOptions,
+			tracingOptions: azappcfg.getTracingOptions(),
+			telemetry:      azappcfg.telemetry,
 		},
 		monitor: monitor,
 		sentinels: &watchedSettingClient{
 			watchedSettings: azappcfg.watchedSettings,
 			client:          client,
-			tracingOptions:  azappcfg.tracingOptions,
+			tracingOptions:  azappcfg.getTracingOptions(),
+			logger:          azappcfg.logger,
 		},
 	}
 }
@@ -981,17 +1795,57 @@ func (azappcfg *AzureAppConfiguration) newFeatureFlagRefreshClient(client *azapp
 		loader: &selectorSettingsClient{
 			selectors:      azappcfg.ffSelectors,
 			client:         client,
-			tracingOptions: azappcfg.tracingOptions,
+			tracingOptions: azappcfg.getTracingOptions(),
+			telemetry:      azappcfg.telemetry,
 		},
 		monitor: &pageETagsClient{
 			client:         client,
-			tracingOptions: azappcfg.tracingOptions,
+			tracingOptions: azappcfg.getTracingOptions(),
 			pageETags:      azappcfg.ffETags,
 		},
 	}
 }
 
+// getTracingOptions returns a copy of the tracing options, which is not changed by concurrent loads and refreshes
+func (azappcfg *AzureAppConfiguration) getTracingOptions() tracing.Options {
+	azappcfg.tracingMu.RLock()
+	defer azappcfg.tracingMu.RUnlock()
+
+	tracingOptions := azappcfg.tracingOptions
+	if tracingOptions.FeatureFlagTracing != nil {
+		featureFlagTracing := *tracingOptions.FeatureFlagTracing
+		tracingOptions.FeatureFlagTracing = &featureFlagTracing
+	}
+
+	return tracingOptions
+}
+
+// updateTracingOptions changes the tracing options with the tracing options locked
+func (azappcfg *AzureAppConfiguration) updateTracingOptions(update func(*tracing.Options)) {
+	azappcfg.tracingMu.Lock()
+	defer azappcfg.tracingMu.Unlock()
+
+	update(&azappcfg.tracingOptions)
+}
+
+// setFailoverRequest sets whether the following requests are sent after failing over from another endpoint
+func (azappcfg *AzureAppConfiguration) setFailoverRequest(isFailoverRequest bool) {
+	azappcfg.updateTracingOptions(func(tracingOptions *tracing.Options) {
+		tracingOptions.IsFailoverRequest = isFailoverRequest
+	})
+}
+
+func (azappcfg *AzureAppConfiguration) getLastSuccessfulEndpoint() string {
+	azappcfg.tracingMu.RLock()
+	defer azappcfg.tracingMu.RUnlock()
+
+	return azappcfg.lastSuccessfulEndpoint
+}
+
 func (azappcfg *AzureAppConfiguration) updateFeatureFlagTracing(featureFlag map[string]any) {
+	azappcfg.tracingMu.Lock()
+	defer azappcfg.tracingMu.Unlock()
+
 	if azappcfg.tracingOptions.FeatureFlagTracing == nil {
 		return
 	}
@@ -1029,6 +1883,54 @@ func (azappcfg *AzureAppConfiguration) updateFeatureFlagTracing(featureFlag map[
 	}
 }
 
+// updateFeatureFlagTelemetryMetadata adds the ETag, FeatureFlagReference and AllocationId of a feature flag
+// with telemetry enabled to its telemetry metadata, and returns the string values of the resulting metadata.
+// It returns nil if telemetry is not enabled for the feature flag.
+func (azappcfg *AzureAppConfiguration) updateFeatureFlagTelemetryMetadata(featureFlag map[string]any, setting azappconfig.Setting) map[string]string {
+	telemetry, ok := featureFlag[telemetryKey].(map[string]any)
+	if !ok {
+		return nil
+	}
+	if enabled, ok := telemetry[enabledKey].(bool); !ok || !enabled {
+		return nil
+	}
+
+	metadata, ok := telemetry[metadataKey].(map[string]any)
+	if !ok {
+		metadata = make(map[string]any)
+		telemetry[metadataKey] = metadata
+	}
+
+	if setting.ETag != nil {
+		metadata[eTagKey] = string(*setting.ETag)
+	}
+	if manager, ok := azappcfg.clientManager.(*configurationClientManager); ok && manager.endpoint != "" {
+		metadata[featureFlagReferenceKey] = generateFeatureFlagReference(manager.endpoint, setting)
+	}
+	if allocationID := generateAllocationID(featureFlag); allocationID != "" {
+		metadata[allocationIdKeyName] = allocationID
+	}
+
+	result := make(map[string]string, len(metadata))
+	for key, value := range metadata {
+		if str, ok := value.(string); ok {
+			result[key] = str
+		}
+	}
+
+	return result
+}
+
+// getFeatureFlagID returns the ID of a feature flag setting, which is the "id" property of the value,
+// or the key without the feature flag key prefix if the value has no ID
+func getFeatureFlagID(key string, featureFlag map[string]any) string {
+	if id, ok := featureFlag["id"].(string); ok && id != "" {
+		return id
+	}
+
+	return strings.TrimPrefix(key, featureFlagKeyPrefix)
+}
+
 func rotateClientsToNextEndpoint(clients []*configurationClientWrapper, lastSuccessfulEndpoint string) {
 	if len(clients) <= 1 {
 		return
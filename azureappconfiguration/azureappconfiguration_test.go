@@ -4,7 +4,9 @@
 package azureappconfiguration
 
 import (
+	"bytes"
 	"context"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"sync"
@@ -13,7 +15,7 @@ import (
 
 	"encoding/json"
 
-	"github.com/Azure/AppConfiguration-GoProvider/azureappconfiguration/internal/fm"
+	"github.com/Azure/AppConfiguration-GoProvider/azureappconfiguration/featureflags"
 	"github.com/Azure/AppConfiguration-GoProvider/azureappconfiguration/internal/tracing"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/data/azappconfig/v2"
@@ -260,12 +262,14 @@ func TestLoadKeyValues_InvalidJson(t *testing.T) {
 
 	mockClient.On("getSettings", ctx).Return(mockResponse, nil)
 
+	var logs bytes.Buffer
 	azappcfg := &AzureAppConfiguration{
 		clientManager: &configurationClientManager{
 			staticClient: &configurationClientWrapper{client: &azappconfig.Client{}},
 		},
 		kvSelectors: deduplicateSelectors([]Selector{}),
 		keyValues:   make(map[string]any),
+		logger:      slog.New(slog.NewJSONHandler(&logs, nil)),
 	}
 
 	err := azappcfg.loadKeyValues(ctx, mockClient)
@@ -275,6 +279,14 @@ func TestLoadKeyValues_InvalidJson(t *testing.T) {
 	assert.Equal(t, &value1, azappcfg.keyValues["key1"])
 	// The invalid JSON key should be treated as a plain string
 	assert.Equal(t, &value2, azappcfg.keyValues["key2"])
+
+	// The failure is logged with the key of the setting
+	var record map[string]any
+	assert.NoError(t, json.Unmarshal(logs.Bytes(), &record))
+	assert.Equal(t, "WARN", record["level"])
+	assert.Equal(t, "key2", record["key"])
+	assert.Equal(t, "", record["label"])
+	assert.Contains(t, record["error"], "invalid character")
 }
 
 func TestDeduplicateSelectors(t *testing.T) {
@@ -628,6 +640,97 @@ func TestUnmarshal_CustomTags(t *testing.T) {
 	assert.Equal(t, []string{"192.168.1.1", "10.0.0.1", "172.16.0.1"}, config.AllowedIPs)
 }
 
+func TestUnmarshal_Defaults(t *testing.T) {
+	azappcfg := &AzureAppConfiguration{
+		keyValues: map[string]any{
+			"App:Name": "loaded",
+		},
+		defaults: map[string]any{
+			"App": map[string]any{
+				"Name":    "default",
+				"Timeout": "30s",
+				"Retries": float64(3),
+			},
+		},
+	}
+	azappcfg.SetOverride("App:Retries", 5, nil)
+
+	type config struct {
+		App struct {
+			Name    string
+			Timeout time.Duration
+			Retries int
+		}
+	}
+
+	var result config
+	err := azappcfg.Unmarshal(&result, &ConstructionOptions{Separator: ":"})
+	assert.NoError(t, err)
+	assert.Equal(t, "loaded", result.App.Name)
+	assert.Equal(t, 30*time.Second, result.App.Timeout)
+	assert.Equal(t, 5, result.App.Retries)
+
+	bytes, err := azappcfg.GetBytes(&ConstructionOptions{Separator: ":"})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"App":{"Name":"loaded","Timeout":"30s","Retries":5}}`, string(bytes))
+
+	// The source of each key follows the precedence of the overrides, the loaded key-values and the defaults
+	for key, expected := range map[string]ValueSource{
+		"App:Retries": ValueSourceOverride,
+		"App:Name":    ValueSourceLoaded,
+		"App:Timeout": ValueSourceDefault,
+		"App:Missing": "",
+	} {
+		source, err := azappcfg.GetSource(key, &ConstructionOptions{Separator: ":"})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, source, key)
+	}
+
+	_, err = azappcfg.GetSource("App:Name", &ConstructionOptions{Separator: "|"})
+	assert.Error(t, err)
+}
+
+func TestUnmarshal_DefaultsUnderJSONObject(t *testing.T) {
+	azappcfg := &AzureAppConfiguration{
+		keyValues: map[string]any{
+			"db":    map[string]any{"host": "loaded-host"},
+			"cache": "loaded-cache",
+		},
+		defaults: map[string]any{
+			"db":    map[string]any{"host": "default-host", "port": json.Number("5432")},
+			"cache": map[string]any{"size": json.Number("64")},
+		},
+	}
+
+	// The loaded JSON object takes precedence over the defaults nested in it regardless of the map order,
+	// and a loaded value replaces the defaults nested under its key
+	for range 100 {
+		bytes, err := azappcfg.GetBytes(nil)
+		assert.NoError(t, err)
+		if !assert.JSONEq(t, `{"db":{"host":"loaded-host","port":5432},"cache":"loaded-cache"}`, string(bytes)) {
+			return
+		}
+	}
+}
+
+func TestUnmarshal_LargeIntegerDefaults(t *testing.T) {
+	type config struct {
+		MaxSize int64
+	}
+
+	defaults, err := normalizeDefaults(config{MaxSize: 1<<53 + 1})
+	assert.NoError(t, err)
+	azappcfg := &AzureAppConfiguration{defaults: defaults}
+
+	var result config
+	assert.NoError(t, azappcfg.Unmarshal(&result, nil))
+	assert.Equal(t, int64(1<<53+1), result.MaxSize)
+
+	bytes, err := azappcfg.GetBytes(nil)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"MaxSize":9007199254740993}`, string(bytes))
+}
+
 func TestUnmarshal_EmptyValues(t *testing.T) {
 	// Define a struct with default values
 	type Config struct {
@@ -942,6 +1045,45 @@ func TestCorrelationContextHeader(t *testing.T) {
 		tracing.AIConfigurationTag+tracing.DelimiterPlus+tracing.AIChatCompletionConfigurationTag)
 }
 
+func TestConfigureTracingOptions(t *testing.T) {
+	t.Setenv(tracing.EnvVarTracingDisabled, "")
+
+	t.Run("disabled", func(t *testing.T) {
+		tracingOptions := configureTracingOptions(&Options{
+			TracingOptions: TracingOptions{Disabled: true, Tags: map[string]string{"Service": "checkout"}},
+		})
+
+		assert.False(t, tracingOptions.Enabled)
+		assert.Empty(t, tracingOptions.CustomTags)
+	})
+
+	t.Run("host type and tags", func(t *testing.T) {
+		tracingOptions := configureTracingOptions(&Options{
+			TracingOptions: TracingOptions{
+				HostType: "Kubernetes",
+				Tags:     map[string]string{"Version": "1.2.0", "Service": "checkout"},
+			},
+		})
+
+		assert.True(t, tracingOptions.Enabled)
+		assert.Equal(t, tracing.HostTypeKubernetes, tracingOptions.Host)
+		assert.Equal(t, []string{"Service=checkout", "Version=1.2.0"}, tracingOptions.CustomTags)
+
+		header := tracing.CreateCorrelationContextHeader(context.Background(), tracingOptions)
+		assert.Contains(t, header.Get(tracing.CorrelationContextHeader), "Service=checkout,Version=1.2.0")
+	})
+
+	t.Run("disabled by environment variable", func(t *testing.T) {
+		t.Setenv(tracing.EnvVarTracingDisabled, "true")
+
+		tracingOptions := configureTracingOptions(&Options{
+			TracingOptions: TracingOptions{Tags: map[string]string{"Service": "checkout"}},
+		})
+
+		assert.False(t, tracingOptions.Enabled)
+	})
+}
+
 func TestUnmarshal_FeatureManagement(t *testing.T) {
 	// Setup a feature flag configuration
 	azappcfg := &AzureAppConfiguration{
@@ -1009,7 +1151,7 @@ func TestUnmarshal_FeatureManagement(t *testing.T) {
 
 	// Create a structure to unmarshal into
 	type ConfigWithFeatureManagement struct {
-		FeatureManagement fm.FeatureManagement `json:"feature_management"`
+		FeatureManagement featureflags.FeatureManagement `json:"feature_management"`
 	}
 
 	// Unmarshal into the struct
@@ -1051,7 +1193,7 @@ func TestUnmarshal_FeatureManagement(t *testing.T) {
 	assert.Equal(t, "value-a", variantsFlag.Variants[0].ConfigurationValue)
 	assert.Equal(t, "variantB", variantsFlag.Variants[1].Name)
 	assert.Equal(t, "value-b", variantsFlag.Variants[1].ConfigurationValue)
-	assert.Equal(t, fm.StatusOverrideDisabled, variantsFlag.Variants[1].StatusOverride)
+	assert.Equal(t, featureflags.StatusOverrideDisabled, variantsFlag.Variants[1].StatusOverride)
 
 	// Verify allocation
 	assert.NotNil(t, variantsFlag.Allocation)
@@ -1066,7 +1208,7 @@ func TestUnmarshal_FeatureManagement(t *testing.T) {
 }
 
 // Helper function to find a feature flag by ID
-func findFeatureFlag(flags []fm.FeatureFlag, id string) *fm.FeatureFlag {
+func findFeatureFlag(flags []featureflags.FeatureFlag, id string) *featureflags.FeatureFlag {
 	for i := range flags {
 		if flags[i].ID == id {
 			return &flags[i]
@@ -1113,7 +1255,7 @@ func TestUnmarshal_FeatureFlagsWithKeyValues(t *testing.T) {
 		Version           string
 		Database          Database
 		EnableLogging     bool
-		FeatureManagement fm.FeatureManagement `json:"feature_management"`
+		FeatureManagement featureflags.FeatureManagement `json:"feature_management"`
 	}
 
 	// Unmarshal into the combined struct
@@ -1198,7 +1340,7 @@ func TestUnmarshal_ComplexFeatureFlag(t *testing.T) {
 
 	// Create the struct to unmarshal into
 	var config struct {
-		FeatureManagement fm.FeatureManagement `json:"feature_management"`
+		FeatureManagement featureflags.FeatureManagement `json:"feature_management"`
 	}
 
 	// Unmarshal the data
@@ -1215,7 +1357,7 @@ func TestUnmarshal_ComplexFeatureFlag(t *testing.T) {
 
 	// Check conditions
 	assert.NotNil(t, complexFlag.Conditions)
-	assert.Equal(t, fm.RequirementTypeAll, complexFlag.Conditions.RequirementType)
+	assert.Equal(t, featureflags.RequirementTypeAll, complexFlag.Conditions.RequirementType)
 	assert.Len(t, complexFlag.Conditions.ClientFilters, 2)
 
 	// Check targeting filter
@@ -1244,7 +1386,7 @@ func TestUnmarshal_ComplexFeatureFlag(t *testing.T) {
 }
 
 // Helper function to find a client filter by name
-func findClientFilter(filters []fm.ClientFilter, name string) *fm.ClientFilter {
+func findClientFilter(filters []featureflags.ClientFilter, name string) *featureflags.ClientFilter {
 	for i := range filters {
 		if filters[i].Name == name {
 			return &filters[i]
@@ -1801,3 +1943,711 @@ func TestSelectorComparableKey_Deterministic(t *testing.T) {
 	assert.Equal(t, key1, key2)
 	assert.Equal(t, `["a=first","m=middle","z=last"]`, key1.TagFilters) // Should be sorted
 }
+
+func TestLoadKeyValues_MarksSecretKeys(t *testing.T) {
+	ctx := context.Background()
+	mockSettingsClient := new(mockSettingsClient)
+	mockSecretResolver := new(mockSecretResolver)
+
+	kvReference := `{"uri":"https://myvault.vault.azure.net/secrets/mysecret"}`
+	mockResponse := &settingsResponse{
+		settings: []azappconfig.Setting{
+			{Key: toPtr("plain"), Value: toPtr("value1"), ContentType: toPtr("")},
+			{Key: toPtr("secret"), Value: toPtr(kvReference), ContentType: toPtr(secretReferenceContentType)},
+			{Key: toPtr("password"), Value: toPtr("p@ss"), Tags: map[string]*string{"sensitive": toPtr("True")}},
+			{Key: toPtr("notSensitive"), Value: toPtr("value2"), Tags: map[string]*string{"sensitive": toPtr("false")}},
+		},
+	}
+
+	mockSettingsClient.On("getSettings", ctx).Return(mockResponse, nil)
+	expectedURL, _ := url.Parse("https://myvault.vault.azure.net/secrets/mysecret")
+	mockSecretResolver.On("ResolveSecret", ctx, *expectedURL).Return("resolved-secret", nil)
+
+	azappcfg := &AzureAppConfiguration{
+		kvSelectors: deduplicateSelectors([]Selector{}),
+		keyValues:   make(map[string]any),
+		resolver: &keyVaultReferenceResolver{
+			clients:        sync.Map{},
+			secretResolver: mockSecretResolver,
+		},
+	}
+
+	err := azappcfg.loadKeyValues(ctx, mockSettingsClient)
+	assert.NoError(t, err)
+	assert.True(t, azappcfg.IsSecret("secret"))
+	assert.True(t, azappcfg.IsSecret("password"))
+	assert.False(t, azappcfg.IsSecret("plain"))
+	assert.False(t, azappcfg.IsSecret("notSensitive"))
+	assert.False(t, azappcfg.IsSecret("missing"))
+}
+
+func TestGetBytes_RedactSecrets(t *testing.T) {
+	azappcfg := &AzureAppConfiguration{
+		keyValues: map[string]any{
+			"Database.Host":     "localhost",
+			"Database.Password": "p@ss",
+			"Credentials":       map[string]any{"token": "abc"},
+		},
+		secretKeys: map[string]struct{}{
+			"Database.Password": {},
+			"Credentials":       {},
+		},
+		featureFlags: make(map[string]any),
+	}
+
+	bytes, err := azappcfg.GetBytes(&ConstructionOptions{RedactSecrets: true})
+	assert.NoError(t, err)
+	assert.NotContains(t, string(bytes), "p@ss")
+	assert.NotContains(t, string(bytes), "abc")
+
+	var result map[string]any
+	assert.NoError(t, json.Unmarshal(bytes, &result))
+	assert.Equal(t, redactedValue, result["Database"].(map[string]any)["Password"])
+	assert.Equal(t, "localhost", result["Database"].(map[string]any)["Host"])
+	assert.Equal(t, redactedValue, result["Credentials"])
+
+	// Secrets are returned as-is unless redaction is requested
+	bytes, err = azappcfg.GetBytes(&ConstructionOptions{Separator: "."})
+	assert.NoError(t, err)
+	assert.Contains(t, string(bytes), "p@ss")
+}
+
+func TestUnmarshal_RedactSecrets(t *testing.T) {
+	azappcfg := &AzureAppConfiguration{
+		keyValues: map[string]any{
+			"Database:Host":     "localhost",
+			"Database:Password": "p@ss",
+		},
+		secretKeys: map[string]struct{}{
+			"Database:Password": {},
+		},
+	}
+
+	type Config struct {
+		Database struct {
+			Host     string
+			Password string
+		}
+	}
+
+	var config Config
+	err := azappcfg.Unmarshal(&config, &ConstructionOptions{Separator: ":", RedactSecrets: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "localhost", config.Database.Host)
+	assert.Equal(t, redactedValue, config.Database.Password)
+
+	err = azappcfg.Unmarshal(&config, &ConstructionOptions{Separator: "|", RedactSecrets: true})
+	assert.Error(t, err)
+}
+
+func TestLoadFeatureFlags_TypedFeatureFlags(t *testing.T) {
+	ctx := context.Background()
+	mockClient := new(mockSettingsClient)
+
+	mockResponse := &settingsResponse{
+		settings: []azappconfig.Setting{
+			{Key: toPtr(".appconfig.featureflag/Beta"), Value: toPtr(`{"id": "Beta", "enabled": false}`), ContentType: toPtr(featureFlagContentType)},
+			{Key: toPtr(".appconfig.featureflag/Alpha"), Value: toPtr(`{"id": "Alpha", "enabled": true}`), ContentType: toPtr(featureFlagContentType)},
+			{Key: toPtr(".appconfig.featureflag/Invalid"), Value: toPtr(`{"id": "Invalid", "conditions": {"requirement_type": "Some"}}`), ContentType: toPtr(featureFlagContentType)},
+			{Key: toPtr(".appconfig.featureflag/Mismatch"), Value: toPtr(`{"id": "Mismatch", "enabled": "yes"}`), ContentType: toPtr(featureFlagContentType)},
+			// The same flag loaded by a later selector takes precedence
+			{Key: toPtr(".appconfig.featureflag/Beta"), Value: toPtr(`{"id": "Beta", "enabled": true}`), ContentType: toPtr(featureFlagContentType)},
+		},
+	}
+
+	mockClient.On("getSettings", ctx).Return(mockResponse, nil)
+
+	azappcfg := &AzureAppConfiguration{
+		ffSelectors:        getFeatureFlagSelectors([]Selector{}),
+		ffValidationPolicy: FeatureFlagValidationPolicyDrop,
+		featureFlags:       make(map[string]any),
+	}
+
+	err := azappcfg.loadFeatureFlags(ctx, mockClient)
+	assert.NoError(t, err)
+
+	featureFlags := azappcfg.FeatureFlags()
+	assert.Len(t, featureFlags, 2)
+	assert.Equal(t, "Beta", featureFlags[0].ID)
+	assert.True(t, featureFlags[0].Enabled)
+	assert.Equal(t, "Alpha", featureFlags[1].ID)
+
+	alpha, ok := azappcfg.FeatureFlag("Alpha")
+	assert.True(t, ok)
+	assert.True(t, alpha.Enabled)
+
+	_, ok = azappcfg.FeatureFlag("Invalid")
+	assert.False(t, ok)
+	_, ok = azappcfg.FeatureFlag("Mismatch")
+	assert.False(t, ok)
+}
+
+func TestLoadFeatureFlags_Precedence(t *testing.T) {
+	ctx := context.Background()
+	mockClient := new(mockSettingsClient)
+
+	// Settings are returned in selector order: the flags without label first, then the flags labeled "prod"
+	mockResponse := &settingsResponse{
+		settings: []azappconfig.Setting{
+			{Key: toPtr(".appconfig.featureflag/Gamma"), Value: toPtr(`{"id": "Gamma", "enabled": true}`), ContentType: toPtr(featureFlagContentType)},
+			{Key: toPtr(".appconfig.featureflag/Beta"), Value: toPtr(`{"id": "Beta", "enabled": false}`), ContentType: toPtr(featureFlagContentType)},
+			{Key: toPtr(".appconfig.featureflag/Alpha"), Value: toPtr(`{"id": "Alpha", "enabled": false}`), ContentType: toPtr(featureFlagContentType)},
+			{Key: toPtr(".appconfig.featureflag/Beta"), Label: toPtr("prod"), Value: toPtr(`{"id": "Beta", "enabled": true}`), ContentType: toPtr(featureFlagContentType)},
+			{Key: toPtr(".appconfig.featureflag/Delta"), Label: toPtr("prod"), Value: toPtr(`{"enabled": true}`), ContentType: toPtr(featureFlagContentType)},
+		},
+	}
+
+	mockClient.On("getSettings", ctx).Return(mockResponse, nil)
+
+	azappcfg := &AzureAppConfiguration{featureFlags: make(map[string]any)}
+
+	for i := 0; i < 3; i++ {
+		err := azappcfg.loadFeatureFlags(ctx, mockClient)
+		assert.NoError(t, err)
+
+		// The order is stable across loads and follows the first occurrence of each feature flag
+		rawFeatureFlags := azappcfg.featureFlags[featureManagementSectionKey].(map[string]any)[featureFlagSectionKey].([]any)
+		assert.Len(t, rawFeatureFlags, 4)
+		assert.Equal(t, "Gamma", rawFeatureFlags[0].(map[string]any)["id"])
+		assert.Equal(t, "Beta", rawFeatureFlags[1].(map[string]any)["id"])
+		assert.Equal(t, true, rawFeatureFlags[1].(map[string]any)["enabled"])
+		assert.Equal(t, "Alpha", rawFeatureFlags[2].(map[string]any)["id"])
+		assert.NotContains(t, rawFeatureFlags[3].(map[string]any), "id")
+	}
+
+	beta, ok := azappcfg.FeatureFlag("Beta")
+	assert.True(t, ok)
+	assert.True(t, beta.Enabled)
+
+	label, ok := azappcfg.FeatureFlagLabel("Beta")
+	assert.True(t, ok)
+	assert.Equal(t, "prod", label)
+
+	label, ok = azappcfg.FeatureFlagLabel("Alpha")
+	assert.True(t, ok)
+	assert.Empty(t, label)
+
+	// The feature flag without ID is identified by its key
+	label, ok = azappcfg.FeatureFlagLabel("Delta")
+	assert.True(t, ok)
+	assert.Equal(t, "prod", label)
+
+	_, ok = azappcfg.FeatureFlagLabel("Missing")
+	assert.False(t, ok)
+}
+
+func TestLoadFeatureFlags_ValidationPolicy(t *testing.T) {
+	settings := []azappconfig.Setting{
+		{Key: toPtr(".appconfig.featureflag/Alpha"), Value: toPtr(`{"id": "Alpha", "enabled": true}`), ContentType: toPtr(featureFlagContentType)},
+		{
+			Key:         toPtr(".appconfig.featureflag/Overlap"),
+			Label:       toPtr("dev"),
+			Value:       toPtr(`{"id": "Overlap", "enabled": true, "variants": [{"name": "A"}], "allocation": {"percentile": [{"variant": "A", "from": 0, "to": 60}, {"variant": "B", "from": 50, "to": 100}]}}`),
+			ContentType: toPtr(featureFlagContentType),
+		},
+		{Key: toPtr(".appconfig.featureflag/Mismatch"), Value: toPtr(`{"id": "Mismatch", "enabled": "yes"}`), ContentType: toPtr(featureFlagContentType)},
+		{Key: toPtr(".appconfig.featureflag/Broken"), Value: toPtr(`{"id": "Broken"`), ContentType: toPtr(featureFlagContentType)},
+	}
+
+	loadedIDs := func(azappcfg *AzureAppConfiguration) []string {
+		ids := make([]string, 0)
+		for _, featureFlag := range azappcfg.featureFlags[featureManagementSectionKey].(map[string]any)[featureFlagSectionKey].([]any) {
+			ids = append(ids, featureFlag.(map[string]any)["id"].(string))
+		}
+		return ids
+	}
+
+	t.Run("warn keeps invalid feature flags", func(t *testing.T) {
+		ctx := context.Background()
+		mockClient := new(mockSettingsClient)
+		mockClient.On("getSettings", ctx).Return(&settingsResponse{settings: settings}, nil)
+		azappcfg := &AzureAppConfiguration{featureFlags: make(map[string]any)}
+
+		assert.NoError(t, azappcfg.loadFeatureFlags(ctx, mockClient))
+		// A feature flag which cannot be decoded into the schema is dropped regardless of the policy
+		assert.Equal(t, []string{"Alpha", "Overlap"}, loadedIDs(azappcfg))
+
+		_, ok := azappcfg.FeatureFlag("Overlap")
+		assert.True(t, ok)
+		_, ok = azappcfg.FeatureFlag("Mismatch")
+		assert.False(t, ok)
+
+		diagnostics := azappcfg.FeatureFlagDiagnostics()
+		assert.Len(t, diagnostics, 3)
+		assert.Equal(t, ".appconfig.featureflag/Overlap", diagnostics[0].Key)
+		assert.Equal(t, "dev", diagnostics[0].Label)
+		assert.Equal(t, "Overlap", diagnostics[0].FeatureID)
+		assert.Len(t, diagnostics[0].Violations, 2)
+		assert.Contains(t, diagnostics[0].Violations[0], "undefined variant 'B'")
+		assert.Contains(t, diagnostics[0].Violations[1], "overlap")
+		assert.False(t, diagnostics[0].Dropped)
+		assert.Equal(t, "Mismatch", diagnostics[1].FeatureID)
+		assert.True(t, diagnostics[1].Dropped)
+		assert.Equal(t, ".appconfig.featureflag/Broken", diagnostics[2].Key)
+		assert.Contains(t, diagnostics[2].Violations[0], "invalid JSON")
+		assert.True(t, diagnostics[2].Dropped)
+	})
+
+	t.Run("drop excludes invalid feature flags", func(t *testing.T) {
+		ctx := context.Background()
+		mockClient := new(mockSettingsClient)
+		mockClient.On("getSettings", ctx).Return(&settingsResponse{settings: settings}, nil)
+		azappcfg := &AzureAppConfiguration{
+			ffValidationPolicy: FeatureFlagValidationPolicyDrop,
+			featureFlags:       make(map[string]any),
+		}
+
+		assert.NoError(t, azappcfg.loadFeatureFlags(ctx, mockClient))
+		assert.Equal(t, []string{"Alpha"}, loadedIDs(azappcfg))
+		_, ok := azappcfg.FeatureFlag("Overlap")
+		assert.False(t, ok)
+
+		diagnostics := azappcfg.FeatureFlagDiagnostics()
+		assert.Len(t, diagnostics, 3)
+		for _, diagnostic := range diagnostics {
+			assert.True(t, diagnostic.Dropped)
+		}
+	})
+
+	t.Run("fail rejects the load", func(t *testing.T) {
+		ctx := context.Background()
+		mockClient := new(mockSettingsClient)
+		mockClient.On("getSettings", ctx).Return(&settingsResponse{settings: settings}, nil)
+		azappcfg := &AzureAppConfiguration{
+			ffValidationPolicy: FeatureFlagValidationPolicyFail,
+			featureFlags:       make(map[string]any),
+		}
+
+		err := azappcfg.loadFeatureFlags(ctx, mockClient)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "key=.appconfig.featureflag/Overlap, label=dev")
+		var diagnostic FeatureFlagDiagnostic
+		assert.ErrorAs(t, err, &diagnostic)
+		assert.Empty(t, azappcfg.FeatureFlags())
+	})
+
+	t.Run("dropped duplicate keeps the earlier feature flag", func(t *testing.T) {
+		ctx := context.Background()
+		mockClient := new(mockSettingsClient)
+		mockClient.On("getSettings", ctx).Return(&settingsResponse{settings: []azappconfig.Setting{
+			settings[0],
+			{Key: toPtr(".appconfig.featureflag/Alpha"), Label: toPtr("dev"), Value: toPtr(`{"id": "Alpha"`), ContentType: toPtr(featureFlagContentType)},
+			{Key: toPtr(".appconfig.featureflag/Alpha"), Label: toPtr("test"), Value: toPtr(`{"id": "Alpha", "enabled": "yes"}`), ContentType: toPtr(featureFlagContentType)},
+		}}, nil)
+		azappcfg := &AzureAppConfiguration{featureFlags: make(map[string]any)}
+
+		assert.NoError(t, azappcfg.loadFeatureFlags(ctx, mockClient))
+		assert.Equal(t, []string{"Alpha"}, loadedIDs(azappcfg))
+		alpha, ok := azappcfg.FeatureFlag("Alpha")
+		assert.True(t, ok)
+		assert.True(t, alpha.Enabled)
+		label, _ := azappcfg.FeatureFlagLabel("Alpha")
+		assert.Empty(t, label)
+		assert.Len(t, azappcfg.FeatureFlagDiagnostics(), 2)
+	})
+
+	t.Run("valid feature flags have no diagnostics", func(t *testing.T) {
+		ctx := context.Background()
+		mockClient := new(mockSettingsClient)
+		mockClient.On("getSettings", ctx).Return(&settingsResponse{settings: settings[:1]}, nil)
+		azappcfg := &AzureAppConfiguration{
+			ffValidationPolicy: FeatureFlagValidationPolicyFail,
+			featureFlags:       make(map[string]any),
+		}
+
+		assert.NoError(t, azappcfg.loadFeatureFlags(ctx, mockClient))
+		assert.NotNil(t, azappcfg.FeatureFlagDiagnostics())
+		assert.Empty(t, azappcfg.FeatureFlagDiagnostics())
+	})
+}
+
+func TestLoadFeatureFlags_TelemetryMetadata(t *testing.T) {
+	ctx := context.Background()
+	mockClient := new(mockSettingsClient)
+	eTag := azcore.ETag("etag-1")
+
+	mockResponse := &settingsResponse{
+		settings: []azappconfig.Setting{
+			{
+				Key:         toPtr(".appconfig.featureflag/Beta"),
+				Label:       toPtr("dev"),
+				ETag:        &eTag,
+				Value:       toPtr(`{"id": "Beta", "enabled": true, "telemetry": {"enabled": true, "metadata": {"Team": "contoso"}}, "allocation": {"seed": "abc"}}`),
+				ContentType: toPtr(featureFlagContentType),
+			},
+			{
+				Key:         toPtr(".appconfig.featureflag/Alpha"),
+				ETag:        &eTag,
+				Value:       toPtr(`{"id": "Alpha", "enabled": true, "telemetry": {"enabled": false}}`),
+				ContentType: toPtr(featureFlagContentType),
+			},
+		},
+	}
+
+	mockClient.On("getSettings", ctx).Return(mockResponse, nil)
+
+	azappcfg := &AzureAppConfiguration{
+		clientManager: &configurationClientManager{endpoint: "https://test.azconfig.io"},
+		ffEnabled:     true,
+		ffSelectors:   getFeatureFlagSelectors([]Selector{}),
+		featureFlags:  make(map[string]any),
+	}
+
+	err := azappcfg.loadFeatureFlags(ctx, mockClient)
+	assert.NoError(t, err)
+
+	beta, ok := azappcfg.FeatureFlag("Beta")
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{
+		"Team":                 "contoso",
+		"ETag":                 "etag-1",
+		"FeatureFlagReference": "https://test.azconfig.io/kv/.appconfig.featureflag/Beta?label=dev",
+		"AllocationId":         "DSx_SQuHmJAouZZpGxNq",
+	}, beta.Telemetry.Metadata)
+
+	alpha, ok := azappcfg.FeatureFlag("Alpha")
+	assert.True(t, ok)
+	assert.Empty(t, alpha.Telemetry.Metadata)
+
+	// The metadata is also part of the feature management section of the configuration
+	var config map[string]any
+	data, err := azappcfg.GetBytes(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(data, &config))
+	for _, featureFlag := range config["feature_management"].(map[string]any)["feature_flags"].([]any) {
+		ff := featureFlag.(map[string]any)
+		if ff["id"] == "Beta" {
+			metadata := ff["telemetry"].(map[string]any)["metadata"].(map[string]any)
+			assert.Equal(t, "etag-1", metadata["ETag"])
+			assert.Equal(t, "DSx_SQuHmJAouZZpGxNq", metadata["AllocationId"])
+		}
+	}
+}
+
+func TestFeatureFlags_NotLoaded(t *testing.T) {
+	azappcfg := &AzureAppConfiguration{}
+
+	assert.Empty(t, azappcfg.FeatureFlags())
+	_, ok := azappcfg.FeatureFlag("Beta")
+	assert.False(t, ok)
+}
+
+func TestIsEnabled(t *testing.T) {
+	azappcfg := &AzureAppConfiguration{
+		featureFlagsByID: map[string]featureflags.FeatureFlag{
+			"Beta": {ID: "Beta", Enabled: true},
+			"TargetingTestFeature": {
+				ID:      "TargetingTestFeature",
+				Enabled: true,
+				Conditions: &featureflags.Conditions{
+					ClientFilters: []featureflags.ClientFilter{
+						{
+							Name: featureflags.TargetingFilterName,
+							Parameters: map[string]any{
+								"Audience": map[string]any{"Users": []any{"Jeff"}},
+							},
+						},
+					},
+				},
+			},
+		},
+		featureFlagIDs: []string{"Beta", "TargetingTestFeature"},
+	}
+
+	enabled, err := azappcfg.IsEnabled(context.Background(), "Beta", featureflags.TargetingContext{})
+	assert.NoError(t, err)
+	assert.True(t, enabled)
+
+	enabled, err = azappcfg.IsEnabled(context.Background(), "TargetingTestFeature", featureflags.TargetingContext{UserID: "Jeff"})
+	assert.NoError(t, err)
+	assert.True(t, enabled)
+
+	enabled, err = azappcfg.IsEnabled(context.Background(), "TargetingTestFeature", featureflags.TargetingContext{UserID: "Anne"})
+	assert.NoError(t, err)
+	assert.False(t, enabled)
+
+	enabled, err = azappcfg.IsEnabled(context.Background(), "Missing", featureflags.TargetingContext{})
+	assert.NoError(t, err)
+	assert.False(t, enabled)
+}
+
+func TestGetVariant(t *testing.T) {
+	azappcfg := &AzureAppConfiguration{
+		featureFlagsByID: map[string]featureflags.FeatureFlag{
+			"Beta": {
+				ID:       "Beta",
+				Enabled:  true,
+				Variants: []featureflags.VariantDefinition{{Name: "Big", ConfigurationValue: "big"}, {Name: "Small", ConfigurationValue: "small"}},
+				Allocation: &featureflags.VariantAllocation{
+					DefaultWhenEnabled: "Small",
+					User:               []featureflags.UserAllocation{{Variant: "Big", Users: []string{"Jeff"}}},
+				},
+			},
+		},
+		featureFlagIDs: []string{"Beta"},
+	}
+
+	variant, err := azappcfg.GetVariant(context.Background(), "Beta", featureflags.TargetingContext{UserID: "Jeff"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Big", variant.Name)
+	assert.Equal(t, "big", variant.ConfigurationValue)
+	assert.Equal(t, featureflags.VariantAssignmentReasonUser, variant.Reason)
+
+	variant, err = azappcfg.GetVariant(context.Background(), "Beta", featureflags.TargetingContext{UserID: "Anne"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Small", variant.Name)
+	assert.Equal(t, featureflags.VariantAssignmentReasonDefaultWhenEnabled, variant.Reason)
+
+	variant, err = azappcfg.GetVariant(context.Background(), "Missing", featureflags.TargetingContext{})
+	assert.NoError(t, err)
+	assert.Nil(t, variant)
+}
+
+type regionFilter struct {
+	region string
+}
+
+func (f *regionFilter) Name() string {
+	return "Contoso.Region"
+}
+
+func (f *regionFilter) Evaluate(ctx context.Context, parameters map[string]any, appContext any) (bool, error) {
+	regions, _ := parameters["Regions"].([]any)
+	for _, region := range regions {
+		if region == f.region {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestIsEnabledWithAppContext_CustomFilter(t *testing.T) {
+	featureFlag := featureflags.FeatureFlag{
+		ID:      "Beta",
+		Enabled: true,
+		Conditions: &featureflags.Conditions{
+			ClientFilters: []featureflags.ClientFilter{{Name: "Contoso.Region", Parameters: map[string]any{"Regions": []any{"westus"}}}},
+		},
+	}
+
+	newProvider := func(options featureflags.EvaluatorOptions) *AzureAppConfiguration {
+		evaluator, err := featureflags.NewEvaluator(&options)
+		assert.NoError(t, err)
+		return &AzureAppConfiguration{
+			featureFlagsByID: map[string]featureflags.FeatureFlag{"Beta": featureFlag},
+			featureFlagIDs:   []string{"Beta"},
+			evaluator:        evaluator,
+		}
+	}
+
+	azappcfg := newProvider(featureflags.EvaluatorOptions{Filters: []featureflags.FeatureFilter{&regionFilter{region: "westus"}}})
+	enabled, err := azappcfg.IsEnabledWithAppContext(context.Background(), "Beta", nil)
+	assert.NoError(t, err)
+	assert.True(t, enabled)
+
+	azappcfg = newProvider(featureflags.EvaluatorOptions{Filters: []featureflags.FeatureFilter{&regionFilter{region: "eastus"}}})
+	enabled, err = azappcfg.IsEnabled(context.Background(), "Beta", featureflags.TargetingContext{})
+	assert.NoError(t, err)
+	assert.False(t, enabled)
+
+	// Unregistered filter
+	azappcfg = newProvider(featureflags.EvaluatorOptions{})
+	_, err = azappcfg.IsEnabled(context.Background(), "Beta", featureflags.TargetingContext{})
+	assert.ErrorIs(t, err, featureflags.ErrFeatureFilterNotRegistered)
+
+	azappcfg = newProvider(featureflags.EvaluatorOptions{MissingFilterPolicy: featureflags.MissingFilterPolicyIgnore})
+	enabled, err = azappcfg.IsEnabled(context.Background(), "Beta", featureflags.TargetingContext{})
+	assert.NoError(t, err)
+	assert.False(t, enabled)
+}
+
+func TestLoad_InvalidFeatureFilters(t *testing.T) {
+	authOptions := AuthenticationOptions{
+		ConnectionString: "Endpoint=https://test.azconfig.io;Id=test-id;Secret=dGVzdA==",
+	}
+	options := &Options{
+		FeatureFlagOptions: FeatureFlagOptions{
+			Enabled: true,
+			Filters: []featureflags.FeatureFilter{&regionFilter{}, &regionFilter{}},
+		},
+	}
+
+	_, err := Load(context.Background(), authOptions, options)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid feature flag options")
+}
+
+func TestLoadAsync_InvalidOptions(t *testing.T) {
+	azappcfg, err := LoadAsync(context.Background(), AuthenticationOptions{}, nil)
+
+	assert.Nil(t, azappcfg)
+	assert.Error(t, err)
+}
+
+func TestReady_Load(t *testing.T) {
+	azappcfg := &AzureAppConfiguration{}
+
+	select {
+	case <-azappcfg.Ready():
+	default:
+		t.Fatal("Ready channel should be closed for an instance created by Load")
+	}
+	assert.NoError(t, azappcfg.Err())
+}
+
+func TestStartAsync_Success(t *testing.T) {
+	azappcfg := &AzureAppConfiguration{
+		kvRefreshTimer: &mockRefreshCondition{shouldRefresh: true},
+	}
+
+	release := make(chan struct{})
+	operation := func(ctx context.Context) error {
+		<-release
+		azappcfg.keyValues = map[string]any{"App.Name": "loaded", "App.Timeout": 30}
+		return nil
+	}
+
+	callbackCount := 0
+	azappcfg.startAsync(context.Background(), StartupOptions{
+		InitialKeyValues: map[string]any{"App.Name": "initial"},
+	}, operation)
+	azappcfg.OnRefreshSuccess(func() {
+		callbackCount++
+	})
+
+	type config struct {
+		App struct {
+			Name    string
+			Timeout int
+		}
+	}
+
+	// The initial key-values are served until the first load succeeds
+	var initial config
+	assert.NoError(t, azappcfg.Unmarshal(&initial, nil))
+	assert.Equal(t, "initial", initial.App.Name)
+	assert.Equal(t, 0, initial.App.Timeout)
+	assert.NoError(t, azappcfg.Err())
+
+	// Refresh has no effect before the first load succeeds
+	assert.NoError(t, azappcfg.Refresh(context.Background()))
+
+	close(release)
+	select {
+	case <-azappcfg.Ready():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Ready channel should be closed after the first load")
+	}
+
+	var loaded config
+	assert.NoError(t, azappcfg.Err())
+	assert.NoError(t, azappcfg.Unmarshal(&loaded, nil))
+	assert.Equal(t, "loaded", loaded.App.Name)
+	assert.Equal(t, 30, loaded.App.Timeout)
+	assert.Equal(t, 1, callbackCount)
+	assert.True(t, azappcfg.tracingOptions.InitialLoadFinished)
+}
+
+func TestStartAsync_ConcurrentReads(t *testing.T) {
+	kvClient := new(mockSettingsClient)
+	kvClient.On("getSettings", mock.Anything).Return(&settingsResponse{
+		settings: []azappconfig.Setting{
+			{Key: toPtr("App.Name"), Value: toPtr("loaded")},
+			{Key: toPtr("App.Password"), Value: toPtr("secret"), Tags: map[string]*string{sensitiveTagName: toPtr("true")}},
+		},
+	}, nil)
+	ffClient := new(mockSettingsClient)
+	ffClient.On("getSettings", mock.Anything).Return(&settingsResponse{
+		settings: []azappconfig.Setting{
+			{Key: toPtr(".appconfig.featureflag/Beta"), Value: toPtr(`{"id": "Beta", "enabled": true}`), ContentType: toPtr(featureFlagContentType)},
+		},
+	}, nil)
+
+	azappcfg := &AzureAppConfiguration{
+		ffEnabled:    true,
+		featureFlags: make(map[string]any),
+	}
+
+	// The load starts once the instance is read and publishes the loaded settings repeatedly while it is read
+	reading := make(chan struct{})
+	operation := func(ctx context.Context) error {
+		<-reading
+		for i := 0; i < 20; i++ {
+			if err := azappcfg.loadKeyValues(ctx, kvClient); err != nil {
+				return err
+			}
+			if err := azappcfg.loadFeatureFlags(ctx, ffClient); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	azappcfg.startAsync(context.Background(), StartupOptions{
+		InitialKeyValues: map[string]any{"App.Name": "initial"},
+	}, operation)
+
+	var once sync.Once
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				_, err := azappcfg.GetBytes(&ConstructionOptions{RedactSecrets: true})
+				assert.NoError(t, err)
+				_, err = azappcfg.IsEnabled(context.Background(), "Beta", featureflags.TargetingContext{})
+				assert.NoError(t, err)
+				azappcfg.IsSecret("App.Password")
+				azappcfg.FeatureFlags()
+				azappcfg.FeatureFlagDiagnostics()
+				once.Do(func() { close(reading) })
+
+				select {
+				case <-azappcfg.Ready():
+					return
+				default:
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.NoError(t, azappcfg.Err())
+	bytes, err := azappcfg.GetBytes(&ConstructionOptions{RedactSecrets: true})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"App":{"Name":"loaded","Password":"`+redactedValue+`"},"feature_management":{"feature_flags":[{"id":"Beta","enabled":true}]}}`, string(bytes))
+	enabled, err := azappcfg.IsEnabled(context.Background(), "Beta", featureflags.TargetingContext{})
+	assert.NoError(t, err)
+	assert.True(t, enabled)
+	assert.True(t, azappcfg.IsSecret("App.Password"))
+}
+
+func TestStartAsync_Failure(t *testing.T) {
+	azappcfg := &AzureAppConfiguration{
+		kvRefreshTimer: &mockRefreshCondition{shouldRefresh: true},
+	}
+
+	operation := func(ctx context.Context) error {
+		return &azcore.ResponseError{StatusCode: http.StatusBadRequest}
+	}
+
+	callbackCount := 0
+	azappcfg.OnRefreshSuccess(func() {
+		callbackCount++
+	})
+	azappcfg.startAsync(context.Background(), StartupOptions{
+		InitialKeyValues: map[string]any{"App.Name": "initial"},
+	}, operation)
+
+	select {
+	case <-azappcfg.Ready():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Ready channel should be closed after the first load fails")
+	}
+
+	assert.ErrorContains(t, azappcfg.Err(), "non-retriable error")
+	assert.ErrorContains(t, azappcfg.Refresh(context.Background()), "failed to load configuration on startup")
+	assert.Equal(t, 0, callbackCount)
+	assert.Equal(t, map[string]any{"App.Name": "initial"}, azappcfg.keyValues)
+}
@@ -0,0 +1,187 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package azureappconfiguration
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azappconfig/v2"
+)
+
+// circuitBreaker is the circuit breaker configuration shared by the clients of a client manager
+type circuitBreaker struct {
+	failureThreshold int
+	openDuration     time.Duration
+	onStateChange    func(CircuitStateChange)
+	probe            func(ctx context.Context, client *azappconfig.Client) error
+	logger           *slog.Logger
+}
+
+// newCircuitBreaker creates the circuit breaker configuration, which is nil if the circuit breaker is not enabled
+func newCircuitBreaker(options CircuitBreakerOptions) *circuitBreaker {
+	if !options.Enabled {
+		return nil
+	}
+
+	breaker := &circuitBreaker{
+		failureThreshold: options.FailureThreshold,
+		openDuration:     options.OpenDuration,
+		onStateChange:    options.OnStateChange,
+		probe:            probeClient,
+	}
+
+	if breaker.failureThreshold <= 0 {
+		breaker.failureThreshold = defaultCircuitBreakerFailureThreshold
+	}
+
+	return breaker
+}
+
+// getOpenDuration returns how long the circuit stays open after the failed attempts
+func (breaker *circuitBreaker) getOpenDuration(failedAttempts int) time.Duration {
+	if breaker.openDuration > 0 {
+		return breaker.openDuration
+	}
+
+	return calculateBackoffDuration(failedAttempts - breaker.failureThreshold + 1)
+}
+
+// notify invokes the state change callback, which must not be called while the client is locked
+func (breaker *circuitBreaker) notify(change *CircuitStateChange) {
+	if change == nil || breaker.onStateChange == nil {
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			getLogger(breaker.logger).Error("Panic in circuit breaker state change callback", "endpoint", change.Endpoint, "panic", r)
+		}
+	}()
+
+	breaker.onStateChange(*change)
+}
+
+// probeClient sends a lightweight request to check if the endpoint of the client is available
+func probeClient(ctx context.Context, client *azappconfig.Client) error {
+	_, err := client.GetSetting(ctx, circuitBreakerProbeKey, nil)
+	return err
+}
+
+// beginRequest checks if a request can be sent to the client and whether a probe must be sent before the request.
+// An open circuit becomes half-open once its open duration elapses, and only one probe is in flight for a half-open circuit.
+func (client *configurationClientWrapper) beginRequest(now time.Time) (probe bool, ok bool) {
+	if client.breaker == nil {
+		return false, true
+	}
+
+	client.mu.Lock()
+	var change *CircuitStateChange
+	switch client.circuitState {
+	case CircuitStateOpen:
+		if now.After(client.backOffEndTime) {
+			change = client.setCircuitState(CircuitStateHalfOpen, nil)
+			client.probeInFlight = true
+			probe, ok = true, true
+		}
+	case CircuitStateHalfOpen:
+		if !client.probeInFlight {
+			client.probeInFlight = true
+			probe, ok = true, true
+		}
+	default:
+		ok = true
+	}
+	client.mu.Unlock()
+
+	client.breaker.notify(change)
+	return probe, ok
+}
+
+// recordProbeResult closes the circuit if the probe succeeded, or opens it again if the probe failed
+func (client *configurationClientWrapper) recordProbeResult(err error) {
+	client.mu.Lock()
+	client.probeInFlight = false
+	var change *CircuitStateChange
+	if err == nil {
+		client.failedAttempts = 0
+		client.backOffEndTime = time.Time{}
+		change = client.setCircuitState(CircuitStateClosed, nil)
+	} else {
+		client.lastError = err
+		change = client.recordCircuitFailure(err)
+	}
+	client.mu.Unlock()
+
+	client.breaker.notify(change)
+}
+
+// releaseProbe ends the probe in flight without recording its result, so that the circuit stays half-open
+// and the next request probes the endpoint again
+func (client *configurationClientWrapper) releaseProbe() {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	client.probeInFlight = false
+}
+
+// isProbeSuccessful checks if the probe reached the endpoint. The probe key does not exist,
+// so a "not found" response indicates that the endpoint is available.
+func isProbeSuccessful(err error) bool {
+	if err == nil {
+		return true
+	}
+
+	var respErr *azcore.ResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound
+}
+
+// recordCircuitFailure counts a failure and opens the circuit if the failure threshold is reached
+// or if the circuit is half-open. The client must be locked.
+func (client *configurationClientWrapper) recordCircuitFailure(err error) *CircuitStateChange {
+	now := time.Now()
+	client.failedAttempts++
+	client.lastFailureTime = now
+	if client.circuitState == CircuitStateHalfOpen || client.failedAttempts >= client.breaker.failureThreshold {
+		client.backOffEndTime = now.Add(client.breaker.getOpenDuration(client.failedAttempts))
+		return client.setCircuitState(CircuitStateOpen, err)
+	}
+
+	return nil
+}
+
+// setCircuitState changes the state of the circuit and returns the change, which is nil if the state does not change.
+// The client must be locked.
+func (client *configurationClientWrapper) setCircuitState(state CircuitState, err error) *CircuitStateChange {
+	from := client.getCircuitState()
+	if from == state {
+		return nil
+	}
+
+	client.circuitState = state
+	return &CircuitStateChange{
+		Endpoint: client.endpoint,
+		From:     from,
+		To:       state,
+		Error:    err,
+	}
+}
+
+// getCircuitState returns the state of the circuit, which is empty if the circuit breaker is not enabled.
+// The client must be locked.
+func (client *configurationClientWrapper) getCircuitState() CircuitState {
+	if client.breaker == nil {
+		return ""
+	}
+
+	if client.circuitState == "" {
+		return CircuitStateClosed
+	}
+
+	return client.circuitState
+}
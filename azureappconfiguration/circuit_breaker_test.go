@@ -0,0 +1,307 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package azureappconfiguration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azappconfig/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCircuitBreaker(changes *[]CircuitStateChange) *circuitBreaker {
+	return &circuitBreaker{
+		failureThreshold: 2,
+		openDuration:     time.Minute,
+		onStateChange: func(change CircuitStateChange) {
+			*changes = append(*changes, change)
+		},
+		probe: probeClient,
+	}
+}
+
+func TestNewCircuitBreaker(t *testing.T) {
+	assert.Nil(t, newCircuitBreaker(CircuitBreakerOptions{FailureThreshold: 5}))
+
+	breaker := newCircuitBreaker(CircuitBreakerOptions{Enabled: true})
+	require.NotNil(t, breaker)
+	assert.Equal(t, defaultCircuitBreakerFailureThreshold, breaker.failureThreshold)
+	assert.NotNil(t, breaker.probe)
+
+	// The open duration grows exponentially with the failures beyond the threshold if not provided
+	assert.Equal(t, minBackoffDuration, breaker.getOpenDuration(defaultCircuitBreakerFailureThreshold))
+	breaker.openDuration = time.Minute
+	assert.Equal(t, time.Minute, breaker.getOpenDuration(10))
+}
+
+func TestCircuitBreaker_Transitions(t *testing.T) {
+	var changes []CircuitStateChange
+	clientWrapper := &configurationClientWrapper{
+		endpoint: "https://primary.azconfig.io",
+		breaker:  newTestCircuitBreaker(&changes),
+	}
+	requestError := &azcore.ResponseError{StatusCode: http.StatusServiceUnavailable}
+	now := time.Now()
+
+	// The circuit stays closed below the failure threshold
+	clientWrapper.recordFailure(requestError)
+	assert.Equal(t, CircuitStateClosed, clientWrapper.getStatus(now).CircuitState)
+	assert.True(t, clientWrapper.isAvailable(now))
+	assert.Empty(t, changes)
+
+	// The circuit opens when the failure threshold is reached
+	clientWrapper.recordFailure(requestError)
+	status := clientWrapper.getStatus(now)
+	assert.Equal(t, CircuitStateOpen, status.CircuitState)
+	assert.True(t, status.InBackoff)
+	assert.False(t, clientWrapper.isAvailable(now))
+	_, ok := clientWrapper.beginRequest(now)
+	assert.False(t, ok)
+
+	// The circuit becomes half-open once the open duration elapses, and only one probe is allowed
+	later := now.Add(2 * time.Minute)
+	assert.True(t, clientWrapper.isAvailable(later))
+	probe, ok := clientWrapper.beginRequest(later)
+	assert.True(t, ok)
+	assert.True(t, probe)
+	assert.False(t, clientWrapper.isAvailable(later))
+	_, ok = clientWrapper.beginRequest(later)
+	assert.False(t, ok)
+
+	// The circuit closes when the probe succeeds
+	clientWrapper.recordProbeResult(nil)
+	status = clientWrapper.getStatus(later)
+	assert.Equal(t, CircuitStateClosed, status.CircuitState)
+	assert.Equal(t, 0, status.ConsecutiveFailures)
+	probe, ok = clientWrapper.beginRequest(later)
+	assert.True(t, ok)
+	assert.False(t, probe)
+
+	require.Len(t, changes, 3)
+	assert.Equal(t, CircuitStateChange{Endpoint: "https://primary.azconfig.io", From: CircuitStateClosed, To: CircuitStateOpen, Error: requestError}, changes[0])
+	assert.Equal(t, CircuitStateChange{Endpoint: "https://primary.azconfig.io", From: CircuitStateOpen, To: CircuitStateHalfOpen}, changes[1])
+	assert.Equal(t, CircuitStateChange{Endpoint: "https://primary.azconfig.io", From: CircuitStateHalfOpen, To: CircuitStateClosed}, changes[2])
+}
+
+func TestCircuitBreaker_ProbeFailureReopens(t *testing.T) {
+	var changes []CircuitStateChange
+	clientWrapper := &configurationClientWrapper{
+		endpoint:       "https://primary.azconfig.io",
+		breaker:        newTestCircuitBreaker(&changes),
+		circuitState:   CircuitStateOpen,
+		failedAttempts: 2,
+	}
+	probeError := &net.DNSError{Err: "no such host", Name: "primary.azconfig.io"}
+
+	probe, ok := clientWrapper.beginRequest(time.Now())
+	require.True(t, ok)
+	require.True(t, probe)
+
+	clientWrapper.recordProbeResult(probeError)
+	status := clientWrapper.getStatus(time.Now())
+	assert.Equal(t, CircuitStateOpen, status.CircuitState)
+	assert.True(t, status.InBackoff)
+	assert.Equal(t, 3, status.ConsecutiveFailures)
+	assert.Equal(t, probeError, status.LastError)
+
+	require.Len(t, changes, 2)
+	assert.Equal(t, CircuitStateHalfOpen, changes[1].From)
+	assert.Equal(t, CircuitStateOpen, changes[1].To)
+	assert.Equal(t, probeError, changes[1].Error)
+}
+
+func TestCircuitBreaker_CallbackPanic(t *testing.T) {
+	var logs bytes.Buffer
+	clientWrapper := &configurationClientWrapper{
+		endpoint: "https://primary.azconfig.io",
+		breaker: &circuitBreaker{
+			failureThreshold: 1,
+			onStateChange:    func(change CircuitStateChange) { panic("callback failed") },
+			logger:           slog.New(slog.NewJSONHandler(&logs, nil)),
+		},
+	}
+
+	assert.NotPanics(t, func() { clientWrapper.recordFailure(&net.DNSError{Err: "no such host"}) })
+	assert.Equal(t, CircuitStateOpen, clientWrapper.getStatus(time.Now()).CircuitState)
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(logs.Bytes(), &record))
+	assert.Equal(t, "ERROR", record["level"])
+	assert.Equal(t, "https://primary.azconfig.io", record["endpoint"])
+	assert.Equal(t, "callback failed", record["panic"])
+}
+
+func TestExecuteFailoverPolicy_CircuitBreakerProbe(t *testing.T) {
+	client1 := &azappconfig.Client{}
+	client2 := &azappconfig.Client{}
+
+	var changes []CircuitStateChange
+	breaker := newTestCircuitBreaker(&changes)
+	probeResults := []error{&net.DNSError{Err: "no such host", Name: "primary.azconfig.io"}, nil}
+	probedClients := make([]*azappconfig.Client, 0)
+	breaker.probe = func(ctx context.Context, client *azappconfig.Client) error {
+		probedClients = append(probedClients, client)
+		result := probeResults[0]
+		probeResults = probeResults[1:]
+		return result
+	}
+
+	// The circuit of the primary endpoint is open and its open duration has elapsed
+	clientWrappers := []*configurationClientWrapper{
+		{endpoint: "https://primary.azconfig.io", client: client1, breaker: breaker, circuitState: CircuitStateOpen, failedAttempts: 2},
+		{endpoint: "https://replica.azconfig.io", client: client2, breaker: breaker},
+	}
+
+	mockClientManager := new(mockClientManager)
+	mockClientManager.On("getClients", mock.Anything).Return(clientWrappers, nil)
+
+	azappcfg := &AzureAppConfiguration{
+		clientManager: mockClientManager,
+	}
+
+	calledClients := make([]*azappconfig.Client, 0)
+	operation := func(ctx context.Context, client *azappconfig.Client) error {
+		calledClients = append(calledClients, client)
+		return nil
+	}
+
+	// The probe fails, so the request fails over to the replica without being sent to the primary endpoint
+	err := azappcfg.executeFailoverPolicy(context.Background(), operation)
+	require.NoError(t, err)
+	assert.Equal(t, []*azappconfig.Client{client1}, probedClients)
+	assert.Equal(t, []*azappconfig.Client{client2}, calledClients)
+	assert.Equal(t, CircuitStateOpen, clientWrappers[0].getStatus(time.Now()).CircuitState)
+
+	// The probe succeeds once the open duration elapses again, so the request is sent to the primary endpoint
+	clientWrappers[0].backOffEndTime = time.Time{}
+	calledClients = calledClients[:0]
+	err = azappcfg.executeFailoverPolicy(context.Background(), operation)
+	require.NoError(t, err)
+	assert.Equal(t, []*azappconfig.Client{client1, client1}, probedClients)
+	assert.Equal(t, []*azappconfig.Client{client1}, calledClients)
+	assert.Equal(t, CircuitStateClosed, clientWrappers[0].getStatus(time.Now()).CircuitState)
+
+	mockClientManager.AssertExpectations(t)
+}
+
+func TestExecuteFailoverPolicy_CircuitBreakerProbeResult(t *testing.T) {
+	tests := []struct {
+		name          string
+		probeError    error
+		expectedState CircuitState
+	}{
+		{
+			name:          "not found closes the circuit",
+			probeError:    &azcore.ResponseError{StatusCode: http.StatusNotFound},
+			expectedState: CircuitStateClosed,
+		},
+		{
+			name:          "unauthorized opens the circuit again",
+			probeError:    &azcore.ResponseError{StatusCode: http.StatusUnauthorized},
+			expectedState: CircuitStateOpen,
+		},
+		{
+			name:          "bad request leaves the circuit half-open",
+			probeError:    &azcore.ResponseError{StatusCode: http.StatusBadRequest},
+			expectedState: CircuitStateHalfOpen,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var changes []CircuitStateChange
+			breaker := newTestCircuitBreaker(&changes)
+			breaker.probe = func(ctx context.Context, client *azappconfig.Client) error {
+				return test.probeError
+			}
+
+			clientWrapper := &configurationClientWrapper{
+				endpoint:       "https://primary.azconfig.io",
+				client:         &azappconfig.Client{},
+				breaker:        breaker,
+				circuitState:   CircuitStateOpen,
+				failedAttempts: 2,
+			}
+			mockClientManager := new(mockClientManager)
+			mockClientManager.On("getClients", mock.Anything).Return([]*configurationClientWrapper{clientWrapper}, nil)
+			mockClientManager.On("refreshClients", mock.Anything).Return()
+
+			azappcfg := &AzureAppConfiguration{
+				clientManager: mockClientManager,
+			}
+
+			err := azappcfg.executeFailoverPolicy(context.Background(), func(ctx context.Context, client *azappconfig.Client) error {
+				return nil
+			})
+
+			assert.Equal(t, test.expectedState, clientWrapper.getStatus(time.Now()).CircuitState)
+			switch test.expectedState {
+			case CircuitStateClosed:
+				assert.NoError(t, err)
+			case CircuitStateOpen:
+				assert.ErrorIs(t, err, errAllClientsFailed)
+			case CircuitStateHalfOpen:
+				// The request does not fail over and the next request probes the endpoint again
+				assert.Error(t, err)
+				assert.NotErrorIs(t, err, errAllClientsFailed)
+				assert.True(t, clientWrapper.isAvailable(time.Now()))
+				assert.Equal(t, 2, clientWrapper.getStatus(time.Now()).ConsecutiveFailures)
+			}
+		})
+	}
+}
+
+func TestExecuteFailoverPolicy_CircuitBreakerProbeContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var changes []CircuitStateChange
+	breaker := newTestCircuitBreaker(&changes)
+	breaker.probe = func(ctx context.Context, client *azappconfig.Client) error {
+		// The caller cancels the request while the probe is in flight
+		cancel()
+		return ctx.Err()
+	}
+
+	client1 := &azappconfig.Client{}
+	client2 := &azappconfig.Client{}
+	clientWrappers := []*configurationClientWrapper{
+		{endpoint: "https://primary.azconfig.io", client: client1, breaker: breaker, circuitState: CircuitStateOpen, failedAttempts: 2},
+		{endpoint: "https://replica.azconfig.io", client: client2, breaker: breaker},
+	}
+	mockClientManager := new(mockClientManager)
+	mockClientManager.On("getClients", mock.Anything).Return(clientWrappers, nil)
+
+	azappcfg := &AzureAppConfiguration{
+		clientManager: mockClientManager,
+	}
+
+	calledClients := make([]*azappconfig.Client, 0)
+	err := azappcfg.executeFailoverPolicy(ctx, func(ctx context.Context, client *azappconfig.Client) error {
+		calledClients = append(calledClients, client)
+		return nil
+	})
+
+	// Nothing is recorded for the canceled probe and the request does not fail over
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, calledClients)
+	status := clientWrappers[0].getStatus(time.Now())
+	assert.Equal(t, CircuitStateHalfOpen, status.CircuitState)
+	assert.Equal(t, 2, status.ConsecutiveFailures)
+	assert.Nil(t, status.LastError)
+	assert.True(t, clientWrappers[0].isAvailable(time.Now()))
+	require.Len(t, changes, 1)
+	assert.Equal(t, CircuitStateHalfOpen, changes[0].To)
+	mockClientManager.AssertNotCalled(t, "refreshClients", mock.Anything)
+}
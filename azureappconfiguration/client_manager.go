@@ -6,41 +6,108 @@ package azureappconfiguration
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"math"
 	"math/rand"
 	"net"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/data/azappconfig/v2"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-// configurationClientManager handles creation and management of app configuration clients
+// configurationClientManager handles creation and management of app configuration clients.
+// It is safe for concurrent use: the discovered clients are swapped atomically as a set and
+// the state of each client is guarded by the client wrapper.
 type configurationClientManager struct {
 	replicaDiscoveryEnabled   bool
 	clientOptions             *azappconfig.ClientOptions
 	staticClient              *configurationClientWrapper
-	dynamicClients            []*configurationClientWrapper
+	replicaClients            []*configurationClientWrapper
+	dynamicClients            atomic.Pointer[dynamicClientSet]
 	endpoint                  string
 	validDomain               string
+	trustedDomains            []string
+	srvResolver               SRVResolver
+	discoveryTimeout          time.Duration
+	discoveryInterval         time.Duration
+	breaker                   *circuitBreaker
+	telemetry                 *telemetry
+	logger                    *slog.Logger
 	credential                azcore.TokenCredential
 	secret                    string
 	id                        string
+	discoveryMu               sync.Mutex // guards lastFallbackClientAttempt
 	lastFallbackClientAttempt time.Time
-	lastFallbackClientRefresh time.Time
+	closeCtx                  context.Context // canceled when the client manager is closed to end the background replica discovery
+	closeCancel               context.CancelFunc
+}
+
+// dynamicClientSet is the set of clients of the replicas discovered via DNS SRV records, which is never modified once created
+type dynamicClientSet struct {
+	clients     []*configurationClientWrapper
+	refreshTime time.Time
 }
 
 // configurationClientWrapper wraps an Azure App Configuration client with additional metadata
 type configurationClientWrapper struct {
-	endpoint       string
-	client         *azappconfig.Client
-	backOffEndTime time.Time
-	failedAttempts int
+	endpoint   string
+	client     *azappconfig.Client
+	discovered bool
+	breaker    *circuitBreaker // nil if the circuit breaker is not enabled
+
+	mu              sync.RWMutex // guards the fields below
+	circuitState    CircuitState
+	probeInFlight   bool
+	backOffEndTime  time.Time
+	failedAttempts  int
+	lastSuccessTime time.Time
+	lastFailureTime time.Time
+	lastError       error
+	latency         time.Duration
+}
+
+// ReplicaStatus describes the endpoints of the Azure App Configuration store known to the provider
+type ReplicaStatus struct {
+	// Replicas contains the status of the origin endpoint, followed by the replicas declared in Options.ReplicaEndpoints
+	// and the replicas discovered via DNS SRV records
+	Replicas []ReplicaEndpointStatus
+	// LastUsedEndpoint is the endpoint which served the most recent successful load or refresh,
+	// which is empty if no request has succeeded yet
+	LastUsedEndpoint string
+}
+
+// ReplicaEndpointStatus describes the health of an endpoint of the Azure App Configuration store
+type ReplicaEndpointStatus struct {
+	// Endpoint is the URL of the endpoint
+	Endpoint string
+	// Discovered indicates whether the endpoint is a replica discovered via DNS SRV records
+	// rather than the endpoint the provider was created with or a replica declared in Options.ReplicaEndpoints
+	Discovered bool
+	// InBackoff indicates whether the endpoint is excluded from requests because of recent failures
+	InBackoff bool
+	// CircuitState is the state of the circuit breaker of the endpoint, which is empty if the circuit breaker is not enabled
+	CircuitState CircuitState
+	// BackoffEndTime is the time until which the endpoint is excluded from requests
+	BackoffEndTime time.Time
+	// ConsecutiveFailures is the number of failed requests since the last successful request
+	ConsecutiveFailures int
+	// LastSuccessTime is the time of the last successful request, which is zero if no request has succeeded
+	LastSuccessTime time.Time
+	// LastFailureTime is the time of the last failed request, which is zero if no request has failed
+	LastFailureTime time.Time
+	// LastError is the error of the last failed request, which is nil if no request has failed
+	LastError error
+	// Latency is the exponentially weighted moving average of the duration of the successful requests,
+	// which is zero if no request has succeeded
+	Latency time.Duration
 }
 
 type clientManager interface {
@@ -51,18 +118,49 @@ type clientManager interface {
 // newConfigurationClientManager creates a new configuration client manager
 func newConfigurationClientManager(authOptions AuthenticationOptions, options *Options) (*configurationClientManager, error) {
 	manager := &configurationClientManager{
-		clientOptions: setTelemetry(options.ClientOptions),
+		clientOptions:     setTelemetry(options.ClientOptions),
+		srvResolver:       options.ReplicaDiscoveryOptions.Resolver,
+		discoveryTimeout:  options.ReplicaDiscoveryOptions.Timeout,
+		discoveryInterval: options.ReplicaDiscoveryOptions.RefreshInterval,
+		breaker:           newCircuitBreaker(options.CircuitBreakerOptions),
+		logger:            options.Logger,
+	}
+
+	if manager.breaker != nil {
+		manager.breaker.logger = options.Logger
 	}
 
+	manager.closeCtx, manager.closeCancel = context.WithCancel(context.Background())
+
 	if options.ReplicaDiscoveryEnabled == nil || *options.ReplicaDiscoveryEnabled {
 		manager.replicaDiscoveryEnabled = true
 	}
 
+	if manager.srvResolver == nil {
+		manager.srvResolver = net.DefaultResolver
+	}
+
+	if manager.discoveryTimeout <= 0 {
+		manager.discoveryTimeout = defaultReplicaDiscoveryTimeout
+	}
+
+	if manager.discoveryInterval <= 0 {
+		manager.discoveryInterval = defaultReplicaDiscoveryInterval
+	}
+
 	// Create client based on authentication options
 	if err := manager.initializeClient(authOptions); err != nil {
 		return nil, fmt.Errorf("failed to initialize configuration client: %w", err)
 	}
 
+	if err := manager.initializeReplicaClients(options.ReplicaEndpoints); err != nil {
+		return nil, fmt.Errorf("failed to initialize replica client: %w", err)
+	}
+
+	for _, domain := range options.TrustedDomains {
+		manager.trustedDomains = append(manager.trustedDomains, strings.Trim(domain, "."))
+	}
+
 	return manager, nil
 }
 
@@ -103,6 +201,30 @@ func (manager *configurationClientManager) initializeClient(authOptions Authenti
 	manager.staticClient = &configurationClientWrapper{
 		endpoint: manager.endpoint,
 		client:   staticClient,
+		breaker:  manager.breaker,
+	}
+
+	return nil
+}
+
+// initializeReplicaClients sets up the clients of the replica endpoints declared in the options
+func (manager *configurationClientManager) initializeReplicaClients(replicaEndpoints []string) error {
+	for _, endpoint := range replicaEndpoints {
+		endpoint = strings.TrimSuffix(endpoint, "/")
+		if manager.isKnownEndpoint(endpoint) {
+			continue
+		}
+
+		client, err := manager.newConfigurationClient(endpoint)
+		if err != nil {
+			return fmt.Errorf("%s: %w", endpoint, err)
+		}
+
+		manager.replicaClients = append(manager.replicaClients, &configurationClientWrapper{
+			endpoint: endpoint,
+			client:   client,
+			breaker:  manager.breaker,
+		})
 	}
 
 	return nil
@@ -110,27 +232,38 @@ func (manager *configurationClientManager) initializeClient(authOptions Authenti
 
 func (manager *configurationClientManager) getClients(ctx context.Context) ([]*configurationClientWrapper, error) {
 	currentTime := time.Now()
-	clients := make([]*configurationClientWrapper, 0, 1+len(manager.dynamicClients))
+	clientSet := manager.dynamicClients.Load()
+	clients := make([]*configurationClientWrapper, 0, 1+len(manager.replicaClients))
 
 	// Add the static client if it is not in backoff
-	if currentTime.After(manager.staticClient.backOffEndTime) {
+	if manager.staticClient.isAvailable(currentTime) {
 		clients = append(clients, manager.staticClient)
 	}
 
+	// Add the replica clients declared in the options if they are not in backoff
+	for _, clientWrapper := range manager.replicaClients {
+		if clientWrapper.isAvailable(currentTime) {
+			clients = append(clients, clientWrapper)
+		}
+	}
+
 	if !manager.replicaDiscoveryEnabled {
 		return clients, nil
 	}
 
-	if currentTime.After(manager.lastFallbackClientAttempt.Add(minimalClientRefreshInterval)) &&
-		(manager.dynamicClients == nil ||
-			currentTime.After(manager.lastFallbackClientRefresh.Add(fallbackClientRefreshExpireInterval))) {
-		manager.lastFallbackClientAttempt = currentTime
+	if manager.tryStartDiscovery(currentTime, func() bool {
+		return clientSet == nil || currentTime.After(clientSet.refreshTime.Add(manager.discoveryInterval))
+	}) {
 		url, _ := url.Parse(manager.endpoint)
-		manager.discoverFallbackClients(url.Host)
+		manager.discoverFallbackClients(ctx, url.Host)
+	}
+
+	if clientSet == nil {
+		return clients, nil
 	}
 
-	for _, clientWrapper := range manager.dynamicClients {
-		if currentTime.After(clientWrapper.backOffEndTime) {
+	for _, clientWrapper := range clientSet.clients {
+		if clientWrapper.isAvailable(currentTime) {
 			clients = append(clients, clientWrapper)
 		}
 	}
@@ -138,35 +271,118 @@ func (manager *configurationClientManager) getClients(ctx context.Context) ([]*c
 	return clients, nil
 }
 
+// getDynamicClients returns the clients of the replicas discovered via DNS SRV records
+func (manager *configurationClientManager) getDynamicClients() []*configurationClientWrapper {
+	if clientSet := manager.dynamicClients.Load(); clientSet != nil {
+		return clientSet.clients
+	}
+
+	return nil
+}
+
+// setDynamicClients replaces the clients of the replicas discovered via DNS SRV records
+func (manager *configurationClientManager) setDynamicClients(clients []*configurationClientWrapper) {
+	manager.dynamicClients.Store(&dynamicClientSet{
+		clients:     clients,
+		refreshTime: time.Now(),
+	})
+}
+
+// tryStartDiscovery records a discovery attempt if no discovery was attempted within the minimal client refresh interval
+// and the condition is met, so that concurrent callers do not start multiple discoveries
+func (manager *configurationClientManager) tryStartDiscovery(now time.Time, condition func() bool) bool {
+	manager.discoveryMu.Lock()
+	defer manager.discoveryMu.Unlock()
+
+	if !now.After(manager.lastFallbackClientAttempt.Add(minimalClientRefreshInterval)) || !condition() {
+		return false
+	}
+
+	manager.lastFallbackClientAttempt = now
+	return true
+}
+
+// getReplicaStatus returns the status of the static client followed by the replica clients and the dynamic clients
+func (manager *configurationClientManager) getReplicaStatus(now time.Time) []ReplicaEndpointStatus {
+	dynamicClients := manager.getDynamicClients()
+	replicas := make([]ReplicaEndpointStatus, 0, 1+len(manager.replicaClients)+len(dynamicClients))
+	if manager.staticClient != nil {
+		replicas = append(replicas, manager.staticClient.getStatus(now))
+	}
+
+	for _, clientWrapper := range manager.replicaClients {
+		replicas = append(replicas, clientWrapper.getStatus(now))
+	}
+
+	for _, clientWrapper := range dynamicClients {
+		replicas = append(replicas, clientWrapper.getStatus(now))
+	}
+
+	return replicas
+}
+
 func (manager *configurationClientManager) refreshClients(ctx context.Context) {
 	currentTime := time.Now()
 	if manager.replicaDiscoveryEnabled &&
-		currentTime.After(manager.lastFallbackClientAttempt.Add(minimalClientRefreshInterval)) {
-		manager.lastFallbackClientAttempt = currentTime
+		manager.tryStartDiscovery(currentTime, func() bool { return true }) {
 		url, _ := url.Parse(manager.endpoint)
-		manager.discoverFallbackClients(url.Host)
+		manager.discoverFallbackClients(ctx, url.Host)
 	}
 }
 
-func (manager *configurationClientManager) discoverFallbackClients(host string) {
+// discoverFallbackClients discovers the replicas in the background within the discovery timeout. The discovery outlives
+// the operation which triggered it, so it keeps the values of the context of the operation but is not canceled with it.
+// It is canceled when the client manager is closed instead.
+func (manager *configurationClientManager) discoverFallbackClients(ctx context.Context, host string) {
+	discoveryCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	closeCtx := manager.closeCtx
+	if closeCtx == nil {
+		closeCtx = context.Background()
+	}
+	stop := context.AfterFunc(closeCtx, cancel)
+
 	go func() {
+		defer cancel()
+		defer stop()
 		defer func() {
 			if r := recover(); r != nil {
-				log.Printf("panic in replica discovery: %v", r)
+				getLogger(manager.logger).Error("Panic in replica discovery", "host", host, "panic", r)
 			}
 		}()
 
-		discoveryCtx, cancel := context.WithTimeout(context.Background(), failoverTimeout)
-		defer cancel()
-
-		srvTargetHosts, err := querySrvTargetHost(discoveryCtx, host)
-		if err != nil {
-			log.Printf("failed to discover fallback clients for %s: %v", host, err)
-			return
+		// A discovery canceled by closing the client manager is not a failure
+		if err := manager.discoverReplicas(discoveryCtx, host); err != nil && closeCtx.Err() == nil {
+			getLogger(manager.logger).Warn("Failed to discover replicas", "host", host, "error", err)
 		}
+	}()
+}
+
+// close cancels the background replica discovery
+func (manager *configurationClientManager) close() {
+	if manager.closeCancel != nil {
+		manager.closeCancel()
+	}
+}
+
+// discoverReplicas queries the SRV records of the host within the discovery timeout and replaces the dynamic clients
+func (manager *configurationClientManager) discoverReplicas(ctx context.Context, host string) (err error) {
+	discoveryCtx, cancel := context.WithTimeout(ctx, manager.discoveryTimeout)
+	defer cancel()
 
-		manager.processSrvTargetHosts(srvTargetHosts)
+	discoveryCtx, span := manager.telemetry.startSpan(discoveryCtx, discoverReplicasSpanName)
+	defer func() {
+		endSpan(span, err)
 	}()
+
+	srvTargetHosts, err := querySrvTargetHost(discoveryCtx, manager.srvResolver, host)
+	if err != nil {
+		return err
+	}
+
+	span.SetAttributes(attribute.Int(replicaCountAttributeKey, len(srvTargetHosts)))
+
+	manager.processSrvTargetHosts(srvTargetHosts)
+	return nil
 }
 
 func (manager *configurationClientManager) processSrvTargetHosts(srvTargetHosts []string) {
@@ -177,31 +393,32 @@ func (manager *configurationClientManager) processSrvTargetHosts(srvTargetHosts
 
 	newDynamicClients := make([]*configurationClientWrapper, 0, len(srvTargetHosts))
 	for _, host := range srvTargetHosts {
-		if isValidEndpoint(host, manager.validDomain) {
+		if manager.isTrustedHost(host) {
 			targetEndpoint := "https://" + host
-			if strings.EqualFold(targetEndpoint, manager.endpoint) {
-				continue // Skip primary endpoint
+			if manager.isKnownEndpoint(targetEndpoint) {
+				continue // Skip primary endpoint and replica endpoints declared in the options
 			}
 			client, err := manager.newConfigurationClient(targetEndpoint)
 			if err != nil {
-				log.Printf("failed to create client for replica %s: %v", targetEndpoint, err)
+				getLogger(manager.logger).Warn("Failed to create client for replica", "endpoint", targetEndpoint, "error", err)
 				continue // Continue with other replicas instead of returning
 			}
 			newDynamicClients = append(newDynamicClients, &configurationClientWrapper{
-				endpoint: targetEndpoint,
-				client:   client,
+				endpoint:   targetEndpoint,
+				client:     client,
+				discovered: true,
+				breaker:    manager.breaker,
 			})
 		}
 	}
 
-	manager.dynamicClients = newDynamicClients
-	manager.lastFallbackClientRefresh = time.Now()
+	manager.setDynamicClients(newDynamicClients)
 }
 
-func querySrvTargetHost(ctx context.Context, host string) ([]string, error) {
+func querySrvTargetHost(ctx context.Context, resolver SRVResolver, host string) ([]string, error) {
 	results := make([]string, 0)
 
-	_, originRecords, err := net.DefaultResolver.LookupSRV(ctx, originKey, tcpKey, host)
+	_, originRecords, err := resolver.LookupSRV(ctx, originKey, tcpKey, host)
 	if err != nil {
 		// If the host does not have SRV records => no replicas
 		if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound {
@@ -220,7 +437,7 @@ func querySrvTargetHost(ctx context.Context, host string) ([]string, error) {
 	index := 0
 	for {
 		currentAlt := altKey + strconv.Itoa(index)
-		_, altRecords, err := net.DefaultResolver.LookupSRV(ctx, currentAlt, tcpKey, originHost)
+		_, altRecords, err := resolver.LookupSRV(ctx, currentAlt, tcpKey, originHost)
 		if err != nil {
 			// If the host does not have SRV records => no more replicas
 			if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound {
@@ -329,16 +546,118 @@ func isValidEndpoint(host string, validDomain string) bool {
 	return strings.HasSuffix(strings.ToLower(host), strings.ToLower(validDomain))
 }
 
-func (client *configurationClientWrapper) updateBackoffStatus(success bool) {
+// isTrustedHost checks if the host is in the domain of the store endpoint or in one of the trusted domains
+func (manager *configurationClientManager) isTrustedHost(host string) bool {
+	if isValidEndpoint(host, manager.validDomain) {
+		return true
+	}
+
+	host = strings.ToLower(host)
+	for _, domain := range manager.trustedDomains {
+		domain = strings.ToLower(domain)
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isKnownEndpoint checks if the endpoint is the store endpoint or one of the replica endpoints declared in the options
+func (manager *configurationClientManager) isKnownEndpoint(endpoint string) bool {
+	if strings.EqualFold(endpoint, strings.TrimSuffix(manager.endpoint, "/")) {
+		return true
+	}
+
+	for _, clientWrapper := range manager.replicaClients {
+		if strings.EqualFold(endpoint, clientWrapper.endpoint) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isAvailable checks if the client is not in backoff, or if its circuit is not open and no probe is in flight
+// when the circuit breaker is enabled
+func (client *configurationClientWrapper) isAvailable(now time.Time) bool {
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+
+	if client.breaker != nil && client.circuitState == CircuitStateHalfOpen {
+		return !client.probeInFlight
+	}
+
+	return now.After(client.backOffEndTime)
+}
+
+// recordFailure records a failed request to the client and puts the client in backoff,
+// or opens the circuit if the failure threshold is reached when the circuit breaker is enabled
+func (client *configurationClientWrapper) recordFailure(err error) {
+	client.mu.Lock()
+	client.lastError = err
+	var change *CircuitStateChange
+	if client.breaker != nil {
+		change = client.recordCircuitFailure(err)
+	} else {
+		client.setBackoffStatus(false)
+	}
+	client.mu.Unlock()
+
+	if client.breaker != nil {
+		client.breaker.notify(change)
+	}
+}
+
+// recordSuccess records a successful request to the client, which took the duration, and ends the backoff of the client
+func (client *configurationClientWrapper) recordSuccess(duration time.Duration) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	client.setBackoffStatus(true)
+	client.setLatency(duration)
+}
+
+func (client *configurationClientWrapper) setBackoffStatus(success bool) {
 	if success {
 		client.failedAttempts = 0
 		client.backOffEndTime = time.Time{}
+		client.lastSuccessTime = time.Now()
 	} else {
 		client.failedAttempts++
+		client.lastFailureTime = time.Now()
 		client.backOffEndTime = time.Now().Add(calculateBackoffDuration(client.failedAttempts))
 	}
 }
 
+// setLatency updates the exponentially weighted moving average of the request duration
+func (client *configurationClientWrapper) setLatency(duration time.Duration) {
+	if client.latency == 0 {
+		client.latency = duration
+		return
+	}
+
+	client.latency = time.Duration(latencyEWMAWeight*float64(duration) + (1-latencyEWMAWeight)*float64(client.latency))
+}
+
+func (client *configurationClientWrapper) getStatus(now time.Time) ReplicaEndpointStatus {
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+
+	return ReplicaEndpointStatus{
+		Endpoint:            client.endpoint,
+		Discovered:          client.discovered,
+		InBackoff:           now.Before(client.backOffEndTime),
+		CircuitState:        client.getCircuitState(),
+		BackoffEndTime:      client.backOffEndTime,
+		ConsecutiveFailures: client.failedAttempts,
+		LastSuccessTime:     client.lastSuccessTime,
+		LastFailureTime:     client.lastFailureTime,
+		LastError:           client.lastError,
+		Latency:             client.latency,
+	}
+}
+
 func calculateBackoffDuration(failedAttempts int) time.Duration {
 	if failedAttempts <= 1 {
 		return minBackoffDuration
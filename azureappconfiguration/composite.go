@@ -0,0 +1,310 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package azureappconfiguration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// CompositeSource specifies an Azure App Configuration store composed by LoadComposite.
+type CompositeSource struct {
+	// Authentication specifies how to authenticate with the Azure App Configuration store.
+	Authentication AuthenticationOptions
+
+	// Options specifies what to load from the Azure App Configuration store and how to refresh it.
+	Options *Options
+}
+
+// CompositeAzureAppConfiguration merges the configuration of multiple independent Azure App Configuration stores.
+// Key-values and feature flags from later sources take precedence over those from earlier sources.
+type CompositeAzureAppConfiguration struct {
+	sources            []*AzureAppConfiguration
+	onRefreshSuccess   []func()
+	onRefreshSuccessMu sync.Mutex
+
+	sourceRefreshed   atomic.Bool
+	refreshInProgress atomic.Bool
+}
+
+// LoadComposite initializes a new CompositeAzureAppConfiguration instance and loads the configuration data
+// from each source concurrently. Each source is loaded and refreshed according to its own options.
+//
+// Parameters:
+//   - ctx: The context for the operation.
+//   - sources: The Azure App Configuration stores to compose, in the order of increasing precedence
+//
+// Returns:
+//   - A CompositeAzureAppConfiguration instance that provides access to the merged configuration data
+//   - An error if no source is provided or if any source fails to load
+func LoadComposite(ctx context.Context, sources []CompositeSource) (*CompositeAzureAppConfiguration, error) {
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("at least one source must be provided")
+	}
+
+	loaded := make([]*AzureAppConfiguration, len(sources))
+	eg, egCtx := errgroup.WithContext(ctx)
+	for i, source := range sources {
+		eg.Go(func() error {
+			azappcfg, err := Load(egCtx, source.Authentication, source.Options)
+			if err != nil {
+				return fmt.Errorf("failed to load source %d: %w", i, err)
+			}
+
+			loaded[i] = azappcfg
+			return nil
+		})
+	}
+
+	if err := eg.Wait(); err != nil {
+		// The sources which loaded successfully are not returned, so they can't be closed by the caller
+		for _, azappcfg := range loaded {
+			if azappcfg != nil {
+				_ = azappcfg.Close()
+			}
+		}
+		return nil, err
+	}
+
+	return newCompositeAzureAppConfiguration(loaded), nil
+}
+
+func newCompositeAzureAppConfiguration(sources []*AzureAppConfiguration) *CompositeAzureAppConfiguration {
+	composite := &CompositeAzureAppConfiguration{
+		sources: sources,
+	}
+
+	for _, source := range sources {
+		source.OnRefreshSuccess(func() {
+			composite.sourceRefreshed.Store(true)
+		})
+	}
+
+	return composite
+}
+
+// Sources returns the loaded sources in the order of increasing precedence, e.g. to evaluate the feature flags
+// or to inspect the replicas of a specific Azure App Configuration store.
+//
+// Returns:
+//   - The AzureAppConfiguration instance of each source
+func (composite *CompositeAzureAppConfiguration) Sources() []*AzureAppConfiguration {
+	sources := make([]*AzureAppConfiguration, len(composite.sources))
+	copy(sources, composite.sources)
+	return sources
+}
+
+// Unmarshal parses the merged configuration and stores the result in the value pointed to v.
+// See AzureAppConfiguration.Unmarshal for how the hierarchical configuration is built.
+//
+// Parameters:
+//   - v: A pointer to the struct to populate with configuration values
+//   - options: Optional parameters (e,g, separator) for controlling the unmarshalling behavior
+//
+// Returns:
+//   - An error if unmarshalling fails due to type conversion issues or invalid configuration
+func (composite *CompositeAzureAppConfiguration) Unmarshal(v any, options *ConstructionOptions) error {
+	constructionOptions, err := normalizeConstructionOptions(options)
+	if err != nil {
+		return err
+	}
+
+	return decodeHierarchicalMap(composite.constructHierarchicalMap(constructionOptions), v)
+}
+
+// GetBytes returns the merged configuration as a JSON byte array with hierarchical structure.
+//
+// Parameters:
+//   - options: Optional parameters for controlling JSON construction, particularly the key separator
+//
+// Returns:
+//   - A byte array containing the JSON representation of the configuration
+//   - An error if JSON marshalling fails or if an invalid separator is specified
+func (composite *CompositeAzureAppConfiguration) GetBytes(options *ConstructionOptions) ([]byte, error) {
+	constructionOptions, err := normalizeConstructionOptions(options)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(composite.constructHierarchicalMap(constructionOptions))
+}
+
+// GetSource reports which source the value of the specified key is taken from in the merged configuration.
+// The overrides and the loaded key-values of any source take precedence over the defaults of every source,
+// see AzureAppConfiguration.GetSource for the precedence within a source.
+//
+// Parameters:
+//   - key: The key of the setting after any configured prefix trimming
+//   - options: Optional parameters for controlling the construction, particularly the key separator the defaults are flattened with
+//
+// Returns:
+//   - The source of the value of the key, or an empty ValueSource if no source has a value for the key
+//   - An error if an invalid separator is specified
+func (composite *CompositeAzureAppConfiguration) GetSource(key string, options *ConstructionOptions) (ValueSource, error) {
+	constructionOptions, err := normalizeConstructionOptions(options)
+	if err != nil {
+		return "", err
+	}
+
+	for _, source := range slices.Backward(composite.sources) {
+		if valueSource := source.getKeyValueSource(key); valueSource != "" {
+			return valueSource, nil
+		}
+	}
+
+	for _, source := range composite.sources {
+		if valueSource := source.getDefaultSource(key, constructionOptions.Separator); valueSource != "" {
+			return valueSource, nil
+		}
+	}
+
+	return "", nil
+}
+
+// Refresh refreshes each source with refresh enabled concurrently, each according to its own refresh intervals.
+// The callbacks registered with OnRefreshSuccess are executed if the configuration of any source changed,
+// even if other sources fail to refresh.
+//
+// Parameters:
+//   - ctx: The context for the operation.
+//
+// Returns:
+//   - An error combining the errors of the sources which failed to refresh, or an error if refresh is not configured for any source
+func (composite *CompositeAzureAppConfiguration) Refresh(ctx context.Context) error {
+	refreshable := make([]int, 0, len(composite.sources))
+	for i, source := range composite.sources {
+		if source.isRefreshConfigured() {
+			refreshable = append(refreshable, i)
+		}
+	}
+
+	if len(refreshable) == 0 {
+		return fmt.Errorf("refresh is not configured for any source")
+	}
+
+	// Try to set refreshInProgress to true, returning false if it was already true
+	if !composite.refreshInProgress.CompareAndSwap(false, true) {
+		return nil // Another refresh is already in progress
+	}
+
+	defer composite.refreshInProgress.Store(false)
+
+	composite.sourceRefreshed.Store(false)
+	errs := make([]error, len(composite.sources))
+	var wg sync.WaitGroup
+	for _, i := range refreshable {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := composite.sources[i].Refresh(ctx); err != nil {
+				errs[i] = fmt.Errorf("failed to refresh source %d: %w", i, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if composite.sourceRefreshed.Load() {
+		composite.onRefreshSuccessMu.Lock()
+		callbacks := slices.Clone(composite.onRefreshSuccess)
+		composite.onRefreshSuccessMu.Unlock()
+
+		for _, callback := range callbacks {
+			callback()
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Close closes every source, see AzureAppConfiguration.Close.
+//
+// Returns:
+//   - An error combining the errors of the sources which failed to close
+func (composite *CompositeAzureAppConfiguration) Close() error {
+	errs := make([]error, 0, len(composite.sources))
+	for _, source := range composite.sources {
+		errs = append(errs, source.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+// OnRefreshSuccess registers a callback function that will be executed after a refresh in which
+// the configuration of any source changed.
+//
+// Parameters:
+//   - callback: A function with no parameters that will be called after a successful refresh
+func (composite *CompositeAzureAppConfiguration) OnRefreshSuccess(callback func()) {
+	if callback == nil {
+		return
+	}
+
+	composite.onRefreshSuccessMu.Lock()
+	defer composite.onRefreshSuccessMu.Unlock()
+	composite.onRefreshSuccess = append(composite.onRefreshSuccess, callback)
+}
+
+func (composite *CompositeAzureAppConfiguration) constructHierarchicalMap(options ConstructionOptions) map[string]any {
+	// The defaults of every source are merged underneath the key-values loaded from any source, which are merged
+	// source by source with the overrides of each source on top of its loaded key-values
+	layers := make([]map[string]any, 0, 3*len(composite.sources))
+	for _, source := range composite.sources {
+		layers = append(layers, flattenDefaults(source.defaults, options.Separator))
+	}
+
+	featureManagementSections := make([]map[string]any, 0, len(composite.sources))
+	for _, source := range composite.sources {
+		keyValues, keyValueOverrides := source.getKeyValues(options)
+		layers = append(layers, keyValues, keyValueOverrides)
+		if section := source.getFeatureManagementSection(); section != nil {
+			featureManagementSections = append(featureManagementSections, section)
+		}
+	}
+
+	return buildHierarchicalMap(layers, mergeFeatureManagementSections(featureManagementSections), options)
+}
+
+// mergeFeatureManagementSections merges the feature flags of the "feature_management" sections by ID,
+// where feature flags from later sections take precedence and keep the position of their first occurrence
+func mergeFeatureManagementSections(sections []map[string]any) map[string]any {
+	if len(sections) == 0 {
+		return nil
+	}
+
+	merged := make([]any, 0)
+	indexes := make(map[string]int)
+	for _, section := range sections {
+		featureManagement, _ := section[featureManagementSectionKey].(map[string]any)
+		featureFlags, _ := featureManagement[featureFlagSectionKey].([]any)
+		for _, featureFlag := range featureFlags {
+			featureFlagMap, _ := featureFlag.(map[string]any)
+			id, _ := featureFlagMap["id"].(string)
+			if id == "" {
+				merged = append(merged, featureFlag)
+				continue
+			}
+
+			if index, ok := indexes[id]; ok {
+				merged[index] = featureFlag
+				continue
+			}
+
+			indexes[id] = len(merged)
+			merged = append(merged, featureFlag)
+		}
+	}
+
+	return map[string]any{
+		featureManagementSectionKey: map[string]any{
+			featureFlagSectionKey: merged,
+		},
+	}
+}
@@ -0,0 +1,328 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package azureappconfiguration
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azappconfig/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestFeatureManagementSection(featureFlags ...map[string]any) map[string]any {
+	flags := make([]any, 0, len(featureFlags))
+	for _, featureFlag := range featureFlags {
+		flags = append(flags, featureFlag)
+	}
+
+	return map[string]any{
+		featureManagementSectionKey: map[string]any{
+			featureFlagSectionKey: flags,
+		},
+	}
+}
+
+func TestLoadComposite_NoSource(t *testing.T) {
+	composite, err := LoadComposite(context.Background(), nil)
+
+	assert.Nil(t, composite)
+	assert.EqualError(t, err, "at least one source must be provided")
+}
+
+func TestLoadComposite_InvalidSource(t *testing.T) {
+	sources := []CompositeSource{
+		{Authentication: AuthenticationOptions{}},
+	}
+
+	composite, err := LoadComposite(context.Background(), sources)
+
+	assert.Nil(t, composite)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to load source 0")
+}
+
+func TestCompositeAzureAppConfiguration_Precedence(t *testing.T) {
+	shared := &AzureAppConfiguration{
+		keyValues: map[string]any{
+			"App.Timeout":  "10s",
+			"App.Region":   "westus",
+			"Shared.Owner": "platform",
+		},
+		ffEnabled: true,
+		featureFlags: newTestFeatureManagementSection(
+			map[string]any{"id": "Beta", "enabled": false},
+			map[string]any{"id": "Logging", "enabled": true},
+		),
+	}
+	product := &AzureAppConfiguration{
+		keyValues: map[string]any{
+			"App.Timeout": "30s",
+			"App.Name":    "checkout",
+		},
+		ffEnabled: true,
+		featureFlags: newTestFeatureManagementSection(
+			map[string]any{"id": "Beta", "enabled": true},
+			map[string]any{"id": "Checkout", "enabled": true},
+		),
+	}
+
+	composite := newCompositeAzureAppConfiguration([]*AzureAppConfiguration{shared, product})
+
+	type config struct {
+		App struct {
+			Timeout string
+			Region  string
+			Name    string
+		}
+		Shared struct {
+			Owner string
+		}
+	}
+
+	var result config
+	err := composite.Unmarshal(&result, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "30s", result.App.Timeout)
+	assert.Equal(t, "westus", result.App.Region)
+	assert.Equal(t, "checkout", result.App.Name)
+	assert.Equal(t, "platform", result.Shared.Owner)
+
+	bytes, err := composite.GetBytes(nil)
+	require.NoError(t, err)
+
+	var merged map[string]any
+	require.NoError(t, json.Unmarshal(bytes, &merged))
+	featureFlags := merged[featureManagementSectionKey].(map[string]any)[featureFlagSectionKey].([]any)
+	assert.Equal(t, []any{
+		map[string]any{"id": "Beta", "enabled": true},
+		map[string]any{"id": "Logging", "enabled": true},
+		map[string]any{"id": "Checkout", "enabled": true},
+	}, featureFlags)
+
+	assert.Equal(t, []*AzureAppConfiguration{shared, product}, composite.Sources())
+}
+
+func TestCompositeAzureAppConfiguration_OverridesAndRedaction(t *testing.T) {
+	shared := &AzureAppConfiguration{
+		keyValues: map[string]any{
+			"Db.Password": "secret",
+			"Db.Host":     "shared.example.com",
+		},
+		secretKeys: map[string]struct{}{"Db.Password": {}},
+	}
+	product := &AzureAppConfiguration{
+		keyValues: map[string]any{
+			"Db.Host": "product.example.com",
+		},
+	}
+	product.OverrideFeature("Maintenance", true, nil)
+	shared.SetOverride("Db.Host", "override.example.com", nil)
+
+	composite := newCompositeAzureAppConfiguration([]*AzureAppConfiguration{shared, product})
+
+	bytes, err := composite.GetBytes(&ConstructionOptions{RedactSecrets: true})
+	require.NoError(t, err)
+
+	var merged map[string]any
+	require.NoError(t, json.Unmarshal(bytes, &merged))
+
+	// The override of an earlier source does not take precedence over a later source
+	assert.Equal(t, map[string]any{"Password": redactedValue, "Host": "product.example.com"}, merged["Db"])
+	assert.Equal(t, []any{map[string]any{"id": "Maintenance", "enabled": true}},
+		merged[featureManagementSectionKey].(map[string]any)[featureFlagSectionKey])
+}
+
+func TestCompositeAzureAppConfiguration_NestedPrecedence(t *testing.T) {
+	shared := &AzureAppConfiguration{
+		keyValues: map[string]any{
+			"Db":          map[string]any{"Host": "shared.example.com", "Port": float64(5432)},
+			"Cache.Size":  "64",
+			"Queue.Limit": "10",
+		},
+	}
+	product := &AzureAppConfiguration{
+		keyValues: map[string]any{
+			"Db.Host": "product.example.com",
+			"Cache":   "disabled",
+			"Queue":   map[string]any{"Name": "orders"},
+		},
+	}
+
+	composite := newCompositeAzureAppConfiguration([]*AzureAppConfiguration{shared, product})
+
+	// The key-values of a later source take precedence over the overlapping key-values of an earlier source
+	// regardless of the map order, whether they are nested in a JSON object or have key-values nested under them
+	for range 100 {
+		bytes, err := composite.GetBytes(nil)
+		require.NoError(t, err)
+		require.JSONEq(t, `{
+			"Db": {"Host": "product.example.com", "Port": 5432},
+			"Cache": "disabled",
+			"Queue": {"Limit": "10", "Name": "orders"}
+		}`, string(bytes))
+	}
+}
+
+func TestCompositeAzureAppConfiguration_Defaults(t *testing.T) {
+	shared := &AzureAppConfiguration{
+		keyValues: map[string]any{"App.Timeout": "10s"},
+		defaults:  map[string]any{"App": map[string]any{"Region": "westus"}},
+	}
+	product := &AzureAppConfiguration{
+		keyValues: map[string]any{"App.Name": "checkout"},
+		defaults:  map[string]any{"App": map[string]any{"Timeout": "60s", "Region": "eastus"}},
+	}
+
+	composite := newCompositeAzureAppConfiguration([]*AzureAppConfiguration{shared, product})
+
+	bytes, err := composite.GetBytes(nil)
+	require.NoError(t, err)
+
+	// The defaults of a later source do not take precedence over the key-values loaded from an earlier source
+	assert.JSONEq(t, `{"App":{"Timeout":"10s","Region":"eastus","Name":"checkout"}}`, string(bytes))
+
+	source, err := composite.GetSource("App.Timeout", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, ValueSourceLoaded, source)
+	source, err = composite.GetSource("App.Region", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, ValueSourceDefault, source)
+	source, err = composite.GetSource("App.Owner", nil)
+	assert.NoError(t, err)
+	assert.Empty(t, source)
+}
+
+func TestCompositeAzureAppConfiguration_Refresh(t *testing.T) {
+	// The first source has no refresh configured and is not refreshed
+	static := &AzureAppConfiguration{
+		keyValues: map[string]any{"app": "static"},
+	}
+
+	// The second source refreshes a Key Vault secret which has changed
+	productClientManager := new(mockClientManager)
+	productClientManager.On("getClients", mock.Anything).Return([]*configurationClientWrapper{
+		{endpoint: "https://product.azconfig.io", client: &azappconfig.Client{}},
+	}, nil)
+
+	secretURL, _ := url.Parse("https://myvault.vault.azure.net/secrets/s1")
+	mockResolver := new(mockSecretResolver)
+	mockResolver.On("ResolveSecret", mock.Anything, *secretURL).Return("new-secret", nil)
+
+	product := &AzureAppConfiguration{
+		clientManager:      productClientManager,
+		keyValues:          map[string]any{"secret": "old-secret"},
+		secretRefreshTimer: &mockRefreshCondition{shouldRefresh: true},
+		keyVaultRefs:       map[string]string{"secret": `{"uri":"https://myvault.vault.azure.net/secrets/s1"}`},
+		resolver: &keyVaultReferenceResolver{
+			clients:        sync.Map{},
+			secretResolver: mockResolver,
+		},
+	}
+
+	// The third source fails to refresh
+	failingClientManager := new(mockClientManager)
+	failingClientManager.On("getClients", mock.Anything).Return([]*configurationClientWrapper{}, nil)
+	failingClientManager.On("refreshClients", mock.Anything).Return()
+
+	failing := &AzureAppConfiguration{
+		clientManager:  failingClientManager,
+		kvRefreshTimer: &mockRefreshCondition{shouldRefresh: true},
+	}
+
+	composite := newCompositeAzureAppConfiguration([]*AzureAppConfiguration{static, product, failing})
+	callbackCount := 0
+	composite.OnRefreshSuccess(func() {
+		callbackCount++
+	})
+
+	err := composite.Refresh(context.Background())
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to refresh source 2")
+	assert.NotContains(t, err.Error(), "source 0")
+	assert.NotContains(t, err.Error(), "source 1")
+	assert.Equal(t, 1, callbackCount)
+	assert.Equal(t, "new-secret", product.keyValues["secret"])
+	mockResolver.AssertExpectations(t)
+}
+
+func TestCompositeAzureAppConfiguration_RefreshNotConfigured(t *testing.T) {
+	composite := newCompositeAzureAppConfiguration([]*AzureAppConfiguration{{}, {}})
+
+	err := composite.Refresh(context.Background())
+
+	assert.EqualError(t, err, "refresh is not configured for any source")
+}
+
+func TestCompositeAzureAppConfiguration_RefreshNoChanges(t *testing.T) {
+	mockClientManager := new(mockClientManager)
+	mockClientManager.On("getClients", mock.Anything).Return([]*configurationClientWrapper{
+		{endpoint: "https://store.azconfig.io", client: &azappconfig.Client{}},
+	}, nil)
+
+	source := &AzureAppConfiguration{
+		clientManager:  mockClientManager,
+		kvRefreshTimer: &mockRefreshCondition{shouldRefresh: false},
+	}
+
+	composite := newCompositeAzureAppConfiguration([]*AzureAppConfiguration{source})
+	callbackCount := 0
+	composite.OnRefreshSuccess(func() {
+		callbackCount++
+	})
+
+	err := composite.Refresh(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, callbackCount)
+}
+
+func TestCompositeAzureAppConfiguration_OnRefreshSuccessConcurrency(t *testing.T) {
+	mockClientManager := new(mockClientManager)
+	mockClientManager.On("getClients", mock.Anything).Return([]*configurationClientWrapper{
+		{endpoint: "https://store.azconfig.io", client: &azappconfig.Client{}},
+	}, nil)
+
+	secretURL, _ := url.Parse("https://myvault.vault.azure.net/secrets/s1")
+	mockResolver := new(mockSecretResolver)
+	mockResolver.On("ResolveSecret", mock.Anything, *secretURL).Return("new-secret", nil)
+
+	source := &AzureAppConfiguration{
+		clientManager:      mockClientManager,
+		keyValues:          map[string]any{"secret": "old-secret"},
+		secretRefreshTimer: &mockRefreshCondition{shouldRefresh: true},
+		keyVaultRefs:       map[string]string{"secret": `{"uri":"https://myvault.vault.azure.net/secrets/s1"}`},
+		resolver: &keyVaultReferenceResolver{
+			clients:        sync.Map{},
+			secretResolver: mockResolver,
+		},
+	}
+
+	composite := newCompositeAzureAppConfiguration([]*AzureAppConfiguration{source})
+
+	// Callbacks are registered while the composite is refreshed
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			composite.OnRefreshSuccess(func() {})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			assert.NoError(t, composite.Refresh(context.Background()))
+		}
+	}()
+	wg.Wait()
+
+	assert.Len(t, composite.onRefreshSuccess, 10)
+}
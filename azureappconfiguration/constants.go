@@ -23,6 +23,8 @@ const (
 	featureFlagKeyPrefix         string = ".appconfig.featureflag/"
 	featureManagementSectionKey  string = "feature_management"
 	featureFlagSectionKey        string = "feature_flags"
+	sensitiveTagName             string = "sensitive"
+	redactedValue                string = "[REDACTED]"
 )
 
 // Feature flag constants
@@ -57,21 +59,54 @@ const (
 
 // Failover constants
 const (
-	tcpKey                              string        = "tcp"
-	originKey                           string        = "origin"
-	altKey                              string        = "alt"
-	azConfigDomainLabel                 string        = ".azconfig."
-	appConfigDomainLabel                string        = ".appconfig."
-	fallbackClientRefreshExpireInterval time.Duration = time.Hour
-	minimalClientRefreshInterval        time.Duration = time.Second * 30
-	maxBackoffDuration                  time.Duration = time.Minute * 10
-	minBackoffDuration                  time.Duration = time.Second * 30
-	failoverTimeout                     time.Duration = time.Second * 10
-	jitterRatio                         float64       = 0.25
-	safeShiftLimit                      int           = 63
+	tcpKey                                string        = "tcp"
+	originKey                             string        = "origin"
+	altKey                                string        = "alt"
+	azConfigDomainLabel                   string        = ".azconfig."
+	appConfigDomainLabel                  string        = ".appconfig."
+	defaultReplicaDiscoveryInterval       time.Duration = time.Hour
+	minimalClientRefreshInterval          time.Duration = time.Second * 30
+	maxBackoffDuration                    time.Duration = time.Minute * 10
+	minBackoffDuration                    time.Duration = time.Second * 30
+	defaultReplicaDiscoveryTimeout        time.Duration = time.Second * 10
+	latencyEWMAWeight                     float64       = 0.3
+	circuitBreakerProbeKey                string        = ".appconfig.circuitbreaker.probe"
+	defaultCircuitBreakerFailureThreshold int           = 3
+	jitterRatio                           float64       = 0.25
+	safeShiftLimit                        int           = 63
 )
 
 // Startup constants
 const (
 	defaultStartupTimeout time.Duration = 100 * time.Second
 )
+
+// OpenTelemetry constants
+const (
+	instrumentationName       string = "github.com/Azure/AppConfiguration-GoProvider/azureappconfiguration"
+	loadSpanName              string = "appconfiguration.load"
+	refreshSpanName           string = "appconfiguration.refresh"
+	listSettingsSpanName      string = "appconfiguration.list_settings"
+	loadSnapshotSpanName      string = "appconfiguration.load_snapshot"
+	resolveSecretSpanName     string = "appconfiguration.resolve_secret"
+	discoverReplicasSpanName  string = "appconfiguration.discover_replicas"
+	loadDurationMetricName    string = "appconfiguration.load.duration"
+	refreshCountMetricName    string = "appconfiguration.refresh.count"
+	changedKeysMetricName     string = "appconfiguration.refresh.changed_keys"
+	failoverCountMetricName   string = "appconfiguration.failover.count"
+	replicaCountMetricName    string = "appconfiguration.replica.count"
+	stalenessMetricName       string = "appconfiguration.staleness"
+	outcomeSucceeded          string = "succeeded"
+	outcomeFailed             string = "failed"
+	outcomeChanged            string = "changed"
+	outcomeUnchanged          string = "unchanged"
+	outcomeAttributeKey       string = "appconfiguration.outcome"
+	keyFilterAttributeKey     string = "appconfiguration.key_filter"
+	labelFilterAttributeKey   string = "appconfiguration.label_filter"
+	snapshotNameAttributeKey  string = "appconfiguration.snapshot_name"
+	pageCountAttributeKey     string = "appconfiguration.page_count"
+	settingKeyAttributeKey    string = "appconfiguration.key"
+	endpointAttributeKey      string = "appconfiguration.endpoint"
+	replicaCountAttributeKey  string = "appconfiguration.replica_count"
+	serverAddressAttributeKey string = "server.address"
+)
@@ -7,16 +7,22 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"net"
 	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/data/azappconfig/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // mockClientManager implements the clientManager interface for testing
@@ -53,7 +59,7 @@ func TestExecuteFailoverPolicy_Success_FirstClient(t *testing.T) {
 	}
 
 	operationCallCount := 0
-	operation := func(client *azappconfig.Client) error {
+	operation := func(ctx context.Context, client *azappconfig.Client) error {
 		operationCallCount++
 		if client == client1 {
 			return nil // Success on first client
@@ -88,7 +94,7 @@ func TestExecuteFailoverPolicy_FailoverToSecondClient(t *testing.T) {
 	}
 
 	operationCallCount := 0
-	operation := func(client *azappconfig.Client) error {
+	operation := func(ctx context.Context, client *azappconfig.Client) error {
 		operationCallCount++
 		if client == client1 {
 			// Simulate a failoverable error (network error)
@@ -129,7 +135,7 @@ func TestExecuteFailoverPolicy_AllClientsFail_FailoverableErrors(t *testing.T) {
 	}
 
 	operationCallCount := 0
-	operation := func(client *azappconfig.Client) error {
+	operation := func(ctx context.Context, client *azappconfig.Client) error {
 		operationCallCount++
 		if client == client1 {
 			return &azcore.ResponseError{StatusCode: http.StatusServiceUnavailable}
@@ -170,7 +176,7 @@ func TestExecuteFailoverPolicy_NonFailoverableError(t *testing.T) {
 
 	operationCallCount := 0
 	nonFailoverableError := &azcore.ResponseError{StatusCode: http.StatusBadRequest}
-	operation := func(client *azappconfig.Client) error {
+	operation := func(ctx context.Context, client *azappconfig.Client) error {
 		operationCallCount++
 		return nonFailoverableError
 	}
@@ -196,7 +202,7 @@ func TestExecuteFailoverPolicy_NoClientsAvailable(t *testing.T) {
 	}
 
 	operationCallCount := 0
-	operation := func(client *azappconfig.Client) error {
+	operation := func(ctx context.Context, client *azappconfig.Client) error {
 		operationCallCount++
 		return nil
 	}
@@ -221,7 +227,7 @@ func TestExecuteFailoverPolicy_ClientManagerError(t *testing.T) {
 	}
 
 	operationCallCount := 0
-	operation := func(client *azappconfig.Client) error {
+	operation := func(ctx context.Context, client *azappconfig.Client) error {
 		operationCallCount++
 		return nil
 	}
@@ -364,7 +370,7 @@ func TestExecuteFailoverPolicy_LoadBalancing_RotateClients(t *testing.T) {
 	}
 
 	var usedClient *azappconfig.Client
-	operation := func(client *azappconfig.Client) error {
+	operation := func(ctx context.Context, client *azappconfig.Client) error {
 		usedClient = client
 		return nil // Success
 	}
@@ -401,7 +407,7 @@ func TestExecuteFailoverPolicy_LoadBalancing_LastClientSuccessful(t *testing.T)
 	}
 
 	var usedClient *azappconfig.Client
-	operation := func(client *azappconfig.Client) error {
+	operation := func(ctx context.Context, client *azappconfig.Client) error {
 		usedClient = client
 		return nil // Success
 	}
@@ -436,7 +442,7 @@ func TestExecuteFailoverPolicy_LoadBalancing_Disabled(t *testing.T) {
 	}
 
 	var usedClient *azappconfig.Client
-	operation := func(client *azappconfig.Client) error {
+	operation := func(ctx context.Context, client *azappconfig.Client) error {
 		usedClient = client
 		return nil // Success
 	}
@@ -470,7 +476,7 @@ func TestExecuteFailoverPolicy_LoadBalancing_SingleClient(t *testing.T) {
 	}
 
 	var usedClient *azappconfig.Client
-	operation := func(client *azappconfig.Client) error {
+	operation := func(ctx context.Context, client *azappconfig.Client) error {
 		usedClient = client
 		return nil // Success
 	}
@@ -628,7 +634,7 @@ func TestRotateSliceInPlace(t *testing.T) {
 }
 
 // Test client wrapper backoff behavior
-func TestClientWrapper_UpdateBackoffStatus(t *testing.T) {
+func TestClientWrapper_RecordFailureAndSuccess(t *testing.T) {
 	client := &configurationClientWrapper{
 		endpoint:       "https://test.azconfig.io",
 		client:         &azappconfig.Client{},
@@ -637,22 +643,35 @@ func TestClientWrapper_UpdateBackoffStatus(t *testing.T) {
 	}
 
 	// Test successful operation resets backoff
-	client.updateBackoffStatus(true)
+	client.recordSuccess(time.Millisecond)
 	assert.Equal(t, 0, client.failedAttempts)
 	assert.True(t, client.backOffEndTime.IsZero())
 
 	// Test failed operation increments attempts and sets backoff
-	client.updateBackoffStatus(false)
+	requestErr := errors.New("request failed")
+	client.recordFailure(requestErr)
 	assert.Equal(t, 1, client.failedAttempts)
+	assert.Equal(t, requestErr, client.lastError)
 	assert.False(t, client.backOffEndTime.IsZero())
 	assert.True(t, client.backOffEndTime.After(time.Now()))
+	assert.False(t, client.isAvailable(time.Now()))
+
+	// Without the circuit breaker, a request can be sent to the client once it is available again
+	probe, ok := client.beginRequest(time.Now())
+	assert.False(t, probe)
+	assert.True(t, ok)
 
 	// Test multiple failures increase backoff duration
 	firstBackoffEnd := client.backOffEndTime
 	time.Sleep(1 * time.Millisecond) // Ensure time progression
-	client.updateBackoffStatus(false)
+	client.recordFailure(requestErr)
 	assert.Equal(t, 2, client.failedAttempts)
 	assert.True(t, client.backOffEndTime.After(firstBackoffEnd))
+
+	// Test a successful operation after failures ends the backoff
+	client.recordSuccess(time.Millisecond)
+	assert.Equal(t, 0, client.failedAttempts)
+	assert.True(t, client.isAvailable(time.Now()))
 }
 
 // Test client wrapper backoff duration calculation
@@ -697,7 +716,7 @@ func TestStartupWithRetry_Success_FirstAttempt(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	err := azappcfg.startupWithRetry(ctx, 10*time.Second, operation)
+	err := azappcfg.startupWithRetry(ctx, StartupOptions{Timeout: 10 * time.Second}, operation)
 
 	assert.NoError(t, err)
 }
@@ -726,7 +745,7 @@ func TestStartupWithRetry_Success_AfterRetriableError(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	err := azappcfg.startupWithRetry(ctx, 10*time.Second, operation)
+	err := azappcfg.startupWithRetry(ctx, StartupOptions{Timeout: 10 * time.Second}, operation)
 
 	assert.NoError(t, err)
 	assert.Equal(t, 2, callCount, "Operation should be called twice")
@@ -753,7 +772,7 @@ func TestStartupWithRetry_NonRetriableError(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	err := azappcfg.startupWithRetry(ctx, 10*time.Second, operation)
+	err := azappcfg.startupWithRetry(ctx, StartupOptions{Timeout: 10 * time.Second}, operation)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "load from Azure App Configuration failed with non-retriable error")
@@ -782,7 +801,7 @@ func TestStartupWithRetry_Timeout(t *testing.T) {
 
 	ctx := context.Background()
 	// Use a very short timeout to trigger timeout quickly
-	err := azappcfg.startupWithRetry(ctx, 100*time.Millisecond, operation)
+	err := azappcfg.startupWithRetry(ctx, StartupOptions{Timeout: 100 * time.Millisecond}, operation)
 
 	assert.Error(t, err)
 	assert.True(t,
@@ -820,7 +839,7 @@ func TestStartupWithRetry_ContextCancelledDuringBackoff(t *testing.T) {
 		cancel()
 	}()
 
-	err := azappcfg.startupWithRetry(ctx, 10*time.Second, operation)
+	err := azappcfg.startupWithRetry(ctx, StartupOptions{Timeout: 10 * time.Second}, operation)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "load from Azure App Configuration timed out: context canceled")
@@ -846,7 +865,7 @@ func TestStartupWithRetry_DefaultTimeout(t *testing.T) {
 
 	ctx := context.Background()
 	// Pass zero timeout to test default timeout usage
-	err := azappcfg.startupWithRetry(ctx, 0, operation)
+	err := azappcfg.startupWithRetry(ctx, StartupOptions{}, operation)
 
 	assert.NoError(t, err)
 	assert.Equal(t, 1, callCount, "Operation should be called once")
@@ -876,7 +895,7 @@ func TestStartupWithRetry_InsufficientTimeForRetry(t *testing.T) {
 
 	ctx := context.Background()
 	// Use a short timeout that will be consumed by the first failure and not allow retry
-	err := azappcfg.startupWithRetry(ctx, 80*time.Millisecond, operation)
+	err := azappcfg.startupWithRetry(ctx, StartupOptions{Timeout: 80 * time.Millisecond}, operation)
 
 	assert.Error(t, err)
 	assert.True(t,
@@ -885,3 +904,755 @@ func TestStartupWithRetry_InsufficientTimeForRetry(t *testing.T) {
 		"Error should indicate timeout or insufficient time: %v", err)
 	assert.True(t, callCount >= 1, "Operation should be called at least once")
 }
+
+// Test executeFailoverPolicy fails over when a request exceeds the request timeout
+func TestExecuteFailoverPolicy_RequestTimeout(t *testing.T) {
+	mockClientManager := new(mockClientManager)
+
+	client1 := &azappconfig.Client{}
+	client2 := &azappconfig.Client{}
+	clientWrappers := []*configurationClientWrapper{
+		{endpoint: "https://primary.azconfig.io", client: client1},
+		{endpoint: "https://replica.azconfig.io", client: client2},
+	}
+	mockClientManager.On("getClients", mock.Anything).Return(clientWrappers, nil)
+
+	azappcfg := &AzureAppConfiguration{
+		clientManager:     mockClientManager,
+		operationTimeouts: OperationTimeouts{Request: 50 * time.Millisecond},
+	}
+
+	calledClients := make([]*azappconfig.Client, 0)
+	operation := func(ctx context.Context, client *azappconfig.Client) error {
+		calledClients = append(calledClients, client)
+		if client == client1 {
+			// The primary endpoint does not respond
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	}
+
+	err := azappcfg.executeFailoverPolicy(context.Background(), operation)
+
+	require.NoError(t, err)
+	assert.Equal(t, []*azappconfig.Client{client1, client2}, calledClients)
+	assert.ErrorIs(t, clientWrappers[0].lastError, context.DeadlineExceeded)
+	assert.Equal(t, 1, clientWrappers[0].failedAttempts)
+	mockClientManager.AssertExpectations(t)
+}
+
+// Test executeFailoverPolicy does not fail over when the caller's context is done
+func TestExecuteFailoverPolicy_ContextDone(t *testing.T) {
+	mockClientManager := new(mockClientManager)
+
+	client1 := &azappconfig.Client{}
+	client2 := &azappconfig.Client{}
+	mockClientManager.On("getClients", mock.Anything).Return([]*configurationClientWrapper{
+		{endpoint: "https://primary.azconfig.io", client: client1},
+		{endpoint: "https://replica.azconfig.io", client: client2},
+	}, nil)
+
+	azappcfg := &AzureAppConfiguration{
+		clientManager: mockClientManager,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	calledClients := make([]*azappconfig.Client, 0)
+	operation := func(ctx context.Context, client *azappconfig.Client) error {
+		calledClients = append(calledClients, client)
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	err := azappcfg.executeFailoverPolicy(ctx, operation)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, []*azappconfig.Client{client1}, calledClients)
+	mockClientManager.AssertNotCalled(t, "refreshClients", mock.Anything)
+}
+
+// Test startupWithRetry with a custom backoff policy reporting each failed attempt
+func TestStartupWithRetry_CustomBackoff(t *testing.T) {
+	azappcfg := &AzureAppConfiguration{}
+
+	callCount := 0
+	retriableError := &azcore.ResponseError{StatusCode: http.StatusServiceUnavailable}
+	operation := func(ctx context.Context) error {
+		callCount++
+		if callCount < 3 {
+			return retriableError
+		}
+		return nil
+	}
+
+	var attempts []StartupAttempt
+	options := StartupOptions{
+		Timeout: 10 * time.Second,
+		Backoff: func(attempt int, elapsed time.Duration) time.Duration {
+			return time.Duration(attempt) * time.Millisecond
+		},
+		OnAttemptFailed: func(attempt StartupAttempt) {
+			attempts = append(attempts, attempt)
+		},
+	}
+
+	err := azappcfg.startupWithRetry(context.Background(), options, operation)
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, callCount)
+	require.Len(t, attempts, 2)
+	assert.Equal(t, 1, attempts[0].Attempt)
+	assert.Equal(t, retriableError, attempts[0].Error)
+	assert.True(t, attempts[0].Retriable)
+	assert.Equal(t, time.Millisecond, attempts[0].NextRetryDelay)
+	assert.Equal(t, 2, attempts[1].Attempt)
+	assert.Equal(t, 2*time.Millisecond, attempts[1].NextRetryDelay)
+}
+
+// Test startupWithRetry stops retrying after the max attempts
+func TestStartupWithRetry_MaxAttempts(t *testing.T) {
+	azappcfg := &AzureAppConfiguration{}
+
+	callCount := 0
+	operation := func(ctx context.Context) error {
+		callCount++
+		return fmt.Errorf("failed to load: %w", errNoClientAvailable)
+	}
+
+	var attempts []StartupAttempt
+	options := StartupOptions{
+		MaxAttempts: 3,
+		Backoff: func(attempt int, elapsed time.Duration) time.Duration {
+			return 0
+		},
+		OnAttemptFailed: func(attempt StartupAttempt) {
+			attempts = append(attempts, attempt)
+		},
+	}
+
+	err := azappcfg.startupWithRetry(context.Background(), options, operation)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed after 3 attempts")
+	assert.ErrorIs(t, err, errNoClientAvailable)
+	assert.Equal(t, 3, callCount)
+	require.Len(t, attempts, 3)
+	assert.Equal(t, time.Duration(0), attempts[2].NextRetryDelay)
+}
+
+// Test startupWithRetry does not retry in fail-fast mode
+func TestStartupWithRetry_FailFast(t *testing.T) {
+	azappcfg := &AzureAppConfiguration{}
+
+	callCount := 0
+	operation := func(ctx context.Context) error {
+		callCount++
+		return &azcore.ResponseError{StatusCode: http.StatusServiceUnavailable}
+	}
+
+	var attempts []StartupAttempt
+	options := StartupOptions{
+		FailFast: true,
+		OnAttemptFailed: func(attempt StartupAttempt) {
+			attempts = append(attempts, attempt)
+		},
+	}
+
+	err := azappcfg.startupWithRetry(context.Background(), options, operation)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed after 1 attempts")
+	assert.Equal(t, 1, callCount)
+	require.Len(t, attempts, 1)
+	assert.True(t, attempts[0].Retriable)
+}
+
+// Test startupWithRetry with a custom retriable error classification
+func TestStartupWithRetry_CustomIsRetriable(t *testing.T) {
+	azappcfg := &AzureAppConfiguration{}
+
+	badRequestError := &azcore.ResponseError{StatusCode: http.StatusBadRequest}
+	callCount := 0
+	operation := func(ctx context.Context) error {
+		callCount++
+		if callCount == 1 {
+			return badRequestError
+		}
+		return &azcore.ResponseError{StatusCode: http.StatusServiceUnavailable}
+	}
+
+	var attempts []StartupAttempt
+	options := StartupOptions{
+		Backoff: func(attempt int, elapsed time.Duration) time.Duration {
+			return time.Millisecond
+		},
+		IsRetriable: func(err error) bool {
+			return errors.Is(err, badRequestError)
+		},
+		OnAttemptFailed: func(attempt StartupAttempt) {
+			attempts = append(attempts, attempt)
+		},
+	}
+
+	err := azappcfg.startupWithRetry(context.Background(), options, operation)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "non-retriable error")
+	assert.Equal(t, 2, callCount)
+	require.Len(t, attempts, 2)
+	assert.True(t, attempts[0].Retriable)
+	assert.False(t, attempts[1].Retriable)
+}
+
+func TestIsRetriableStartupError(t *testing.T) {
+	assert.True(t, isRetriableStartupError(&azcore.ResponseError{StatusCode: http.StatusServiceUnavailable}))
+	assert.True(t, isRetriableStartupError(fmt.Errorf("load failed: %w", errNoClientAvailable)))
+	assert.True(t, isRetriableStartupError(fmt.Errorf("%w: %v", errAllClientsFailed, []error{fmt.Errorf("timeout")})))
+	assert.False(t, isRetriableStartupError(&azcore.ResponseError{StatusCode: http.StatusBadRequest}))
+	assert.False(t, isRetriableStartupError(fmt.Errorf("no client is available")))
+}
+
+// Test ReplicaStatus reports the backoff state of each endpoint and the endpoint used by the last request
+func TestReplicaStatus(t *testing.T) {
+	client1 := &azappconfig.Client{}
+	client2 := &azappconfig.Client{}
+
+	manager := &configurationClientManager{
+		replicaDiscoveryEnabled:   true,
+		lastFallbackClientAttempt: time.Now(),
+		endpoint:                  "https://primary.azconfig.io",
+		staticClient:              &configurationClientWrapper{endpoint: "https://primary.azconfig.io", client: client1},
+	}
+	manager.setDynamicClients([]*configurationClientWrapper{{endpoint: "https://replica.azconfig.io", client: client2, discovered: true}})
+
+	azappcfg := &AzureAppConfiguration{
+		clientManager: manager,
+	}
+
+	status := azappcfg.ReplicaStatus()
+	assert.Empty(t, status.LastUsedEndpoint)
+	require.Len(t, status.Replicas, 2)
+	assert.Equal(t, "https://primary.azconfig.io", status.Replicas[0].Endpoint)
+	assert.False(t, status.Replicas[0].Discovered)
+	assert.Equal(t, "https://replica.azconfig.io", status.Replicas[1].Endpoint)
+	assert.True(t, status.Replicas[1].Discovered)
+
+	primaryError := &net.DNSError{Err: "no such host", Name: "primary.azconfig.io"}
+	operation := func(ctx context.Context, client *azappconfig.Client) error {
+		if client == client1 {
+			return primaryError
+		}
+		return nil
+	}
+
+	err := azappcfg.executeFailoverPolicy(context.Background(), operation)
+	require.NoError(t, err)
+
+	status = azappcfg.ReplicaStatus()
+	assert.Equal(t, "https://replica.azconfig.io", status.LastUsedEndpoint)
+
+	primary := status.Replicas[0]
+	assert.True(t, primary.InBackoff)
+	assert.True(t, primary.BackoffEndTime.After(time.Now()))
+	assert.Equal(t, 1, primary.ConsecutiveFailures)
+	assert.True(t, primary.LastSuccessTime.IsZero())
+	assert.Equal(t, primaryError, primary.LastError)
+
+	replica := status.Replicas[1]
+	assert.False(t, replica.InBackoff)
+	assert.Equal(t, 0, replica.ConsecutiveFailures)
+	assert.False(t, replica.LastSuccessTime.IsZero())
+	assert.Nil(t, replica.LastError)
+}
+
+// Test ReplicaStatus keeps the last error of an endpoint after it recovers
+func TestReplicaStatus_Recovered(t *testing.T) {
+	clientWrapper := &configurationClientWrapper{endpoint: "https://primary.azconfig.io"}
+	lastError := &azcore.ResponseError{StatusCode: http.StatusServiceUnavailable}
+
+	clientWrapper.recordFailure(lastError)
+	clientWrapper.recordFailure(lastError)
+	status := clientWrapper.getStatus(time.Now())
+	assert.True(t, status.InBackoff)
+	assert.Equal(t, 2, status.ConsecutiveFailures)
+
+	clientWrapper.recordSuccess(time.Millisecond)
+	status = clientWrapper.getStatus(time.Now())
+	assert.False(t, status.InBackoff)
+	assert.True(t, status.BackoffEndTime.IsZero())
+	assert.Equal(t, 0, status.ConsecutiveFailures)
+	assert.False(t, status.LastSuccessTime.IsZero())
+	assert.Equal(t, lastError, status.LastError)
+}
+
+// Test replica endpoints declared in the options are used alongside the discovered replicas
+func TestNewConfigurationClientManager_ReplicaEndpoints(t *testing.T) {
+	authOptions := AuthenticationOptions{
+		ConnectionString: "Endpoint=https://primary.example.com;Id=test-id;Secret=dGVzdA==",
+	}
+	options := &Options{
+		ReplicaEndpoints: []string{"https://replica1.example.com/", "https://PRIMARY.example.com", "https://replica2.example.com"},
+		TrustedDomains:   []string{".example.com"},
+	}
+
+	manager, err := newConfigurationClientManager(authOptions, options)
+	require.NoError(t, err)
+	require.Len(t, manager.replicaClients, 2)
+	assert.Equal(t, "https://replica1.example.com", manager.replicaClients[0].endpoint)
+	assert.Equal(t, "https://replica2.example.com", manager.replicaClients[1].endpoint)
+
+	// Prevent the discovery from running in the background
+	manager.lastFallbackClientAttempt = time.Now()
+	manager.processSrvTargetHosts([]string{"primary.example.com", "replica1.example.com", "replica3.example.com", "replica.contoso.com"})
+	require.Len(t, manager.getDynamicClients(), 1)
+	assert.Equal(t, "https://replica3.example.com", manager.getDynamicClients()[0].endpoint)
+
+	manager.replicaClients[0].recordFailure(errors.New("request failed"))
+	clients, err := manager.getClients(context.Background())
+	require.NoError(t, err)
+	endpoints := make([]string, 0, len(clients))
+	for _, clientWrapper := range clients {
+		endpoints = append(endpoints, clientWrapper.endpoint)
+	}
+	assert.Equal(t, []string{"https://primary.example.com", "https://replica2.example.com", "https://replica3.example.com"}, endpoints)
+
+	status := (&AzureAppConfiguration{clientManager: manager}).ReplicaStatus()
+	require.Len(t, status.Replicas, 4)
+	assert.False(t, status.Replicas[1].Discovered)
+	assert.True(t, status.Replicas[1].InBackoff)
+	assert.True(t, status.Replicas[3].Discovered)
+}
+
+// Test replica endpoints are used when replica discovery is disabled
+func TestGetClients_ReplicaEndpointsWithoutDiscovery(t *testing.T) {
+	manager := &configurationClientManager{
+		endpoint:       "https://primary.example.com",
+		staticClient:   &configurationClientWrapper{endpoint: "https://primary.example.com"},
+		replicaClients: []*configurationClientWrapper{{endpoint: "https://replica.example.com"}},
+	}
+	manager.setDynamicClients([]*configurationClientWrapper{{endpoint: "https://replica.azconfig.io"}})
+
+	clients, err := manager.getClients(context.Background())
+	require.NoError(t, err)
+	require.Len(t, clients, 2)
+	assert.Equal(t, "https://replica.example.com", clients[1].endpoint)
+}
+
+func TestIsTrustedHost(t *testing.T) {
+	manager := &configurationClientManager{
+		validDomain:    getValidDomain("https://store.azconfig.io"),
+		trustedDomains: []string{"example.com"},
+	}
+
+	assert.True(t, manager.isTrustedHost("store-replica.azconfig.io"))
+	assert.True(t, manager.isTrustedHost("replica.EXAMPLE.com"))
+	assert.True(t, manager.isTrustedHost("example.com"))
+	assert.False(t, manager.isTrustedHost("badexample.com"))
+	assert.False(t, manager.isTrustedHost("replica.contoso.com"))
+}
+
+// mockSRVResolver simulates the SRV records of the origin and the alternative replicas
+type mockSRVResolver struct {
+	records map[string][]*net.SRV
+	err     error
+	delay   time.Duration
+}
+
+func (r *mockSRVResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	if r.delay > 0 {
+		select {
+		case <-time.After(r.delay):
+		case <-ctx.Done():
+			return "", nil, ctx.Err()
+		}
+	}
+
+	if r.err != nil {
+		return "", nil, r.err
+	}
+
+	fqdn := "_" + service + "._" + proto + "." + name
+	records, ok := r.records[fqdn]
+	if !ok {
+		return "", nil, &net.DNSError{Err: "no such host", Name: fqdn, IsNotFound: true}
+	}
+
+	return fqdn, records, nil
+}
+
+func TestQuerySrvTargetHost(t *testing.T) {
+	resolver := &mockSRVResolver{
+		records: map[string][]*net.SRV{
+			"_origin._tcp.store.azconfig.io":   {{Target: "store.azconfig.io."}},
+			"_alt0._tcp.store.azconfig.io":     {{Target: "store-eastus.azconfig.io."}},
+			"_alt1._tcp.store.azconfig.io":     {{Target: "store-westus.azconfig.io."}, {Target: ""}},
+			"_origin._tcp.store-no-replica.io": {},
+		},
+	}
+
+	hosts, err := querySrvTargetHost(context.Background(), resolver, "store.azconfig.io")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"store.azconfig.io", "store-eastus.azconfig.io", "store-westus.azconfig.io"}, hosts)
+
+	hosts, err = querySrvTargetHost(context.Background(), resolver, "store-no-replica.io")
+	require.NoError(t, err)
+	assert.Empty(t, hosts)
+
+	hosts, err = querySrvTargetHost(context.Background(), resolver, "unknown.azconfig.io")
+	require.NoError(t, err)
+	assert.Empty(t, hosts)
+
+	resolver.err = &net.DNSError{Err: "server misbehaving", Name: "store.azconfig.io", IsTemporary: true}
+	_, err = querySrvTargetHost(context.Background(), resolver, "store.azconfig.io")
+	assert.Error(t, err)
+}
+
+func TestDiscoverReplicas(t *testing.T) {
+	authOptions := AuthenticationOptions{
+		ConnectionString: "Endpoint=https://store.azconfig.io;Id=test-id;Secret=dGVzdA==",
+	}
+	resolver := &mockSRVResolver{
+		records: map[string][]*net.SRV{
+			"_origin._tcp.store.azconfig.io": {{Target: "store.azconfig.io."}},
+			"_alt0._tcp.store.azconfig.io":   {{Target: "store-eastus.azconfig.io."}},
+		},
+	}
+	options := &Options{
+		ReplicaDiscoveryOptions: ReplicaDiscoveryOptions{
+			Resolver:        resolver,
+			Timeout:         50 * time.Millisecond,
+			RefreshInterval: time.Minute,
+		},
+	}
+
+	manager, err := newConfigurationClientManager(authOptions, options)
+	require.NoError(t, err)
+	assert.Equal(t, 50*time.Millisecond, manager.discoveryTimeout)
+	assert.Equal(t, time.Minute, manager.discoveryInterval)
+
+	err = manager.discoverReplicas(context.Background(), "store.azconfig.io")
+	require.NoError(t, err)
+	require.Len(t, manager.getDynamicClients(), 1)
+	assert.Equal(t, "https://store-eastus.azconfig.io", manager.getDynamicClients()[0].endpoint)
+	assert.False(t, manager.dynamicClients.Load().refreshTime.IsZero())
+
+	// The discovery is abandoned when the resolver does not respond within the timeout
+	resolver.delay = time.Second
+	err = manager.discoverReplicas(context.Background(), "store.azconfig.io")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Len(t, manager.getDynamicClients(), 1)
+
+	// The discovery is abandoned when the context of the caller is canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = manager.discoverReplicas(ctx, "store.azconfig.io")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// blockingSRVResolver blocks each lookup until the context is done and reports the error of the context
+type blockingSRVResolver struct {
+	errs chan error
+}
+
+func (r *blockingSRVResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	<-ctx.Done()
+	r.errs <- ctx.Err()
+	return "", nil, ctx.Err()
+}
+
+// Test the background discovery started by a load still completes after the load has returned and canceled its context
+func TestDiscoverFallbackClients_OutlivesOperation(t *testing.T) {
+	authOptions := AuthenticationOptions{
+		ConnectionString: "Endpoint=https://store.azconfig.io;Id=test-id;Secret=dGVzdA==",
+	}
+	options := &Options{
+		ReplicaDiscoveryOptions: ReplicaDiscoveryOptions{
+			Resolver: &mockSRVResolver{
+				records: map[string][]*net.SRV{
+					"_origin._tcp.store.azconfig.io": {{Target: "store.azconfig.io."}},
+					"_alt0._tcp.store.azconfig.io":   {{Target: "store-eastus.azconfig.io."}},
+				},
+				delay: 50 * time.Millisecond,
+			},
+			Timeout: 5 * time.Second,
+		},
+	}
+
+	manager, err := newConfigurationClientManager(authOptions, options)
+	require.NoError(t, err)
+	azappcfg := &AzureAppConfiguration{clientManager: manager}
+
+	err = azappcfg.startup(context.Background(), StartupOptions{}, func(ctx context.Context) error {
+		return azappcfg.executeFailoverPolicy(ctx, func(ctx context.Context, client *azappconfig.Client) error {
+			return nil
+		})
+	})
+	require.NoError(t, err)
+	assert.Nil(t, manager.getDynamicClients())
+
+	require.Eventually(t, func() bool {
+		return len(manager.getDynamicClients()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "https://store-eastus.azconfig.io", manager.getDynamicClients()[0].endpoint)
+}
+
+// Test the background discovery is canceled when the instance is closed
+func TestDiscoverFallbackClients_Close(t *testing.T) {
+	resolver := &blockingSRVResolver{errs: make(chan error, 1)}
+	manager := &configurationClientManager{
+		replicaDiscoveryEnabled: true,
+		endpoint:                "https://store.azconfig.io",
+		srvResolver:             resolver,
+		discoveryTimeout:        time.Minute,
+		logger:                  slog.New(slog.DiscardHandler),
+	}
+	manager.closeCtx, manager.closeCancel = context.WithCancel(context.Background())
+
+	manager.refreshClients(context.Background())
+	require.NoError(t, (&AzureAppConfiguration{clientManager: manager}).Close())
+
+	select {
+	case err := <-resolver.errs:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		t.Fatal("The discovery should be canceled when the instance is closed")
+	}
+	assert.Nil(t, manager.getDynamicClients())
+}
+
+func TestNewConfigurationClientManager_DefaultReplicaDiscoveryOptions(t *testing.T) {
+	authOptions := AuthenticationOptions{
+		ConnectionString: "Endpoint=https://store.azconfig.io;Id=test-id;Secret=dGVzdA==",
+	}
+
+	manager, err := newConfigurationClientManager(authOptions, &Options{})
+	require.NoError(t, err)
+	assert.Equal(t, net.DefaultResolver, manager.srvResolver)
+	assert.Equal(t, defaultReplicaDiscoveryTimeout, manager.discoveryTimeout)
+	assert.Equal(t, defaultReplicaDiscoveryInterval, manager.discoveryInterval)
+}
+
+// Test concurrent calls to executeFailoverPolicy update the tracing options and the last successful endpoint safely
+func TestExecuteFailoverPolicy_Concurrency(t *testing.T) {
+	primary := &azappconfig.Client{}
+	manager := &configurationClientManager{
+		endpoint:       "https://primary.azconfig.io",
+		staticClient:   &configurationClientWrapper{endpoint: "https://primary.azconfig.io", client: primary},
+		replicaClients: []*configurationClientWrapper{{endpoint: "https://replica.azconfig.io", client: &azappconfig.Client{}}},
+	}
+
+	azappcfg := &AzureAppConfiguration{
+		clientManager:        manager,
+		loadBalancingEnabled: true,
+	}
+
+	const iterations = 100
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				_ = azappcfg.executeFailoverPolicy(context.Background(), func(ctx context.Context, client *azappconfig.Client) error {
+					if client == primary && i%2 == 0 {
+						return &net.DNSError{Err: "no such host", Name: "primary.azconfig.io"}
+					}
+					return nil
+				})
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < iterations; i++ {
+			tracingOptions := azappcfg.getTracingOptions()
+			assert.LessOrEqual(t, tracingOptions.ReplicaCount, 1)
+		}
+	}()
+
+	wg.Wait()
+
+	assert.Contains(t, []string{"https://primary.azconfig.io", "https://replica.azconfig.io"}, azappcfg.getLastSuccessfulEndpoint())
+}
+
+// Test load, refresh, replica discovery and status introspection running concurrently, which is meaningful with -race
+func TestConfigurationClientManager_Concurrency(t *testing.T) {
+	authOptions := AuthenticationOptions{
+		ConnectionString: "Endpoint=https://store.azconfig.io;Id=test-id;Secret=dGVzdA==",
+	}
+	resolver := &mockSRVResolver{
+		records: map[string][]*net.SRV{
+			"_origin._tcp.store.azconfig.io": {{Target: "store.azconfig.io."}},
+			"_alt0._tcp.store.azconfig.io":   {{Target: "store-eastus.azconfig.io."}, {Target: "store-westus.azconfig.io."}},
+		},
+	}
+	options := &Options{
+		ReplicaEndpoints: []string{"https://store-private.azconfig.io"},
+		ReplicaDiscoveryOptions: ReplicaDiscoveryOptions{
+			Resolver: resolver,
+		},
+	}
+
+	manager, err := newConfigurationClientManager(authOptions, options)
+	require.NoError(t, err)
+
+	azappcfg := &AzureAppConfiguration{
+		clientManager:   manager,
+		replicaSelector: NewLatencyReplicaSelector(),
+	}
+
+	const iterations = 200
+	var wg sync.WaitGroup
+
+	// Load and refresh, which are serialized by the provider
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < iterations; i++ {
+			attempt := 0
+			_ = azappcfg.executeFailoverPolicy(context.Background(), func(ctx context.Context, client *azappconfig.Client) error {
+				attempt++
+				if (i+attempt)%3 == 0 {
+					return &net.DNSError{Err: "no such host", Name: "store.azconfig.io"}
+				}
+				return nil
+			})
+		}
+	}()
+
+	// Replica discovery
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < iterations; i++ {
+			assert.NoError(t, manager.discoverReplicas(context.Background(), "store.azconfig.io"))
+		}
+	}()
+
+	// Concurrent requests updating the state of the same clients
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				clients, err := manager.getClients(context.Background())
+				assert.NoError(t, err)
+				for _, clientWrapper := range clients {
+					if i%2 == 0 {
+						clientWrapper.recordFailure(fmt.Errorf("request failed"))
+					} else {
+						clientWrapper.recordSuccess(time.Millisecond)
+					}
+				}
+				manager.refreshClients(context.Background())
+			}
+		}()
+	}
+
+	// Status introspection
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < iterations; i++ {
+			status := azappcfg.ReplicaStatus()
+			assert.NotEmpty(t, status.Replicas)
+		}
+	}()
+
+	wg.Wait()
+
+	status := azappcfg.ReplicaStatus()
+	require.Len(t, status.Replicas, 4)
+	assert.Equal(t, "https://store.azconfig.io", status.Replicas[0].Endpoint)
+	assert.Equal(t, "https://store-private.azconfig.io", status.Replicas[1].Endpoint)
+	assert.ElementsMatch(t, []string{"https://store-eastus.azconfig.io", "https://store-westus.azconfig.io"},
+		[]string{status.Replicas[2].Endpoint, status.Replicas[3].Endpoint})
+}
+
+// settingsTransport serves a key-value whose value and ETag change with every request, so that every refresh finds a change
+type settingsTransport struct {
+	requests atomic.Int64
+}
+
+func (t *settingsTransport) Do(req *http.Request) (*http.Response, error) {
+	n := t.requests.Add(1)
+	body := fmt.Sprintf(`{"items":[{"key":"app","value":"v%d","etag":"etag-%d"}]}`, n, n)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header: http.Header{
+			"Content-Type": {"application/vnd.microsoft.appconfig.kvset+json"},
+			"Etag":         {fmt.Sprintf(`"page-%d"`, n)},
+			"Sync-Token":   {fmt.Sprintf("token=value;sn=%d", n)},
+		},
+		Body:    io.NopCloser(strings.NewReader(body)),
+		Request: req,
+	}, nil
+}
+
+// Test refresh and status introspection running concurrently on a loaded instance, which is meaningful with -race
+func TestAzureAppConfiguration_RefreshAndReplicaStatusConcurrency(t *testing.T) {
+	transport := &settingsTransport{}
+	options := &Options{
+		ClientOptions: &azappconfig.ClientOptions{
+			ClientOptions: azcore.ClientOptions{
+				Transport: transport,
+				Retry:     policy.RetryOptions{MaxRetries: -1},
+			},
+		},
+		ReplicaDiscoveryOptions: ReplicaDiscoveryOptions{
+			Resolver: &mockSRVResolver{
+				records: map[string][]*net.SRV{
+					"_origin._tcp.store.azconfig.io": {{Target: "store.azconfig.io."}},
+					"_alt0._tcp.store.azconfig.io":   {{Target: "store-eastus.azconfig.io."}},
+				},
+			},
+		},
+		RefreshOptions:       KeyValueRefreshOptions{Enabled: true},
+		LoadBalancingEnabled: true,
+	}
+
+	azappcfg, err := Load(context.Background(), AuthenticationOptions{
+		ConnectionString: "Endpoint=https://store.azconfig.io;Id=test-id;Secret=dGVzdA==",
+	}, options)
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, azappcfg.Close())
+	}()
+	azappcfg.kvRefreshTimer = &mockRefreshCondition{shouldRefresh: true}
+
+	const iterations = 50
+	var wg sync.WaitGroup
+	for g := 0; g < 2; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				assert.NoError(t, azappcfg.Refresh(context.Background()))
+			}
+		}()
+	}
+
+	for g := 0; g < 2; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				status := azappcfg.ReplicaStatus()
+				assert.NotEmpty(t, status.Replicas)
+				assert.NotEmpty(t, status.LastUsedEndpoint)
+				_, err := azappcfg.GetBytes(nil)
+				assert.NoError(t, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Greater(t, transport.requests.Load(), int64(1))
+	assert.Contains(t, []string{"https://store.azconfig.io", "https://store-eastus.azconfig.io"}, azappcfg.ReplicaStatus().LastUsedEndpoint)
+}
@@ -0,0 +1,306 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package featureflags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// ErrFeatureFilterNotRegistered is returned when a feature flag references a client filter that is neither
+// built-in nor registered in EvaluatorOptions, and the MissingFilterPolicy is MissingFilterPolicyError.
+var ErrFeatureFilterNotRegistered = errors.New("feature filter is not registered")
+
+// MissingFilterPolicy determines how a client filter without a registered implementation is handled
+type MissingFilterPolicy string
+
+const (
+	// MissingFilterPolicyError fails the evaluation with ErrFeatureFilterNotRegistered
+	MissingFilterPolicyError MissingFilterPolicy = "Error"
+	// MissingFilterPolicyIgnore treats the client filter as not satisfied
+	MissingFilterPolicyIgnore MissingFilterPolicy = "Ignore"
+)
+
+// EvaluatorOptions contains optional parameters to configure an Evaluator.
+type EvaluatorOptions struct {
+	// Filters are the custom feature filters used in addition to the built-in filters.
+	// The name of each filter must be unique and must not conflict with a built-in filter.
+	Filters []FeatureFilter
+
+	// MissingFilterPolicy specifies how a client filter without a registered implementation is handled.
+	// If not provided, MissingFilterPolicyError will be used.
+	MissingFilterPolicy MissingFilterPolicy
+
+	// TelemetryPublisher receives an evaluation event for every evaluation of a feature flag with telemetry enabled.
+	// If not provided, no evaluation events are produced.
+	TelemetryPublisher TelemetryPublisher
+
+	// Logger receives the messages of the evaluator, such as failures to publish evaluation events.
+	// If not provided, slog.Default() will be used.
+	Logger *slog.Logger
+}
+
+// Evaluator evaluates feature flags with the built-in feature filters "Microsoft.Targeting",
+// "Microsoft.TimeWindow" and "Microsoft.Percentage", and any custom feature filters.
+//
+// An Evaluator is safe for concurrent use by multiple goroutines.
+type Evaluator struct {
+	filters             map[string]featureFilter
+	missingFilterPolicy MissingFilterPolicy
+	telemetryPublisher  TelemetryPublisher
+	logger              *slog.Logger
+}
+
+// NewEvaluator creates an Evaluator with the built-in feature filters and the custom filters in options registered.
+//
+// Parameters:
+//   - options: Optional parameters to register custom feature filters and configure the evaluation behavior
+//
+// Returns:
+//   - A new Evaluator
+//   - An error if a custom filter has an empty or duplicate name, or the missing filter policy is invalid
+func NewEvaluator(options *EvaluatorOptions) (*Evaluator, error) {
+	if options == nil {
+		options = &EvaluatorOptions{}
+	}
+
+	evaluator := &Evaluator{
+		filters:             make(map[string]featureFilter),
+		missingFilterPolicy: options.MissingFilterPolicy,
+		telemetryPublisher:  options.TelemetryPublisher,
+		logger:              options.Logger,
+	}
+
+	switch evaluator.missingFilterPolicy {
+	case "":
+		evaluator.missingFilterPolicy = MissingFilterPolicyError
+	case MissingFilterPolicyError, MissingFilterPolicyIgnore:
+	default:
+		return nil, fmt.Errorf("invalid missing filter policy '%s'", options.MissingFilterPolicy)
+	}
+
+	for _, filter := range []featureFilter{
+		&targetingFilter{},
+		&timeWindowFilter{},
+		&percentageFilter{},
+	} {
+		evaluator.filters[filter.name()] = filter
+	}
+
+	for _, filter := range options.Filters {
+		if filter == nil {
+			return nil, fmt.Errorf("feature filter cannot be nil")
+		}
+
+		name := filter.Name()
+		if name == "" {
+			return nil, fmt.Errorf("feature filter name cannot be empty")
+		}
+
+		if _, exists := evaluator.filters[name]; exists {
+			return nil, fmt.Errorf("feature filter '%s' is registered more than once or conflicts with a built-in filter", name)
+		}
+
+		evaluator.filters[name] = &customFilter{filter: filter}
+	}
+
+	return evaluator, nil
+}
+
+// IsEnabled determines whether the feature flag is enabled for the specified targeting context.
+//
+// A feature flag is enabled when its "enabled" property is true and its client filters are satisfied according
+// to the requirement type: any filter for "Any" (the default), every filter for "All".
+// The status override of the assigned variant, if any, takes precedence over the evaluated state.
+//
+// Parameters:
+//   - ctx: The context for the operation
+//   - featureFlag: The feature flag to evaluate
+//   - targetingContext: The user and groups the feature flag is evaluated for
+//
+// Returns:
+//   - true if the feature flag is enabled, false otherwise
+//   - An error if a client filter is unknown or has invalid parameters
+func (e *Evaluator) IsEnabled(ctx context.Context, featureFlag FeatureFlag, targetingContext TargetingContext) (bool, error) {
+	return e.IsEnabledWithAppContext(ctx, featureFlag, targetingContext)
+}
+
+// IsEnabledWithAppContext determines whether the feature flag is enabled for the specified application context.
+// The application context is passed to custom feature filters as is. The built-in filters and variant allocation
+// use the TargetingContext of the application context, if it is a TargetingContext or implements TargetingContextAccessor.
+//
+// Parameters:
+//   - ctx: The context for the operation
+//   - featureFlag: The feature flag to evaluate
+//   - appContext: The application context the feature flag is evaluated for
+//
+// Returns:
+//   - true if the feature flag is enabled, false otherwise
+//   - An error if a client filter is not registered or fails to evaluate
+func (e *Evaluator) IsEnabledWithAppContext(ctx context.Context, featureFlag FeatureFlag, appContext any) (bool, error) {
+	result, err := e.evaluate(ctx, featureFlag, appContext)
+	if err != nil {
+		return false, err
+	}
+
+	return result.enabled, nil
+}
+
+// GetVariant assigns a variant of the feature flag to the specified targeting context.
+//
+// When the feature flag is disabled, the "default_when_disabled" variant is assigned. Otherwise the variant is
+// allocated by user, then by group, then by percentile, falling back to the "default_when_enabled" variant.
+//
+// Parameters:
+//   - ctx: The context for the operation
+//   - featureFlag: The feature flag to evaluate
+//   - targetingContext: The user and groups the variant is assigned for
+//
+// Returns:
+//   - The assigned variant, or nil if no variant is assigned
+//   - An error if a client filter is unknown or has invalid parameters
+func (e *Evaluator) GetVariant(ctx context.Context, featureFlag FeatureFlag, targetingContext TargetingContext) (*Variant, error) {
+	return e.GetVariantWithAppContext(ctx, featureFlag, targetingContext)
+}
+
+// GetVariantWithAppContext assigns a variant of the feature flag to the specified application context.
+// See IsEnabledWithAppContext for how the application context is used.
+//
+// Parameters:
+//   - ctx: The context for the operation
+//   - featureFlag: The feature flag to evaluate
+//   - appContext: The application context the variant is assigned for
+//
+// Returns:
+//   - The assigned variant, or nil if no variant is assigned
+//   - An error if a client filter is not registered or fails to evaluate
+func (e *Evaluator) GetVariantWithAppContext(ctx context.Context, featureFlag FeatureFlag, appContext any) (*Variant, error) {
+	result, err := e.evaluate(ctx, featureFlag, appContext)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.variant, nil
+}
+
+// evaluationResult is the outcome of evaluating a feature flag
+type evaluationResult struct {
+	enabled bool
+	variant *Variant
+	reason  VariantAssignmentReason
+}
+
+// getLogger returns the logger of the evaluator, or the default logger of the slog package if no logger is configured
+func (e *Evaluator) getLogger() *slog.Logger {
+	if e.logger == nil {
+		return slog.Default()
+	}
+
+	return e.logger
+}
+
+func (e *Evaluator) evaluate(ctx context.Context, featureFlag FeatureFlag, appContext any) (evaluationResult, error) {
+	targetingContext := getTargetingContext(appContext)
+	result, err := e.evaluateFeature(ctx, featureFlag, appContext, targetingContext)
+	if err != nil {
+		return result, err
+	}
+
+	if e.telemetryPublisher != nil && featureFlag.Telemetry != nil && featureFlag.Telemetry.Enabled {
+		event := newEvaluationEvent(featureFlag, result, targetingContext)
+		if err := e.telemetryPublisher.Publish(ctx, event); err != nil {
+			e.getLogger().Warn("Failed to publish evaluation event", "feature_flag", featureFlag.ID, "error", err)
+		}
+	}
+
+	return result, nil
+}
+
+func (e *Evaluator) evaluateFeature(ctx context.Context, featureFlag FeatureFlag, appContext any, targetingContext TargetingContext) (evaluationResult, error) {
+	enabled, err := e.evaluateConditions(ctx, featureFlag, appContext, targetingContext)
+	if err != nil {
+		return evaluationResult{}, err
+	}
+
+	result := evaluationResult{
+		enabled: enabled,
+		reason:  VariantAssignmentReasonNone,
+	}
+
+	if len(featureFlag.Variants) == 0 {
+		return result, nil
+	}
+
+	variant, reason, err := assignVariant(featureFlag, enabled, targetingContext)
+	if err != nil {
+		return evaluationResult{}, err
+	}
+	result.reason = reason
+
+	if variant != nil {
+		result.variant = &Variant{
+			Name:               variant.Name,
+			ConfigurationValue: variant.ConfigurationValue,
+			Reason:             reason,
+		}
+
+		// The status override only applies to feature flags which are turned on
+		if featureFlag.Enabled {
+			switch variant.StatusOverride {
+			case StatusOverrideEnabled:
+				result.enabled = true
+			case StatusOverrideDisabled:
+				result.enabled = false
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func (e *Evaluator) evaluateConditions(ctx context.Context, featureFlag FeatureFlag, appContext any, targetingContext TargetingContext) (bool, error) {
+	if !featureFlag.Enabled {
+		return false, nil
+	}
+
+	if featureFlag.Conditions == nil || len(featureFlag.Conditions.ClientFilters) == 0 {
+		return true, nil
+	}
+
+	requirementType := featureFlag.Conditions.RequirementType
+	if requirementType == "" {
+		requirementType = RequirementTypeAny
+	}
+
+	for _, clientFilter := range featureFlag.Conditions.ClientFilters {
+		var passed bool
+		if filter, ok := e.filters[clientFilter.Name]; ok {
+			evaluation := featureFilterEvaluation{
+				featureName:      featureFlag.ID,
+				parameters:       clientFilter.Parameters,
+				appContext:       appContext,
+				targetingContext: targetingContext,
+			}
+
+			var err error
+			if passed, err = filter.evaluate(ctx, evaluation); err != nil {
+				return false, fmt.Errorf("failed to evaluate feature filter '%s' of feature flag '%s': %w", clientFilter.Name, featureFlag.ID, err)
+			}
+		} else if e.missingFilterPolicy == MissingFilterPolicyError {
+			return false, fmt.Errorf("%w: '%s' used by feature flag '%s'", ErrFeatureFilterNotRegistered, clientFilter.Name, featureFlag.ID)
+		}
+
+		// Short circuit as soon as the result is determined
+		if requirementType == RequirementTypeAll && !passed {
+			return false, nil
+		}
+		if requirementType == RequirementTypeAny && passed {
+			return true, nil
+		}
+	}
+
+	return requirementType == RequirementTypeAll, nil
+}
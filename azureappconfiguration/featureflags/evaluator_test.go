@@ -0,0 +1,248 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package featureflags
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestEvaluator(t *testing.T, options *EvaluatorOptions) *Evaluator {
+	evaluator, err := NewEvaluator(options)
+	assert.NoError(t, err)
+	return evaluator
+}
+
+// mockFeatureFilter is a custom feature filter which records the arguments it is evaluated with
+type mockFeatureFilter struct {
+	filterName string
+	result     bool
+	err        error
+	parameters map[string]any
+	appContext any
+}
+
+func (f *mockFeatureFilter) Name() string {
+	return f.filterName
+}
+
+func (f *mockFeatureFilter) Evaluate(ctx context.Context, parameters map[string]any, appContext any) (bool, error) {
+	f.parameters = parameters
+	f.appContext = appContext
+	return f.result, f.err
+}
+
+// tenantContext is an application context which carries a targeting context
+type tenantContext struct {
+	Tier   string
+	UserID string
+}
+
+func (c tenantContext) GetTargetingContext() TargetingContext {
+	return TargetingContext{UserID: c.UserID}
+}
+
+func TestEvaluator_IsEnabled(t *testing.T) {
+	alwaysOn := ClientFilter{Name: PercentageFilterName, Parameters: map[string]any{"Value": 100}}
+	alwaysOff := ClientFilter{Name: PercentageFilterName, Parameters: map[string]any{"Value": 0}}
+
+	tests := []struct {
+		name        string
+		featureFlag FeatureFlag
+		expected    bool
+	}{
+		{
+			name:        "disabled flag",
+			featureFlag: FeatureFlag{ID: "Beta", Enabled: false, Conditions: &Conditions{ClientFilters: []ClientFilter{alwaysOn}}},
+			expected:    false,
+		},
+		{
+			name:        "enabled flag without filters",
+			featureFlag: FeatureFlag{ID: "Beta", Enabled: true},
+			expected:    true,
+		},
+		{
+			name:        "any filter satisfied",
+			featureFlag: FeatureFlag{ID: "Beta", Enabled: true, Conditions: &Conditions{ClientFilters: []ClientFilter{alwaysOff, alwaysOn}}},
+			expected:    true,
+		},
+		{
+			name:        "no filter satisfied",
+			featureFlag: FeatureFlag{ID: "Beta", Enabled: true, Conditions: &Conditions{ClientFilters: []ClientFilter{alwaysOff, alwaysOff}}},
+			expected:    false,
+		},
+		{
+			name: "all filters satisfied",
+			featureFlag: FeatureFlag{ID: "Beta", Enabled: true, Conditions: &Conditions{
+				RequirementType: RequirementTypeAll,
+				ClientFilters:   []ClientFilter{alwaysOn, alwaysOn},
+			}},
+			expected: true,
+		},
+		{
+			name: "not all filters satisfied",
+			featureFlag: FeatureFlag{ID: "Beta", Enabled: true, Conditions: &Conditions{
+				RequirementType: RequirementTypeAll,
+				ClientFilters:   []ClientFilter{alwaysOn, alwaysOff},
+			}},
+			expected: false,
+		},
+	}
+
+	evaluator := newTestEvaluator(t, nil)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			enabled, err := evaluator.IsEnabled(context.Background(), test.featureFlag, TargetingContext{})
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, enabled)
+		})
+	}
+}
+
+func TestEvaluator_IsEnabled_Targeting(t *testing.T) {
+	featureFlag := FeatureFlag{
+		ID:      "TargetingTestFeature",
+		Enabled: true,
+		Conditions: &Conditions{
+			ClientFilters: []ClientFilter{{Name: TargetingFilterName, Parameters: targetingTestParameters()}},
+		},
+	}
+
+	evaluator := newTestEvaluator(t, nil)
+	enabled, err := evaluator.IsEnabled(context.Background(), featureFlag, TargetingContext{UserID: "Anne"})
+	assert.NoError(t, err)
+	assert.True(t, enabled)
+
+	enabled, err = evaluator.IsEnabled(context.Background(), featureFlag, TargetingContext{UserID: "Patty"})
+	assert.NoError(t, err)
+	assert.False(t, enabled)
+}
+
+func TestEvaluator_IsEnabled_UnknownFilter(t *testing.T) {
+	featureFlag := FeatureFlag{
+		ID:         "Beta",
+		Enabled:    true,
+		Conditions: &Conditions{ClientFilters: []ClientFilter{{Name: "Contoso.Region"}}},
+	}
+
+	_, err := newTestEvaluator(t, nil).IsEnabled(context.Background(), featureFlag, TargetingContext{})
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, ErrFeatureFilterNotRegistered))
+	assert.Contains(t, err.Error(), "'Contoso.Region'")
+}
+
+func TestEvaluator_MissingFilterPolicyIgnore(t *testing.T) {
+	evaluator := newTestEvaluator(t, &EvaluatorOptions{MissingFilterPolicy: MissingFilterPolicyIgnore})
+	missing := ClientFilter{Name: "Contoso.Region"}
+	alwaysOn := ClientFilter{Name: PercentageFilterName, Parameters: map[string]any{"Value": 100}}
+
+	// The missing filter is treated as not satisfied
+	enabled, err := evaluator.IsEnabled(context.Background(), FeatureFlag{ID: "Beta", Enabled: true, Conditions: &Conditions{
+		ClientFilters: []ClientFilter{missing, alwaysOn},
+	}}, TargetingContext{})
+	assert.NoError(t, err)
+	assert.True(t, enabled)
+
+	enabled, err = evaluator.IsEnabled(context.Background(), FeatureFlag{ID: "Beta", Enabled: true, Conditions: &Conditions{
+		RequirementType: RequirementTypeAll,
+		ClientFilters:   []ClientFilter{missing, alwaysOn},
+	}}, TargetingContext{})
+	assert.NoError(t, err)
+	assert.False(t, enabled)
+}
+
+func TestEvaluator_CustomFilter(t *testing.T) {
+	filter := &mockFeatureFilter{filterName: "Contoso.TenantTier", result: true}
+	evaluator := newTestEvaluator(t, &EvaluatorOptions{Filters: []FeatureFilter{filter}})
+
+	featureFlag := FeatureFlag{
+		ID:      "Beta",
+		Enabled: true,
+		Conditions: &Conditions{
+			RequirementType: RequirementTypeAll,
+			ClientFilters: []ClientFilter{
+				{Name: "Contoso.TenantTier", Parameters: map[string]any{"Tiers": []any{"Premium"}}},
+				{Name: TargetingFilterName, Parameters: map[string]any{"Audience": map[string]any{"Users": []any{"Jeff"}}}},
+			},
+		},
+	}
+
+	appContext := tenantContext{Tier: "Premium", UserID: "Jeff"}
+	enabled, err := evaluator.IsEnabledWithAppContext(context.Background(), featureFlag, appContext)
+	assert.NoError(t, err)
+	assert.True(t, enabled)
+	assert.Equal(t, appContext, filter.appContext)
+	assert.Equal(t, map[string]any{"Tiers": []any{"Premium"}}, filter.parameters)
+
+	// The targeting context is passed as the application context by IsEnabled
+	enabled, err = evaluator.IsEnabled(context.Background(), featureFlag, TargetingContext{UserID: "Anne"})
+	assert.NoError(t, err)
+	assert.False(t, enabled)
+	assert.Equal(t, TargetingContext{UserID: "Anne"}, filter.appContext)
+
+	filter.err = errors.New("tier service unavailable")
+	_, err = evaluator.IsEnabledWithAppContext(context.Background(), featureFlag, appContext)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "tier service unavailable")
+}
+
+func TestNewEvaluator_InvalidOptions(t *testing.T) {
+	tests := []struct {
+		name           string
+		options        *EvaluatorOptions
+		expectedErrMsg string
+	}{
+		{
+			name:           "nil filter",
+			options:        &EvaluatorOptions{Filters: []FeatureFilter{nil}},
+			expectedErrMsg: "cannot be nil",
+		},
+		{
+			name:           "empty filter name",
+			options:        &EvaluatorOptions{Filters: []FeatureFilter{&mockFeatureFilter{}}},
+			expectedErrMsg: "name cannot be empty",
+		},
+		{
+			name: "duplicate filter name",
+			options: &EvaluatorOptions{Filters: []FeatureFilter{
+				&mockFeatureFilter{filterName: "Contoso.Region"},
+				&mockFeatureFilter{filterName: "Contoso.Region"},
+			}},
+			expectedErrMsg: "'Contoso.Region' is registered more than once",
+		},
+		{
+			name:           "conflict with built-in filter",
+			options:        &EvaluatorOptions{Filters: []FeatureFilter{&mockFeatureFilter{filterName: TargetingFilterName}}},
+			expectedErrMsg: "conflicts with a built-in filter",
+		},
+		{
+			name:           "invalid missing filter policy",
+			options:        &EvaluatorOptions{MissingFilterPolicy: "Skip"},
+			expectedErrMsg: "invalid missing filter policy",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewEvaluator(test.options)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), test.expectedErrMsg)
+		})
+	}
+}
+
+func TestEvaluator_IsEnabled_InvalidFilterParameters(t *testing.T) {
+	featureFlag := FeatureFlag{
+		ID:         "Beta",
+		Enabled:    true,
+		Conditions: &Conditions{ClientFilters: []ClientFilter{{Name: PercentageFilterName, Parameters: map[string]any{"Value": "half"}}}},
+	}
+
+	_, err := newTestEvaluator(t, nil).IsEnabled(context.Background(), featureFlag, TargetingContext{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), PercentageFilterName)
+}
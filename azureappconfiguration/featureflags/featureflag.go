@@ -1,8 +1,13 @@
 // Copyright (c) Microsoft Corporation.
 // Licensed under the MIT License.
 
-package fm
+// Package featureflags defines the feature flag schema used by Azure App Configuration.
+//
+// The types in this package follow the v2.0.0 feature management schema, see:
+// https://github.com/microsoft/FeatureManagement/blob/main/Schema/FeatureManagement.v2.0.0.schema.json
+package featureflags
 
+// FeatureManagement represents the "feature_management" section of the configuration
 type FeatureManagement struct {
 	FeatureFlags []FeatureFlag `json:"feature_flags"`
 }
@@ -123,6 +128,7 @@ const (
 	VariantAssignmentReasonPercentile VariantAssignmentReason = "Percentile"
 )
 
+// RequirementType determines whether any or all client filters must be satisfied
 type RequirementType string
 
 const (
@@ -132,6 +138,7 @@ const (
 	RequirementTypeAll RequirementType = "All"
 )
 
+// StatusOverride overrides the enabled state of a feature when a variant is assigned
 type StatusOverride string
 
 const (
@@ -0,0 +1,175 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package featureflags
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeatureFlag_Unmarshal(t *testing.T) {
+	value := `{
+		"id": "Beta",
+		"enabled": true,
+		"conditions": {
+			"requirement_type": "All",
+			"client_filters": [{"name": "Microsoft.Targeting", "parameters": {"Audience": {"Users": ["alice"]}}}]
+		},
+		"variants": [{"name": "Big", "configuration_value": {"size": 10}, "status_override": "Disabled"}],
+		"allocation": {"default_when_enabled": "Big", "seed": "s1", "percentile": [{"variant": "Big", "from": 0, "to": 100}]},
+		"telemetry": {"enabled": true, "metadata": {"owner": "team"}}
+	}`
+
+	var featureFlag FeatureFlag
+	assert.NoError(t, json.Unmarshal([]byte(value), &featureFlag))
+	assert.NoError(t, featureFlag.Validate())
+	assert.Equal(t, "Beta", featureFlag.ID)
+	assert.Equal(t, RequirementTypeAll, featureFlag.Conditions.RequirementType)
+	assert.Equal(t, "Microsoft.Targeting", featureFlag.Conditions.ClientFilters[0].Name)
+	assert.Equal(t, StatusOverrideDisabled, featureFlag.Variants[0].StatusOverride)
+	assert.Equal(t, map[string]any{"size": float64(10)}, featureFlag.Variants[0].ConfigurationValue)
+	assert.Equal(t, "s1", featureFlag.Allocation.Seed)
+	assert.Equal(t, 100.0, featureFlag.Allocation.Percentile[0].To)
+	assert.Equal(t, "team", featureFlag.Telemetry.Metadata["owner"])
+}
+
+func TestFeatureFlag_Validate(t *testing.T) {
+	tests := []struct {
+		name           string
+		featureFlag    FeatureFlag
+		expectedErrMsg string
+	}{
+		{
+			name:        "minimal flag",
+			featureFlag: FeatureFlag{ID: "Beta"},
+		},
+		{
+			name:           "empty id",
+			featureFlag:    FeatureFlag{},
+			expectedErrMsg: "id cannot be empty",
+		},
+		{
+			name:           "invalid requirement type",
+			featureFlag:    FeatureFlag{ID: "Beta", Conditions: &Conditions{RequirementType: "Some"}},
+			expectedErrMsg: "invalid requirement type",
+		},
+		{
+			name:           "client filter without name",
+			featureFlag:    FeatureFlag{ID: "Beta", Conditions: &Conditions{ClientFilters: []ClientFilter{{}}}},
+			expectedErrMsg: "client filter without name",
+		},
+		{
+			name:           "variant without name",
+			featureFlag:    FeatureFlag{ID: "Beta", Variants: []VariantDefinition{{}}},
+			expectedErrMsg: "variant without name",
+		},
+		{
+			name:           "duplicate variants",
+			featureFlag:    FeatureFlag{ID: "Beta", Variants: []VariantDefinition{{Name: "A"}, {Name: "A"}}},
+			expectedErrMsg: "duplicate variant 'A'",
+		},
+		{
+			name:           "invalid status override",
+			featureFlag:    FeatureFlag{ID: "Beta", Variants: []VariantDefinition{{Name: "A", StatusOverride: "On"}}},
+			expectedErrMsg: "invalid status override",
+		},
+		{
+			name: "allocation references undefined variant",
+			featureFlag: FeatureFlag{ID: "Beta", Variants: []VariantDefinition{{Name: "A"}},
+				Allocation: &VariantAllocation{DefaultWhenEnabled: "A", Group: []GroupAllocation{{Variant: "B", Groups: []string{"Ring0"}}}}},
+			expectedErrMsg: "group allocation references undefined variant 'B'",
+		},
+		{
+			name: "percentile exceeds 100",
+			featureFlag: FeatureFlag{ID: "Beta", Variants: []VariantDefinition{{Name: "A"}},
+				Allocation: &VariantAllocation{Percentile: []PercentileAllocation{{Variant: "A", From: 50, To: 150}}}},
+			expectedErrMsg: "invalid range [50, 150]",
+		},
+		{
+			name: "overlapping percentiles",
+			featureFlag: FeatureFlag{ID: "Beta", Variants: []VariantDefinition{{Name: "A"}, {Name: "B"}},
+				Allocation: &VariantAllocation{Percentile: []PercentileAllocation{{Variant: "B", From: 40, To: 100}, {Variant: "A", From: 0, To: 50}}}},
+			expectedErrMsg: "'A' [0, 50] and 'B' [40, 100] overlap",
+		},
+		{
+			name: "adjacent percentiles",
+			featureFlag: FeatureFlag{ID: "Beta", Variants: []VariantDefinition{{Name: "A"}, {Name: "B"}},
+				Allocation: &VariantAllocation{Percentile: []PercentileAllocation{{Variant: "A", From: 0, To: 50}, {Variant: "B", From: 50, To: 100}}}},
+		},
+		{
+			name: "malformed time window",
+			featureFlag: FeatureFlag{ID: "Beta", Conditions: &Conditions{ClientFilters: []ClientFilter{
+				{Name: TimeWindowFilterName, Parameters: map[string]any{"Start": "yesterday"}}}}},
+			expectedErrMsg: "invalid 'Start' parameter",
+		},
+		{
+			name: "time window ends before it starts",
+			featureFlag: FeatureFlag{ID: "Beta", Conditions: &Conditions{ClientFilters: []ClientFilter{
+				{Name: TimeWindowFilterName, Parameters: map[string]any{"Start": "Tue, 02 Jan 2024 00:00:00 GMT", "End": "Mon, 01 Jan 2024 00:00:00 GMT"}}}}},
+			expectedErrMsg: "cannot be earlier than",
+		},
+		{
+			name: "invalid time window recurrence",
+			featureFlag: FeatureFlag{ID: "Beta", Conditions: &Conditions{ClientFilters: []ClientFilter{
+				{Name: TimeWindowFilterName, Parameters: map[string]any{"Start": "2024-01-01T00:00:00Z", "End": "2024-01-01T01:00:00Z",
+					"Recurrence": map[string]any{"Pattern": map[string]any{"Type": "Monthly"}}}}}}},
+			expectedErrMsg: "invalid 'Recurrence.Pattern.Type' parameter 'Monthly'",
+		},
+		{
+			name: "invalid targeting rollout",
+			featureFlag: FeatureFlag{ID: "Beta", Conditions: &Conditions{ClientFilters: []ClientFilter{
+				{Name: TargetingFilterName, Parameters: map[string]any{"Audience": map[string]any{"DefaultRolloutPercentage": 120}}}}}},
+			expectedErrMsg: "DefaultRolloutPercentage",
+		},
+		{
+			name: "invalid percentage filter",
+			featureFlag: FeatureFlag{ID: "Beta", Conditions: &Conditions{ClientFilters: []ClientFilter{
+				{Name: PercentageFilterName, Parameters: map[string]any{"Value": -1}}}}},
+			expectedErrMsg: "between 0 and 100",
+		},
+		{
+			name: "custom filter parameters are not validated",
+			featureFlag: FeatureFlag{ID: "Beta", Conditions: &Conditions{ClientFilters: []ClientFilter{
+				{Name: "Contoso.Region", Parameters: map[string]any{"Start": "yesterday"}}}}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.featureFlag.Validate()
+			if err != nil {
+				var validationErr *ValidationError
+				assert.ErrorAs(t, err, &validationErr)
+			}
+			if test.expectedErrMsg != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.expectedErrMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestFeatureFlag_Validate_AllViolations(t *testing.T) {
+	featureFlag := FeatureFlag{
+		ID:         "Beta",
+		Conditions: &Conditions{RequirementType: "Some"},
+		Variants:   []VariantDefinition{{Name: "A"}, {Name: "A"}},
+		Allocation: &VariantAllocation{DefaultWhenDisabled: "Off"},
+	}
+
+	err := featureFlag.Validate()
+	var validationErr *ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "Beta", validationErr.FeatureID)
+	assert.Equal(t, []string{
+		"invalid requirement type 'Some'",
+		"duplicate variant 'A'",
+		"allocation 'default_when_disabled' references undefined variant 'Off'",
+	}, validationErr.Violations)
+	assert.Contains(t, err.Error(), "feature flag 'Beta' is invalid")
+}
@@ -0,0 +1,102 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package featureflags
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+)
+
+// Built-in feature filter names
+const (
+	TargetingFilterName  = "Microsoft.Targeting"
+	TimeWindowFilterName = "Microsoft.TimeWindow"
+	PercentageFilterName = "Microsoft.Percentage"
+)
+
+// FeatureFilter is implemented by custom feature filters, which decide whether a client filter
+// of a feature flag is satisfied. Register custom filters with EvaluatorOptions.Filters.
+type FeatureFilter interface {
+	// Name returns the name of the client filter handled by the filter, e.g. "Contoso.Region".
+	Name() string
+
+	// Evaluate determines whether the client filter is satisfied.
+	//
+	// Parameters:
+	//   - ctx: The context for the operation
+	//   - parameters: The parameters of the client filter as defined in the feature flag
+	//   - appContext: The application context passed to the evaluation, or the TargetingContext if none was passed
+	//
+	// Returns:
+	//   - true if the client filter is satisfied, false otherwise
+	//   - An error if the client filter cannot be evaluated, e.g. the parameters are invalid
+	Evaluate(ctx context.Context, parameters map[string]any, appContext any) (bool, error)
+}
+
+// featureFilterEvaluation contains the information needed by a filter to evaluate a client filter
+type featureFilterEvaluation struct {
+	featureName      string
+	parameters       map[string]any
+	appContext       any
+	targetingContext TargetingContext
+}
+
+// featureFilter is implemented by the built-in feature filters and the adapter of custom feature filters
+type featureFilter interface {
+	name() string
+	evaluate(ctx context.Context, evaluation featureFilterEvaluation) (bool, error)
+}
+
+// customFilter adapts a FeatureFilter to the featureFilter interface
+type customFilter struct {
+	filter FeatureFilter
+}
+
+func (f *customFilter) name() string {
+	return f.filter.Name()
+}
+
+func (f *customFilter) evaluate(ctx context.Context, evaluation featureFilterEvaluation) (bool, error) {
+	return f.filter.Evaluate(ctx, evaluation.parameters, evaluation.appContext)
+}
+
+// percentageFilter enables a feature for a random percentage of evaluations
+type percentageFilter struct{}
+
+type percentageFilterParameters struct {
+	Value float64 `json:"Value"`
+}
+
+func (f *percentageFilter) name() string {
+	return PercentageFilterName
+}
+
+func (f *percentageFilter) evaluate(ctx context.Context, evaluation featureFilterEvaluation) (bool, error) {
+	var params percentageFilterParameters
+	if err := decodeParameters(evaluation.parameters, &params); err != nil {
+		return false, err
+	}
+
+	if params.Value < 0 {
+		return false, fmt.Errorf("the 'Value' parameter cannot be negative")
+	}
+
+	return rand.Float64()*100 < params.Value, nil
+}
+
+// decodeParameters decodes the loosely typed filter parameters into a typed parameter struct
+func decodeParameters(parameters map[string]any, v any) error {
+	data, err := json.Marshal(parameters)
+	if err != nil {
+		return fmt.Errorf("invalid filter parameters: %w", err)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid filter parameters: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,365 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package featureflags
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Recurrence pattern and range types of the time window filter
+const (
+	recurrencePatternDaily  = "Daily"
+	recurrencePatternWeekly = "Weekly"
+
+	recurrenceRangeNoEnd    = "NoEnd"
+	recurrenceRangeEndDate  = "EndDate"
+	recurrenceRangeNumbered = "Numbered"
+)
+
+const (
+	daysPerWeek = 7
+	oneDay      = 24 * time.Hour
+	// maxTimeWindowDuration is the maximum duration of a recurring time window
+	maxTimeWindowDuration = 10 * 365 * oneDay
+)
+
+// recurrence is the "Recurrence" parameter of the time window filter
+type recurrence struct {
+	Pattern recurrencePattern `json:"Pattern"`
+	Range   recurrenceRange   `json:"Range"`
+}
+
+type recurrencePattern struct {
+	Type           string   `json:"Type"`
+	Interval       *int     `json:"Interval"`
+	DaysOfWeek     []string `json:"DaysOfWeek"`
+	FirstDayOfWeek string   `json:"FirstDayOfWeek"`
+}
+
+type recurrenceRange struct {
+	Type                string `json:"Type"`
+	EndDate             string `json:"EndDate"`
+	NumberOfOccurrences *int   `json:"NumberOfOccurrences"`
+}
+
+// recurrenceSpec is the validated recurrence of a time window. All the days are calculated
+// in the time zone of the start of the first time window.
+type recurrenceSpec struct {
+	start               time.Time
+	duration            time.Duration
+	patternType         string
+	interval            int
+	daysOfWeek          []time.Weekday // sorted by their offset from firstDayOfWeek
+	firstDayOfWeek      time.Weekday
+	rangeType           string
+	endDate             time.Time
+	numberOfOccurrences int
+}
+
+// recurrenceState is the most recent occurrence of a recurring time window
+type recurrenceState struct {
+	previousOccurrence  time.Time
+	numberOfOccurrences int // number of occurrences up to and including previousOccurrence
+}
+
+// newRecurrenceSpec validates the recurrence of the time window [start, end) with the same rules
+// as the other Microsoft feature management libraries
+func newRecurrenceSpec(start time.Time, end time.Time, r *recurrence) (*recurrenceSpec, error) {
+	duration := end.Sub(start)
+	if duration <= 0 {
+		return nil, fmt.Errorf("the 'End' parameter must be later than the 'Start' parameter of a recurring time window")
+	}
+	if duration > maxTimeWindowDuration {
+		return nil, fmt.Errorf("the duration of a recurring time window cannot exceed 10 years")
+	}
+
+	spec := &recurrenceSpec{
+		start:          start,
+		duration:       duration,
+		interval:       1,
+		firstDayOfWeek: time.Sunday,
+		rangeType:      recurrenceRangeNoEnd,
+	}
+
+	if err := spec.setPattern(r.Pattern); err != nil {
+		return nil, err
+	}
+
+	if err := spec.setRange(r.Range); err != nil {
+		return nil, err
+	}
+
+	return spec, nil
+}
+
+func (s *recurrenceSpec) setPattern(pattern recurrencePattern) error {
+	if pattern.Interval != nil {
+		if *pattern.Interval <= 0 {
+			return fmt.Errorf("the 'Recurrence.Pattern.Interval' parameter must be greater than 0")
+		}
+		s.interval = *pattern.Interval
+	}
+
+	switch {
+	case strings.EqualFold(pattern.Type, recurrencePatternDaily):
+		s.patternType = recurrencePatternDaily
+		if s.duration > time.Duration(s.interval)*oneDay {
+			return fmt.Errorf("the time window duration cannot exceed the recurrence interval of %d day(s)", s.interval)
+		}
+	case strings.EqualFold(pattern.Type, recurrencePatternWeekly):
+		s.patternType = recurrencePatternWeekly
+		if pattern.FirstDayOfWeek != "" {
+			day, err := parseDayOfWeek(pattern.FirstDayOfWeek)
+			if err != nil {
+				return fmt.Errorf("invalid 'Recurrence.Pattern.FirstDayOfWeek' parameter: %w", err)
+			}
+			s.firstDayOfWeek = day
+		}
+
+		if len(pattern.DaysOfWeek) == 0 {
+			return fmt.Errorf("the 'Recurrence.Pattern.DaysOfWeek' parameter is required for a weekly recurrence")
+		}
+		for _, name := range pattern.DaysOfWeek {
+			day, err := parseDayOfWeek(name)
+			if err != nil {
+				return fmt.Errorf("invalid 'Recurrence.Pattern.DaysOfWeek' parameter: %w", err)
+			}
+			if !slices.Contains(s.daysOfWeek, day) {
+				s.daysOfWeek = append(s.daysOfWeek, day)
+			}
+		}
+		slices.SortFunc(s.daysOfWeek, func(a, b time.Weekday) int {
+			return weeklyDayOffset(a, s.firstDayOfWeek) - weeklyDayOffset(b, s.firstDayOfWeek)
+		})
+
+		if s.duration > time.Duration(s.interval*daysPerWeek)*oneDay || !s.isDurationCompliantWithDaysOfWeek() {
+			return fmt.Errorf("the time window duration cannot exceed the gap between two consecutive occurrences of the weekly recurrence")
+		}
+
+		if !slices.Contains(s.daysOfWeek, s.start.Weekday()) {
+			return fmt.Errorf("the 'Start' parameter must be on one of the days of the weekly recurrence")
+		}
+	case pattern.Type == "":
+		return fmt.Errorf("the 'Recurrence.Pattern.Type' parameter is required")
+	default:
+		return fmt.Errorf("invalid 'Recurrence.Pattern.Type' parameter '%s'", pattern.Type)
+	}
+
+	return nil
+}
+
+func (s *recurrenceSpec) setRange(recurrenceRange recurrenceRange) error {
+	switch {
+	case recurrenceRange.Type == "" || strings.EqualFold(recurrenceRange.Type, recurrenceRangeNoEnd):
+		s.rangeType = recurrenceRangeNoEnd
+	case strings.EqualFold(recurrenceRange.Type, recurrenceRangeEndDate):
+		s.rangeType = recurrenceRangeEndDate
+		if recurrenceRange.EndDate == "" {
+			return fmt.Errorf("the 'Recurrence.Range.EndDate' parameter is required for an end date range")
+		}
+		endDate, err := parseTimeWindowTime(recurrenceRange.EndDate)
+		if err != nil {
+			return fmt.Errorf("invalid 'Recurrence.Range.EndDate' parameter: %w", err)
+		}
+		if endDate.Before(s.start) {
+			return fmt.Errorf("the 'Recurrence.Range.EndDate' parameter cannot be earlier than the 'Start' parameter")
+		}
+		s.endDate = endDate
+	case strings.EqualFold(recurrenceRange.Type, recurrenceRangeNumbered):
+		s.rangeType = recurrenceRangeNumbered
+		if recurrenceRange.NumberOfOccurrences == nil || *recurrenceRange.NumberOfOccurrences <= 0 {
+			return fmt.Errorf("the 'Recurrence.Range.NumberOfOccurrences' parameter must be greater than 0 for a numbered range")
+		}
+		s.numberOfOccurrences = *recurrenceRange.NumberOfOccurrences
+	default:
+		return fmt.Errorf("invalid 'Recurrence.Range.Type' parameter '%s'", recurrenceRange.Type)
+	}
+
+	return nil
+}
+
+// isDurationCompliantWithDaysOfWeek checks that the time window duration does not exceed the
+// minimum gap between two consecutive days of the weekly recurrence
+func (s *recurrenceSpec) isDurationCompliantWithDaysOfWeek() bool {
+	if len(s.daysOfWeek) == 1 {
+		return true
+	}
+
+	minGap := daysPerWeek * oneDay
+	previous := s.daysOfWeek[0]
+	for _, day := range s.daysOfWeek[1:] {
+		minGap = min(minGap, time.Duration(weeklyDayOffset(day, previous))*oneDay)
+		previous = day
+	}
+
+	// The gap may cross weeks if the recurrence occurs every week
+	if s.interval == 1 {
+		minGap = min(minGap, time.Duration(weeklyDayOffset(s.daysOfWeek[0], previous))*oneDay)
+	}
+
+	return minGap >= s.duration
+}
+
+// isMatch checks if the time is within an occurrence of the recurring time window
+func (s *recurrenceSpec) isMatch(t time.Time) bool {
+	state, ok := s.findPreviousRecurrence(t)
+	return ok && t.Before(state.previousOccurrence.Add(s.duration))
+}
+
+// findPreviousRecurrence finds the most recent occurrence which started at or before the time,
+// or returns false if there is none within the recurrence range
+func (s *recurrenceSpec) findPreviousRecurrence(t time.Time) (recurrenceState, bool) {
+	if t.Before(s.start) {
+		return recurrenceState{}, false
+	}
+
+	var state recurrenceState
+	if s.patternType == recurrencePatternDaily {
+		state = s.findPreviousDailyRecurrence(t)
+	} else {
+		state = s.findPreviousWeeklyRecurrence(t)
+	}
+
+	if !s.isInRange(state) {
+		return recurrenceState{}, false
+	}
+
+	return state, true
+}
+
+func (s *recurrenceSpec) findPreviousDailyRecurrence(t time.Time) recurrenceState {
+	intervalDuration := time.Duration(s.interval) * oneDay
+	numberOfIntervals := int(t.Sub(s.start) / intervalDuration)
+	return recurrenceState{
+		previousOccurrence:  s.start.Add(time.Duration(numberOfIntervals) * intervalDuration),
+		numberOfOccurrences: numberOfIntervals + 1,
+	}
+}
+
+// findPreviousWeeklyRecurrence finds the first day of the most recent occurring week, then the most recent
+// day of the week which the time is not before, or the last day of the previous occurring week
+func (s *recurrenceSpec) findPreviousWeeklyRecurrence(t time.Time) recurrenceState {
+	start := s.start
+	t = t.In(start.Location())
+
+	firstDayOfFirstWeek := addDays(start, -weeklyDayOffset(start.Weekday(), s.firstDayOfWeek))
+	daysInInterval := s.interval * daysPerWeek
+	numberOfIntervals := int(t.Sub(firstDayOfFirstWeek) / (time.Duration(daysInInterval) * oneDay))
+	firstDayOfMostRecentWeek := addDays(firstDayOfFirstWeek, numberOfIntervals*daysInInterval)
+
+	// The days of the first week before the start are not occurrences
+	numberOfOccurrences := numberOfIntervals*len(s.daysOfWeek) - slices.Index(s.daysOfWeek, start.Weekday())
+	lastDayOffset := weeklyDayOffset(s.daysOfWeek[len(s.daysOfWeek)-1], s.firstDayOfWeek)
+
+	// The time is after the first week of the most recent interval
+	if t.Sub(firstDayOfMostRecentWeek) > daysPerWeek*oneDay {
+		return recurrenceState{
+			previousOccurrence:  addDays(firstDayOfMostRecentWeek, lastDayOffset),
+			numberOfOccurrences: numberOfOccurrences + len(s.daysOfWeek),
+		}
+	}
+
+	dayWithMinOffset := addDays(firstDayOfMostRecentWeek, weeklyDayOffset(s.daysOfWeek[0], s.firstDayOfWeek))
+	if dayWithMinOffset.Before(start) {
+		numberOfOccurrences = 0
+		dayWithMinOffset = start
+	}
+
+	if t.Before(dayWithMinOffset) {
+		// The most recent occurrence is the last day of the previous occurring week
+		return recurrenceState{
+			previousOccurrence:  addDays(firstDayOfMostRecentWeek, lastDayOffset-daysInInterval),
+			numberOfOccurrences: numberOfOccurrences,
+		}
+	}
+
+	previousOccurrence := dayWithMinOffset
+	numberOfOccurrences++
+	nextDay := addDays(dayWithMinOffset, 1)
+	for i := weeklyDayOffset(dayWithMinOffset.Weekday(), s.firstDayOfWeek) + 1; i < daysPerWeek; i++ {
+		if nextDay.After(t) {
+			break
+		}
+		if slices.Contains(s.daysOfWeek, nextDay.Weekday()) {
+			previousOccurrence = nextDay
+			numberOfOccurrences++
+		}
+		nextDay = addDays(nextDay, 1)
+	}
+
+	return recurrenceState{
+		previousOccurrence:  previousOccurrence,
+		numberOfOccurrences: numberOfOccurrences,
+	}
+}
+
+// findNextOccurrence finds the first occurrence which starts after the time,
+// or returns false if there is none within the recurrence range
+func (s *recurrenceSpec) findNextOccurrence(t time.Time) (time.Time, bool) {
+	if t.Before(s.start) {
+		return s.start, true
+	}
+
+	state, ok := s.findPreviousRecurrence(t)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	next := recurrenceState{numberOfOccurrences: state.numberOfOccurrences + 1}
+	if s.patternType == recurrencePatternDaily {
+		next.previousOccurrence = addDays(state.previousOccurrence, s.interval)
+	} else {
+		previous := state.previousOccurrence.In(s.start.Location())
+		previousOffset := weeklyDayOffset(previous.Weekday(), s.firstDayOfWeek)
+		firstDayOfWeek := addDays(previous, -previousOffset)
+		next.previousOccurrence = addDays(firstDayOfWeek, s.interval*daysPerWeek+weeklyDayOffset(s.daysOfWeek[0], s.firstDayOfWeek))
+		for _, day := range s.daysOfWeek {
+			if offset := weeklyDayOffset(day, s.firstDayOfWeek); offset > previousOffset {
+				next.previousOccurrence = addDays(firstDayOfWeek, offset)
+				break
+			}
+		}
+	}
+
+	if !s.isInRange(next) {
+		return time.Time{}, false
+	}
+
+	return next.previousOccurrence, true
+}
+
+// isInRange checks if the occurrence is within the recurrence range
+func (s *recurrenceSpec) isInRange(state recurrenceState) bool {
+	switch s.rangeType {
+	case recurrenceRangeEndDate:
+		return !state.previousOccurrence.After(s.endDate)
+	case recurrenceRangeNumbered:
+		return state.numberOfOccurrences <= s.numberOfOccurrences
+	}
+
+	return true
+}
+
+// weeklyDayOffset returns the number of days from day2 to the next day1, from 0 to 6
+func weeklyDayOffset(day1 time.Weekday, day2 time.Weekday) int {
+	return (int(day1) - int(day2) + daysPerWeek) % daysPerWeek
+}
+
+// addDays adds whole days of 24 hours, so that the time of day is kept in the fixed offset time zone of the start
+func addDays(t time.Time, days int) time.Time {
+	return t.Add(time.Duration(days) * oneDay)
+}
+
+func parseDayOfWeek(name string) (time.Weekday, error) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(name, day.String()) {
+			return day, nil
+		}
+	}
+
+	return time.Sunday, fmt.Errorf("'%s' is not a valid day of week", name)
+}
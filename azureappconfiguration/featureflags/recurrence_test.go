@@ -0,0 +1,285 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package featureflags
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mustParseTime(t *testing.T, value string) time.Time {
+	parsed, err := time.Parse(time.RFC3339, value)
+	assert.NoError(t, err)
+	return parsed
+}
+
+func TestTimeWindowFilter_Recurrence(t *testing.T) {
+	tests := []struct {
+		name       string
+		parameters map[string]any
+		now        string
+		expected   bool
+	}{
+		{
+			name: "daily recurrence every two days",
+			parameters: map[string]any{"Start": "2023-09-01T00:00:00+08:00", "End": "2023-09-01T00:00:01+08:00",
+				"Recurrence": map[string]any{"Pattern": map[string]any{"Type": "Daily", "Interval": 2}}},
+			now:      "2023-09-02T16:00:00Z",
+			expected: true,
+		},
+		{
+			name: "daily recurrence after the window",
+			parameters: map[string]any{"Start": "2023-09-01T00:00:00+08:00", "End": "2023-09-01T00:00:01+08:00",
+				"Recurrence": map[string]any{"Pattern": map[string]any{"Type": "Daily", "Interval": 2}}},
+			now:      "2023-09-02T16:00:01Z",
+			expected: false,
+		},
+		{
+			name: "daily recurrence on a skipped day",
+			parameters: map[string]any{"Start": "2023-09-01T00:00:00+08:00", "End": "2023-09-01T00:00:01+08:00",
+				"Recurrence": map[string]any{"Pattern": map[string]any{"Type": "Daily", "Interval": 2}}},
+			now:      "2023-09-01T16:00:00Z",
+			expected: false,
+		},
+		{
+			name: "numbered range includes the last occurrence",
+			parameters: map[string]any{"Start": "2023-09-01T00:00:00+08:00", "End": "2023-09-01T00:00:01+08:00",
+				"Recurrence": map[string]any{"Pattern": map[string]any{"Type": "Daily"}, "Range": map[string]any{"Type": "Numbered", "NumberOfOccurrences": 2}}},
+			now:      "2023-09-01T16:00:00Z",
+			expected: true,
+		},
+		{
+			name: "numbered range excludes later occurrences",
+			parameters: map[string]any{"Start": "2023-09-01T00:00:00+08:00", "End": "2023-09-01T00:00:01+08:00",
+				"Recurrence": map[string]any{"Pattern": map[string]any{"Type": "Daily"}, "Range": map[string]any{"Type": "Numbered", "NumberOfOccurrences": 2}}},
+			now:      "2023-09-02T16:00:00Z",
+			expected: false,
+		},
+		{
+			name: "end date range includes the occurrence on the end date",
+			parameters: map[string]any{"Start": "2023-09-01T00:00:00+08:00", "End": "2023-09-01T00:00:01+08:00",
+				"Recurrence": map[string]any{"Pattern": map[string]any{"Type": "Daily"}, "Range": map[string]any{"Type": "EndDate", "EndDate": "2023-09-03T00:00:00+08:00"}}},
+			now:      "2023-09-02T16:00:00Z",
+			expected: true,
+		},
+		{
+			name: "end date range excludes later occurrences",
+			parameters: map[string]any{"Start": "2023-09-01T00:00:00+08:00", "End": "2023-09-01T00:00:01+08:00",
+				"Recurrence": map[string]any{"Pattern": map[string]any{"Type": "Daily"}, "Range": map[string]any{"Type": "EndDate", "EndDate": "2023-09-03T00:00:00+08:00"}}},
+			now:      "2023-09-03T16:00:00Z",
+			expected: false,
+		},
+		{
+			name: "weekly recurrence",
+			parameters: map[string]any{"Start": "2023-09-01T00:00:00+08:00", "End": "2023-09-01T00:00:01+08:00",
+				"Recurrence": map[string]any{"Pattern": map[string]any{"Type": "Weekly", "DaysOfWeek": []any{"Monday", "Friday"}}}},
+			now:      "2023-09-04T00:00:00+08:00",
+			expected: true,
+		},
+		{
+			name: "weekly recurrence skips weeks",
+			parameters: map[string]any{"Start": "2023-09-01T00:00:00+08:00", "End": "2023-09-01T00:00:01+08:00",
+				"Recurrence": map[string]any{"Pattern": map[string]any{"Type": "Weekly", "Interval": 2, "DaysOfWeek": []any{"Monday", "Friday"}}}},
+			now:      "2023-09-04T00:00:00+08:00",
+			expected: false,
+		},
+		{
+			name: "weekly recurrence in the next occurring week",
+			parameters: map[string]any{"Start": "2023-09-01T00:00:00+08:00", "End": "2023-09-01T00:00:01+08:00",
+				"Recurrence": map[string]any{"Pattern": map[string]any{"Type": "Weekly", "Interval": 2, "DaysOfWeek": []any{"Monday", "Friday"}}}},
+			now:      "2023-09-11T00:00:00+08:00",
+			expected: true,
+		},
+		{
+			name: "first day of week monday keeps sunday in the first week",
+			parameters: map[string]any{"Start": "2023-09-01T00:00:00+08:00", "End": "2023-09-01T00:00:01+08:00",
+				"Recurrence": map[string]any{"Pattern": map[string]any{"Type": "Weekly", "Interval": 2, "DaysOfWeek": []any{"Friday", "Sunday"}, "FirstDayOfWeek": "Monday"}}},
+			now:      "2023-09-03T00:00:00+08:00",
+			expected: true,
+		},
+		{
+			name: "first day of week sunday starts a new week on sunday",
+			parameters: map[string]any{"Start": "2023-09-01T00:00:00+08:00", "End": "2023-09-01T00:00:01+08:00",
+				"Recurrence": map[string]any{"Pattern": map[string]any{"Type": "Weekly", "Interval": 2, "DaysOfWeek": []any{"Friday", "Sunday"}, "FirstDayOfWeek": "Sunday"}}},
+			now:      "2023-09-03T00:00:00+08:00",
+			expected: false,
+		},
+		{
+			name: "days of week are calculated in the time zone of start",
+			parameters: map[string]any{"Start": "2023-09-01T00:00:00+08:00", "End": "2023-09-01T00:00:01+08:00",
+				"Recurrence": map[string]any{"Pattern": map[string]any{"Type": "Weekly", "DaysOfWeek": []any{"Friday"}}}},
+			now:      "2023-09-07T16:00:00Z",
+			expected: true,
+		},
+		{
+			name: "numbered weekly range starting in the middle of the week",
+			parameters: map[string]any{"Start": "2024-01-01T07:00:00+08:00", "End": "2024-01-01T08:00:00+08:00",
+				"Recurrence": map[string]any{
+					"Pattern": map[string]any{"Type": "Weekly", "DaysOfWeek": []any{"Monday", "Friday", "Saturday"}, "FirstDayOfWeek": "Tuesday"},
+					"Range":   map[string]any{"Type": "Numbered", "NumberOfOccurrences": 6},
+				}},
+			now:      "2024-01-13T07:30:00+08:00",
+			expected: true,
+		},
+		{
+			name: "business hours",
+			parameters: map[string]any{"Start": "Mon, 06 May 2024 09:00:00 GMT", "End": "Mon, 06 May 2024 17:00:00 GMT",
+				"Recurrence": map[string]any{"Pattern": map[string]any{"Type": "Weekly", "DaysOfWeek": []any{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday"}}}},
+			now:      "2024-05-16T16:59:59Z",
+			expected: true,
+		},
+		{
+			name: "business hours on weekend",
+			parameters: map[string]any{"Start": "Mon, 06 May 2024 09:00:00 GMT", "End": "Mon, 06 May 2024 17:00:00 GMT",
+				"Recurrence": map[string]any{"Pattern": map[string]any{"Type": "Weekly", "DaysOfWeek": []any{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday"}}}},
+			now:      "2024-05-18T12:00:00Z",
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			now := mustParseTime(t, test.now)
+			filter := &timeWindowFilter{now: func() time.Time { return now }}
+			enabled, err := filter.evaluate(context.Background(), featureFilterEvaluation{parameters: test.parameters})
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, enabled)
+		})
+	}
+}
+
+func TestTimeWindowFilter_InvalidRecurrence(t *testing.T) {
+	tests := []struct {
+		name           string
+		parameters     map[string]any
+		expectedErrMsg string
+	}{
+		{
+			name:           "missing end",
+			parameters:     map[string]any{"Start": "2023-09-01T00:00:00+08:00", "Recurrence": map[string]any{"Pattern": map[string]any{"Type": "Daily"}}},
+			expectedErrMsg: "requires both 'Start' and 'End'",
+		},
+		{
+			name: "missing pattern type",
+			parameters: map[string]any{"Start": "2023-09-01T00:00:00+08:00", "End": "2023-09-01T00:00:01+08:00",
+				"Recurrence": map[string]any{"Pattern": map[string]any{}}},
+			expectedErrMsg: "'Recurrence.Pattern.Type' parameter is required",
+		},
+		{
+			name: "invalid interval",
+			parameters: map[string]any{"Start": "2023-09-01T00:00:00+08:00", "End": "2023-09-01T00:00:01+08:00",
+				"Recurrence": map[string]any{"Pattern": map[string]any{"Type": "Daily", "Interval": 0}}},
+			expectedErrMsg: "'Recurrence.Pattern.Interval' parameter must be greater than 0",
+		},
+		{
+			name: "duration exceeds daily interval",
+			parameters: map[string]any{"Start": "2023-09-01T00:00:00+08:00", "End": "2023-09-02T00:00:01+08:00",
+				"Recurrence": map[string]any{"Pattern": map[string]any{"Type": "Daily"}}},
+			expectedErrMsg: "cannot exceed the recurrence interval",
+		},
+		{
+			name: "missing days of week",
+			parameters: map[string]any{"Start": "2023-09-01T00:00:00+08:00", "End": "2023-09-01T00:00:01+08:00",
+				"Recurrence": map[string]any{"Pattern": map[string]any{"Type": "Weekly"}}},
+			expectedErrMsg: "'Recurrence.Pattern.DaysOfWeek' parameter is required",
+		},
+		{
+			name: "invalid day of week",
+			parameters: map[string]any{"Start": "2023-09-01T00:00:00+08:00", "End": "2023-09-01T00:00:01+08:00",
+				"Recurrence": map[string]any{"Pattern": map[string]any{"Type": "Weekly", "DaysOfWeek": []any{"Funday"}}}},
+			expectedErrMsg: "'Funday' is not a valid day of week",
+		},
+		{
+			name: "duration exceeds gap between days of week",
+			parameters: map[string]any{"Start": "2023-09-01T00:00:00+08:00", "End": "2023-09-02T00:00:01+08:00",
+				"Recurrence": map[string]any{"Pattern": map[string]any{"Type": "Weekly", "DaysOfWeek": []any{"Friday", "Saturday"}}}},
+			expectedErrMsg: "cannot exceed the gap between two consecutive occurrences",
+		},
+		{
+			name: "start is not a day of week",
+			parameters: map[string]any{"Start": "2023-09-01T00:00:00+08:00", "End": "2023-09-01T00:00:01+08:00",
+				"Recurrence": map[string]any{"Pattern": map[string]any{"Type": "Weekly", "DaysOfWeek": []any{"Monday"}}}},
+			expectedErrMsg: "'Start' parameter must be on one of the days",
+		},
+		{
+			name: "end date before start",
+			parameters: map[string]any{"Start": "2023-09-01T00:00:00+08:00", "End": "2023-09-01T00:00:01+08:00",
+				"Recurrence": map[string]any{"Pattern": map[string]any{"Type": "Daily"}, "Range": map[string]any{"Type": "EndDate", "EndDate": "2023-08-31T00:00:00+08:00"}}},
+			expectedErrMsg: "'Recurrence.Range.EndDate' parameter cannot be earlier",
+		},
+		{
+			name: "invalid number of occurrences",
+			parameters: map[string]any{"Start": "2023-09-01T00:00:00+08:00", "End": "2023-09-01T00:00:01+08:00",
+				"Recurrence": map[string]any{"Pattern": map[string]any{"Type": "Daily"}, "Range": map[string]any{"Type": "Numbered", "NumberOfOccurrences": 0}}},
+			expectedErrMsg: "'Recurrence.Range.NumberOfOccurrences' parameter must be greater than 0",
+		},
+		{
+			name: "invalid range type",
+			parameters: map[string]any{"Start": "2023-09-01T00:00:00+08:00", "End": "2023-09-01T00:00:01+08:00",
+				"Recurrence": map[string]any{"Pattern": map[string]any{"Type": "Daily"}, "Range": map[string]any{"Type": "Forever"}}},
+			expectedErrMsg: "invalid 'Recurrence.Range.Type' parameter 'Forever'",
+		},
+	}
+
+	filter := &timeWindowFilter{}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := filter.evaluate(context.Background(), featureFilterEvaluation{parameters: test.parameters})
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), test.expectedErrMsg)
+			}
+		})
+	}
+}
+
+func TestNextTimeWindowBoundary(t *testing.T) {
+	timeWindowFlag := func(parameters ...map[string]any) FeatureFlag {
+		filters := make([]ClientFilter, 0, len(parameters))
+		for _, p := range parameters {
+			filters = append(filters, ClientFilter{Name: TimeWindowFilterName, Parameters: p})
+		}
+		return FeatureFlag{ID: "Beta", Enabled: true, Conditions: &Conditions{ClientFilters: filters}}
+	}
+
+	window := map[string]any{"Start": "2024-05-06T09:00:00Z", "End": "2024-05-06T17:00:00Z"}
+	businessHours := map[string]any{"Start": "2024-05-06T09:00:00Z", "End": "2024-05-06T17:00:00Z",
+		"Recurrence": map[string]any{"Pattern": map[string]any{"Type": "Weekly", "DaysOfWeek": []any{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday"}}}}
+	twoDays := map[string]any{"Start": "2024-05-06T09:00:00Z", "End": "2024-05-06T17:00:00Z",
+		"Recurrence": map[string]any{"Pattern": map[string]any{"Type": "Daily"}, "Range": map[string]any{"Type": "Numbered", "NumberOfOccurrences": 2}}}
+
+	tests := []struct {
+		name        string
+		featureFlag FeatureFlag
+		now         string
+		expected    string
+	}{
+		{name: "before window", featureFlag: timeWindowFlag(window), now: "2024-05-06T08:00:00Z", expected: "2024-05-06T09:00:00Z"},
+		{name: "inside window", featureFlag: timeWindowFlag(window), now: "2024-05-06T09:00:00Z", expected: "2024-05-06T17:00:00Z"},
+		{name: "after window", featureFlag: timeWindowFlag(window), now: "2024-05-06T17:00:00Z"},
+		{name: "inside recurrence", featureFlag: timeWindowFlag(businessHours), now: "2024-05-08T12:00:00Z", expected: "2024-05-08T17:00:00Z"},
+		{name: "between recurrences", featureFlag: timeWindowFlag(businessHours), now: "2024-05-08T18:00:00Z", expected: "2024-05-09T09:00:00Z"},
+		{name: "over the weekend", featureFlag: timeWindowFlag(businessHours), now: "2024-05-10T17:00:00Z", expected: "2024-05-13T09:00:00Z"},
+		{name: "last occurrence of numbered range", featureFlag: timeWindowFlag(twoDays), now: "2024-05-07T10:00:00Z", expected: "2024-05-07T17:00:00Z"},
+		{name: "after numbered range", featureFlag: timeWindowFlag(twoDays), now: "2024-05-07T17:00:00Z"},
+		{name: "earliest of several filters", featureFlag: timeWindowFlag(twoDays, map[string]any{"End": "2024-05-06T10:00:00Z"}), now: "2024-05-06T09:30:00Z", expected: "2024-05-06T10:00:00Z"},
+		{name: "no conditions", featureFlag: FeatureFlag{ID: "Beta"}, now: "2024-05-06T09:30:00Z"},
+		{name: "invalid parameters", featureFlag: timeWindowFlag(map[string]any{"Start": "yesterday"}), now: "2024-05-06T09:30:00Z"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			boundary, ok := NextTimeWindowBoundary(test.featureFlag, mustParseTime(t, test.now))
+			if test.expected == "" {
+				assert.False(t, ok)
+				return
+			}
+
+			assert.True(t, ok)
+			assert.True(t, mustParseTime(t, test.expected).Equal(boundary), "expected %s, got %s", test.expected, boundary)
+		})
+	}
+}
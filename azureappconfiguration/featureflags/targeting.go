@@ -0,0 +1,156 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package featureflags
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// TargetingContext contains the information used to target a feature flag to a user
+type TargetingContext struct {
+	// UserID is the identifier of the user
+	UserID string
+	// Groups are the groups the user belongs to
+	Groups []string
+}
+
+// TargetingContextAccessor is implemented by application contexts which carry a TargetingContext,
+// so that the built-in targeting filter and variant allocation can be used together with custom filters.
+type TargetingContextAccessor interface {
+	GetTargetingContext() TargetingContext
+}
+
+// getTargetingContext extracts the targeting context from an application context
+func getTargetingContext(appContext any) TargetingContext {
+	switch c := appContext.(type) {
+	case TargetingContext:
+		return c
+	case *TargetingContext:
+		if c != nil {
+			return *c
+		}
+	case TargetingContextAccessor:
+		return c.GetTargetingContext()
+	}
+
+	return TargetingContext{}
+}
+
+// targetingFilter enables a feature for an audience of users and groups.
+// The percentage rollout is compatible with the other Microsoft feature management libraries,
+// so a user gets the same result regardless of the language the application is written in.
+type targetingFilter struct{}
+
+type targetingFilterParameters struct {
+	Audience audience `json:"Audience"`
+}
+
+type audience struct {
+	Users                    []string         `json:"Users"`
+	Groups                   []groupRollout   `json:"Groups"`
+	DefaultRolloutPercentage float64          `json:"DefaultRolloutPercentage"`
+	Exclusion                *audienceExclude `json:"Exclusion"`
+}
+
+type groupRollout struct {
+	Name              string  `json:"Name"`
+	RolloutPercentage float64 `json:"RolloutPercentage"`
+}
+
+type audienceExclude struct {
+	Users  []string `json:"Users"`
+	Groups []string `json:"Groups"`
+}
+
+func (f *targetingFilter) name() string {
+	return TargetingFilterName
+}
+
+func (f *targetingFilter) evaluate(ctx context.Context, evaluation featureFilterEvaluation) (bool, error) {
+	var params targetingFilterParameters
+	if err := decodeParameters(evaluation.parameters, &params); err != nil {
+		return false, err
+	}
+
+	if err := params.Audience.validate(); err != nil {
+		return false, err
+	}
+
+	return params.Audience.isTargeted(evaluation.featureName, evaluation.targetingContext), nil
+}
+
+func (a *audience) validate() error {
+	if a.DefaultRolloutPercentage < 0 || a.DefaultRolloutPercentage > 100 {
+		return fmt.Errorf("the 'Audience.DefaultRolloutPercentage' parameter must be between 0 and 100")
+	}
+
+	for _, group := range a.Groups {
+		if group.RolloutPercentage < 0 || group.RolloutPercentage > 100 {
+			return fmt.Errorf("the 'RolloutPercentage' of group '%s' must be between 0 and 100", group.Name)
+		}
+	}
+
+	return nil
+}
+
+func (a *audience) isTargeted(featureName string, targetingContext TargetingContext) bool {
+	// Exclusion takes precedence over everything else
+	if a.Exclusion != nil {
+		if isTargetedUser(targetingContext.UserID, a.Exclusion.Users) ||
+			isTargetedGroup(targetingContext.Groups, a.Exclusion.Groups) {
+			return false
+		}
+	}
+
+	if isTargetedUser(targetingContext.UserID, a.Users) {
+		return true
+	}
+
+	for _, group := range a.Groups {
+		if slices.Contains(targetingContext.Groups, group.Name) {
+			contextID := targetingContext.UserID + "\n" + featureName + "\n" + group.Name
+			if isTargetedPercentile(contextID, 0, group.RolloutPercentage) {
+				return true
+			}
+		}
+	}
+
+	contextID := targetingContext.UserID + "\n" + featureName
+	return isTargetedPercentile(contextID, 0, a.DefaultRolloutPercentage)
+}
+
+func isTargetedUser(userID string, users []string) bool {
+	return userID != "" && slices.Contains(users, userID)
+}
+
+func isTargetedGroup(groups []string, targetedGroups []string) bool {
+	for _, group := range groups {
+		if slices.Contains(targetedGroups, group) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isTargetedPercentile checks whether the context falls into the [from, to) percentile range.
+// The first 4 bytes of the SHA-256 hash of the context ID are read as a little-endian uint32,
+// which is how every Microsoft feature management library buckets its users.
+func isTargetedPercentile(contextID string, from float64, to float64) bool {
+	hash := sha256.Sum256([]byte(contextID))
+	contextMarker := binary.LittleEndian.Uint32(hash[:4])
+	contextPercentage := float64(contextMarker) / float64(math.MaxUint32) * 100
+
+	// The upper bound is inclusive when the range ends at 100
+	if to == 100 {
+		return contextPercentage >= from
+	}
+
+	return contextPercentage >= from && contextPercentage < to
+}
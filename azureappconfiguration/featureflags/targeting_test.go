@@ -0,0 +1,123 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package featureflags
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The same audience and expectations are used by the tests of the .NET feature management library
+func targetingTestParameters() map[string]any {
+	return map[string]any{
+		"Audience": map[string]any{
+			"Users": []any{"Jeff", "Alicia"},
+			"Groups": []any{
+				map[string]any{"Name": "Ring0", "RolloutPercentage": 100},
+				map[string]any{"Name": "Ring1", "RolloutPercentage": 50},
+			},
+			"DefaultRolloutPercentage": 20,
+		},
+	}
+}
+
+func TestTargetingFilter_CompatibleWithOtherLibraries(t *testing.T) {
+	tests := []struct {
+		name             string
+		targetingContext TargetingContext
+		expected         bool
+	}{
+		{name: "targeted by user id", targetingContext: TargetingContext{UserID: "Alicia"}, expected: true},
+		{name: "targeted by default rollout", targetingContext: TargetingContext{UserID: "Anne"}, expected: true},
+		{name: "not targeted by user id or default rollout", targetingContext: TargetingContext{UserID: "Patty"}, expected: false},
+		{name: "targeted by group rollout", targetingContext: TargetingContext{UserID: "Patty", Groups: []string{"Ring1"}}, expected: true},
+		{name: "not targeted by group rollout", targetingContext: TargetingContext{UserID: "Isaac", Groups: []string{"Ring1"}}, expected: false},
+		{name: "targeted by 100 percent group", targetingContext: TargetingContext{UserID: "Isaac", Groups: []string{"Ring0"}}, expected: true},
+	}
+
+	filter := &targetingFilter{}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			enabled, err := filter.evaluate(context.Background(), featureFilterEvaluation{
+				featureName:      "TargetingTestFeature",
+				parameters:       targetingTestParameters(),
+				targetingContext: test.targetingContext,
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, enabled)
+		})
+	}
+}
+
+func TestTargetingFilter_Exclusion(t *testing.T) {
+	parameters := targetingTestParameters()
+	parameters["Audience"].(map[string]any)["Exclusion"] = map[string]any{
+		"Users":  []any{"Jeff"},
+		"Groups": []any{"Ring0"},
+	}
+
+	filter := &targetingFilter{}
+	evaluate := func(targetingContext TargetingContext) bool {
+		enabled, err := filter.evaluate(context.Background(), featureFilterEvaluation{
+			featureName:      "TargetingTestFeature",
+			parameters:       parameters,
+			targetingContext: targetingContext,
+		})
+		assert.NoError(t, err)
+		return enabled
+	}
+
+	assert.False(t, evaluate(TargetingContext{UserID: "Jeff"}))
+	assert.False(t, evaluate(TargetingContext{UserID: "Alicia", Groups: []string{"Ring0"}}))
+	assert.True(t, evaluate(TargetingContext{UserID: "Alicia"}))
+}
+
+func TestTargetingFilter_InvalidParameters(t *testing.T) {
+	tests := []struct {
+		name           string
+		parameters     map[string]any
+		expectedErrMsg string
+	}{
+		{
+			name:           "default rollout out of range",
+			parameters:     map[string]any{"Audience": map[string]any{"DefaultRolloutPercentage": 101}},
+			expectedErrMsg: "DefaultRolloutPercentage",
+		},
+		{
+			name: "group rollout out of range",
+			parameters: map[string]any{"Audience": map[string]any{
+				"Groups": []any{map[string]any{"Name": "Ring0", "RolloutPercentage": -1}},
+			}},
+			expectedErrMsg: "Ring0",
+		},
+		{
+			name:           "malformed audience",
+			parameters:     map[string]any{"Audience": "everyone"},
+			expectedErrMsg: "invalid filter parameters",
+		},
+	}
+
+	filter := &targetingFilter{}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := filter.evaluate(context.Background(), featureFilterEvaluation{
+				featureName: "TargetingTestFeature",
+				parameters:  test.parameters,
+			})
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), test.expectedErrMsg)
+		})
+	}
+}
+
+func TestIsTargetedPercentile(t *testing.T) {
+	// "Anne\nTargetingTestFeature" falls into the 11th percentile
+	assert.True(t, isTargetedPercentile("Anne\nTargetingTestFeature", 0, 20))
+	assert.False(t, isTargetedPercentile("Anne\nTargetingTestFeature", 0, 10))
+	assert.True(t, isTargetedPercentile("Anne\nTargetingTestFeature", 10, 100))
+	assert.False(t, isTargetedPercentile("Anne\nTargetingTestFeature", 0, 0))
+	assert.True(t, isTargetedPercentile("any", 0, 100))
+}
@@ -0,0 +1,142 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package featureflags
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"sync"
+	"time"
+)
+
+// Keys of the telemetry metadata injected by Azure App Configuration
+const (
+	allocationIDMetadataKey         = "AllocationId"
+	eTagMetadataKey                 = "ETag"
+	featureFlagReferenceMetadataKey = "FeatureFlagReference"
+)
+
+// EvaluationEvent describes the evaluation of a feature flag with telemetry enabled.
+// The field names match the feature evaluation events of the other Microsoft feature management libraries.
+type EvaluationEvent struct {
+	// Timestamp is the time of the evaluation
+	Timestamp time.Time `json:"Timestamp"`
+	// FeatureName is the ID of the evaluated feature flag
+	FeatureName string `json:"FeatureName"`
+	// Enabled is the evaluated state of the feature flag
+	Enabled bool `json:"Enabled"`
+	// Variant is the name of the assigned variant, or empty if no variant is assigned
+	Variant string `json:"Variant,omitempty"`
+	// VariantAssignmentReason is the reason the variant was assigned
+	VariantAssignmentReason VariantAssignmentReason `json:"VariantAssignmentReason"`
+	// AllocationID identifies the allocation of the feature flag revision
+	AllocationID string `json:"AllocationId,omitempty"`
+	// ETag is the ETag of the feature flag setting
+	ETag string `json:"ETag,omitempty"`
+	// FeatureFlagReference is the URL of the feature flag setting
+	FeatureFlagReference string `json:"FeatureFlagReference,omitempty"`
+	// TargetingID is the ID of the user the feature flag was evaluated for
+	TargetingID string `json:"TargetingId,omitempty"`
+	// Metadata is the telemetry metadata of the feature flag
+	Metadata map[string]string `json:"Metadata,omitempty"`
+}
+
+// TelemetryPublisher receives an EvaluationEvent for every evaluation of a feature flag with telemetry enabled.
+// Events are published synchronously during evaluation, so implementations should return quickly.
+type TelemetryPublisher interface {
+	// Publish publishes an evaluation event. An error is logged and does not fail the evaluation.
+	Publish(ctx context.Context, event EvaluationEvent) error
+}
+
+// MemoryTelemetryPublisher keeps published evaluation events in memory, which is useful for tests and debugging.
+// It is safe for concurrent use by multiple goroutines.
+type MemoryTelemetryPublisher struct {
+	mu     sync.Mutex
+	events []EvaluationEvent
+}
+
+// NewMemoryTelemetryPublisher creates an empty MemoryTelemetryPublisher.
+func NewMemoryTelemetryPublisher() *MemoryTelemetryPublisher {
+	return &MemoryTelemetryPublisher{}
+}
+
+// Publish appends the event to the in-memory events.
+func (p *MemoryTelemetryPublisher) Publish(ctx context.Context, event EvaluationEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.events = append(p.events, event)
+	return nil
+}
+
+// Events returns a copy of the events published so far, in publishing order.
+func (p *MemoryTelemetryPublisher) Events() []EvaluationEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	events := make([]EvaluationEvent, len(p.events))
+	copy(events, p.events)
+	return events
+}
+
+// Reset removes all the events published so far.
+func (p *MemoryTelemetryPublisher) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.events = nil
+}
+
+// JSONLinesTelemetryPublisher writes each evaluation event as a single line of JSON.
+// It is safe for concurrent use by multiple goroutines.
+type JSONLinesTelemetryPublisher struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+// NewJSONLinesTelemetryPublisher creates a JSONLinesTelemetryPublisher writing to w, e.g. os.Stdout or a file.
+func NewJSONLinesTelemetryPublisher(w io.Writer) *JSONLinesTelemetryPublisher {
+	return &JSONLinesTelemetryPublisher{
+		encoder: json.NewEncoder(w),
+	}
+}
+
+// Publish writes the event as a line of JSON.
+func (p *JSONLinesTelemetryPublisher) Publish(ctx context.Context, event EvaluationEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.encoder.Encode(event); err != nil {
+		return fmt.Errorf("failed to write evaluation event of feature flag '%s': %w", event.FeatureName, err)
+	}
+
+	return nil
+}
+
+// newEvaluationEvent creates the evaluation event of a feature flag
+func newEvaluationEvent(featureFlag FeatureFlag, result evaluationResult, targetingContext TargetingContext) EvaluationEvent {
+	event := EvaluationEvent{
+		Timestamp:               time.Now().UTC(),
+		FeatureName:             featureFlag.ID,
+		Enabled:                 result.enabled,
+		VariantAssignmentReason: result.reason,
+		TargetingID:             targetingContext.UserID,
+	}
+
+	if result.variant != nil {
+		event.Variant = result.variant.Name
+	}
+
+	if featureFlag.Telemetry != nil && len(featureFlag.Telemetry.Metadata) > 0 {
+		event.AllocationID = featureFlag.Telemetry.Metadata[allocationIDMetadataKey]
+		event.ETag = featureFlag.Telemetry.Metadata[eTagMetadataKey]
+		event.FeatureFlagReference = featureFlag.Telemetry.Metadata[featureFlagReferenceMetadataKey]
+		event.Metadata = maps.Clone(featureFlag.Telemetry.Metadata)
+	}
+
+	return event
+}
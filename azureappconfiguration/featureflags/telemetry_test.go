@@ -0,0 +1,128 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package featureflags
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type failingTelemetryPublisher struct{}
+
+func (p failingTelemetryPublisher) Publish(ctx context.Context, event EvaluationEvent) error {
+	return errors.New("publish failed")
+}
+
+func telemetryTestFeatureFlag() FeatureFlag {
+	featureFlag := variantTestFeatureFlag()
+	featureFlag.Telemetry = &Telemetry{
+		Enabled: true,
+		Metadata: map[string]string{
+			"AllocationId":         "allocation-id",
+			"ETag":                 "etag",
+			"FeatureFlagReference": "https://test.azconfig.io/kv/.appconfig.featureflag/VariantFeature",
+			"Team":                 "contoso",
+		},
+	}
+	return featureFlag
+}
+
+func TestEvaluator_PublishesEvaluationEvents(t *testing.T) {
+	publisher := NewMemoryTelemetryPublisher()
+	evaluator := newTestEvaluator(t, &EvaluatorOptions{TelemetryPublisher: publisher})
+	featureFlag := telemetryTestFeatureFlag()
+
+	enabled, err := evaluator.IsEnabled(context.Background(), featureFlag, TargetingContext{UserID: "Jeff"})
+	assert.NoError(t, err)
+	assert.True(t, enabled)
+
+	variant, err := evaluator.GetVariant(context.Background(), featureFlag, TargetingContext{UserID: "Alice"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Small", variant.Name)
+
+	events := publisher.Events()
+	assert.Len(t, events, 2)
+
+	assert.Equal(t, "VariantFeature", events[0].FeatureName)
+	assert.True(t, events[0].Enabled)
+	assert.Equal(t, "Big", events[0].Variant)
+	assert.Equal(t, VariantAssignmentReasonUser, events[0].VariantAssignmentReason)
+	assert.Equal(t, "Jeff", events[0].TargetingID)
+	assert.Equal(t, "allocation-id", events[0].AllocationID)
+	assert.Equal(t, "etag", events[0].ETag)
+	assert.Equal(t, "https://test.azconfig.io/kv/.appconfig.featureflag/VariantFeature", events[0].FeatureFlagReference)
+	assert.Equal(t, "contoso", events[0].Metadata["Team"])
+	assert.False(t, events[0].Timestamp.IsZero())
+
+	assert.Equal(t, "Small", events[1].Variant)
+	assert.Equal(t, VariantAssignmentReasonDefaultWhenEnabled, events[1].VariantAssignmentReason)
+	assert.Equal(t, "Alice", events[1].TargetingID)
+
+	publisher.Reset()
+	assert.Empty(t, publisher.Events())
+}
+
+func TestEvaluator_TelemetryDisabled(t *testing.T) {
+	publisher := NewMemoryTelemetryPublisher()
+	evaluator := newTestEvaluator(t, &EvaluatorOptions{TelemetryPublisher: publisher})
+
+	featureFlag := telemetryTestFeatureFlag()
+	featureFlag.Telemetry.Enabled = false
+	_, err := evaluator.IsEnabled(context.Background(), featureFlag, TargetingContext{UserID: "Jeff"})
+	assert.NoError(t, err)
+
+	_, err = evaluator.IsEnabled(context.Background(), variantTestFeatureFlag(), TargetingContext{UserID: "Jeff"})
+	assert.NoError(t, err)
+
+	assert.Empty(t, publisher.Events())
+}
+
+func TestEvaluator_PublishFailureDoesNotFailEvaluation(t *testing.T) {
+	var buf bytes.Buffer
+	evaluator := newTestEvaluator(t, &EvaluatorOptions{
+		TelemetryPublisher: failingTelemetryPublisher{},
+		Logger:             slog.New(slog.NewJSONHandler(&buf, nil)),
+	})
+
+	enabled, err := evaluator.IsEnabled(context.Background(), telemetryTestFeatureFlag(), TargetingContext{UserID: "Jeff"})
+	assert.NoError(t, err)
+	assert.True(t, enabled)
+
+	var record map[string]any
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "WARN", record["level"])
+	assert.Equal(t, telemetryTestFeatureFlag().ID, record["feature_flag"])
+	assert.Equal(t, "publish failed", record["error"])
+}
+
+func TestJSONLinesTelemetryPublisher(t *testing.T) {
+	var buf bytes.Buffer
+	evaluator := newTestEvaluator(t, &EvaluatorOptions{TelemetryPublisher: NewJSONLinesTelemetryPublisher(&buf)})
+	featureFlag := telemetryTestFeatureFlag()
+
+	_, err := evaluator.IsEnabled(context.Background(), featureFlag, TargetingContext{UserID: "Jeff"})
+	assert.NoError(t, err)
+	_, err = evaluator.IsEnabled(context.Background(), featureFlag, TargetingContext{UserID: "Alice"})
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+
+	var event map[string]any
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &event))
+	assert.Equal(t, "VariantFeature", event["FeatureName"])
+	assert.Equal(t, true, event["Enabled"])
+	assert.Equal(t, "Big", event["Variant"])
+	assert.Equal(t, "User", event["VariantAssignmentReason"])
+	assert.Equal(t, "Jeff", event["TargetingId"])
+	assert.Equal(t, "allocation-id", event["AllocationId"])
+	assert.Equal(t, "etag", event["ETag"])
+}
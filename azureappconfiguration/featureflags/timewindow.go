@@ -0,0 +1,205 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package featureflags
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Time formats accepted for the "Start" and "End" parameters of the time window filter
+var timeWindowLayouts = []string{
+	time.RFC1123,
+	time.RFC1123Z,
+	time.RFC3339,
+	time.RFC3339Nano,
+}
+
+// timeWindowFilter enables a feature during a window of time
+type timeWindowFilter struct {
+	now func() time.Time // overridable for testing
+}
+
+type timeWindowFilterParameters struct {
+	Start      string      `json:"Start"`
+	End        string      `json:"End"`
+	Recurrence *recurrence `json:"Recurrence"`
+}
+
+// timeWindow is a parsed time window, which is active from start (inclusive) to end (exclusive)
+// and optionally recurs after the first window
+type timeWindow struct {
+	start      *time.Time
+	end        *time.Time
+	recurrence *recurrenceSpec
+}
+
+func (f *timeWindowFilter) name() string {
+	return TimeWindowFilterName
+}
+
+func (f *timeWindowFilter) evaluate(ctx context.Context, evaluation featureFilterEvaluation) (bool, error) {
+	var params timeWindowFilterParameters
+	if err := decodeParameters(evaluation.parameters, &params); err != nil {
+		return false, err
+	}
+
+	window, err := params.parse()
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	if f.now != nil {
+		now = f.now()
+	}
+
+	return window.isActive(now), nil
+}
+
+// parse parses the boundaries and the recurrence of the time window
+func (p *timeWindowFilterParameters) parse() (timeWindow, error) {
+	var window timeWindow
+	if p.Start == "" && p.End == "" {
+		return window, fmt.Errorf("the time window filter requires at least one of 'Start' and 'End' parameters")
+	}
+
+	if p.Start != "" {
+		start, err := parseTimeWindowTime(p.Start)
+		if err != nil {
+			return window, fmt.Errorf("invalid 'Start' parameter: %w", err)
+		}
+		window.start = &start
+	}
+
+	if p.End != "" {
+		end, err := parseTimeWindowTime(p.End)
+		if err != nil {
+			return window, fmt.Errorf("invalid 'End' parameter: %w", err)
+		}
+		window.end = &end
+	}
+
+	if p.Recurrence != nil {
+		if window.start == nil || window.end == nil {
+			return window, fmt.Errorf("a recurring time window requires both 'Start' and 'End' parameters")
+		}
+
+		spec, err := newRecurrenceSpec(*window.start, *window.end, p.Recurrence)
+		if err != nil {
+			return window, err
+		}
+		window.recurrence = spec
+	}
+
+	return window, nil
+}
+
+// validate checks that the parameters can be parsed and that the window does not end before it starts
+func (p *timeWindowFilterParameters) validate() error {
+	window, err := p.parse()
+	if err != nil {
+		return err
+	}
+
+	if window.start != nil && window.end != nil && window.end.Before(*window.start) {
+		return fmt.Errorf("the 'End' parameter cannot be earlier than the 'Start' parameter")
+	}
+
+	return nil
+}
+
+// isActive checks if the time is within the first time window or one of its recurrences
+func (w timeWindow) isActive(now time.Time) bool {
+	if (w.start == nil || !now.Before(*w.start)) && (w.end == nil || now.Before(*w.end)) {
+		return true
+	}
+
+	return w.recurrence != nil && w.recurrence.isMatch(now)
+}
+
+// nextBoundary returns the earliest time after now at which the time window starts or ends
+func (w timeWindow) nextBoundary(now time.Time) (time.Time, bool) {
+	candidates := make([]time.Time, 0, 4)
+	if w.start != nil {
+		candidates = append(candidates, *w.start)
+	}
+	if w.end != nil {
+		candidates = append(candidates, *w.end)
+	}
+
+	if w.recurrence != nil {
+		if state, ok := w.recurrence.findPreviousRecurrence(now); ok {
+			candidates = append(candidates, state.previousOccurrence.Add(w.recurrence.duration))
+		}
+		if next, ok := w.recurrence.findNextOccurrence(now); ok {
+			candidates = append(candidates, next)
+		}
+	}
+
+	var boundary time.Time
+	found := false
+	for _, candidate := range candidates {
+		if candidate.After(now) && (!found || candidate.Before(boundary)) {
+			boundary = candidate
+			found = true
+		}
+	}
+
+	return boundary, found
+}
+
+// NextTimeWindowBoundary returns the earliest time after now at which a "Microsoft.TimeWindow" filter of the
+// feature flag starts or ends a time window, including the recurrences of recurring time windows.
+// Callers which cache evaluation results can use it to schedule the next re-evaluation.
+// Time window filters with invalid parameters are ignored.
+//
+// Parameters:
+//   - featureFlag: The feature flag
+//   - now: The time after which to find the next boundary
+//
+// Returns:
+//   - The next time window boundary
+//   - false if the feature flag has no time window boundary after now
+func NextTimeWindowBoundary(featureFlag FeatureFlag, now time.Time) (time.Time, bool) {
+	if featureFlag.Conditions == nil {
+		return time.Time{}, false
+	}
+
+	var boundary time.Time
+	found := false
+	for _, filter := range featureFlag.Conditions.ClientFilters {
+		if filter.Name != TimeWindowFilterName {
+			continue
+		}
+
+		var params timeWindowFilterParameters
+		if err := decodeParameters(filter.Parameters, &params); err != nil {
+			continue
+		}
+
+		window, err := params.parse()
+		if err != nil {
+			continue
+		}
+
+		if next, ok := window.nextBoundary(now); ok && (!found || next.Before(boundary)) {
+			boundary = next
+			found = true
+		}
+	}
+
+	return boundary, found
+}
+
+func parseTimeWindowTime(value string) (time.Time, error) {
+	for _, layout := range timeWindowLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("'%s' is not a valid RFC 1123 or RFC 3339 time", value)
+}
@@ -0,0 +1,67 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package featureflags
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeWindowFilter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		parameters map[string]any
+		expected   bool
+	}{
+		{
+			name:       "inside window",
+			parameters: map[string]any{"Start": "Wed, 01 May 2024 11:00:00 GMT", "End": "Wed, 01 May 2024 13:00:00 GMT"},
+			expected:   true,
+		},
+		{
+			name:       "before window",
+			parameters: map[string]any{"Start": "Wed, 01 May 2024 12:00:01 GMT", "End": "Wed, 01 May 2024 13:00:00 GMT"},
+			expected:   false,
+		},
+		{
+			name:       "end is exclusive",
+			parameters: map[string]any{"Start": "Wed, 01 May 2024 11:00:00 GMT", "End": "Wed, 01 May 2024 12:00:00 GMT"},
+			expected:   false,
+		},
+		{
+			name:       "start only",
+			parameters: map[string]any{"Start": "2024-05-01T12:00:00Z"},
+			expected:   true,
+		},
+		{
+			name:       "end only with offset",
+			parameters: map[string]any{"End": "2024-05-01T13:00:00+02:00"},
+			expected:   false,
+		},
+	}
+
+	filter := &timeWindowFilter{now: func() time.Time { return now }}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			enabled, err := filter.evaluate(context.Background(), featureFilterEvaluation{parameters: test.parameters})
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, enabled)
+		})
+	}
+}
+
+func TestTimeWindowFilter_InvalidParameters(t *testing.T) {
+	filter := &timeWindowFilter{}
+
+	_, err := filter.evaluate(context.Background(), featureFilterEvaluation{parameters: map[string]any{}})
+	assert.Error(t, err)
+
+	_, err = filter.evaluate(context.Background(), featureFilterEvaluation{parameters: map[string]any{"Start": "yesterday"}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid 'Start' parameter")
+}
@@ -0,0 +1,160 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package featureflags
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ValidationError describes the schema violations of a feature flag
+type ValidationError struct {
+	// FeatureID is the ID of the invalid feature flag, which may be empty
+	FeatureID string
+	// Violations describes each violation of the feature flag schema
+	Violations []string
+}
+
+// Error returns all the violations of the feature flag in a single message
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("feature flag '%s' is invalid: %s", e.FeatureID, strings.Join(e.Violations, "; "))
+}
+
+// Validate checks that the feature flag conforms to the v2.0.0 schema, including the allocation
+// and the parameters of the built-in feature filters.
+//
+// Returns:
+//   - A *ValidationError listing all the violations found, or nil if the feature flag is valid
+func (f *FeatureFlag) Validate() error {
+	var violations []string
+	if f.ID == "" {
+		violations = append(violations, "id cannot be empty")
+	}
+
+	if f.Conditions != nil {
+		switch f.Conditions.RequirementType {
+		case "", RequirementTypeAny, RequirementTypeAll:
+		default:
+			violations = append(violations, fmt.Sprintf("invalid requirement type '%s'", f.Conditions.RequirementType))
+		}
+
+		for i, filter := range f.Conditions.ClientFilters {
+			if filter.Name == "" {
+				violations = append(violations, fmt.Sprintf("client filter without name at index %d", i))
+				continue
+			}
+
+			if err := validateFilterParameters(filter); err != nil {
+				violations = append(violations, fmt.Sprintf("invalid parameters of client filter '%s': %s", filter.Name, err.Error()))
+			}
+		}
+	}
+
+	variantNames := make(map[string]struct{}, len(f.Variants))
+	for _, variant := range f.Variants {
+		if variant.Name == "" {
+			violations = append(violations, "variant without name")
+			continue
+		}
+
+		if _, exists := variantNames[variant.Name]; exists {
+			violations = append(violations, fmt.Sprintf("duplicate variant '%s'", variant.Name))
+		}
+		variantNames[variant.Name] = struct{}{}
+
+		switch variant.StatusOverride {
+		case "", StatusOverrideNone, StatusOverrideEnabled, StatusOverrideDisabled:
+		default:
+			violations = append(violations, fmt.Sprintf("invalid status override '%s' in variant '%s'", variant.StatusOverride, variant.Name))
+		}
+	}
+
+	if f.Allocation != nil {
+		violations = append(violations, f.Allocation.violations(variantNames)...)
+	}
+
+	if len(violations) > 0 {
+		return &ValidationError{FeatureID: f.ID, Violations: violations}
+	}
+
+	return nil
+}
+
+// violations checks that the allocation only references defined variants
+// and that its percentile ranges are within 0 to 100 and do not overlap
+func (a *VariantAllocation) violations(variantNames map[string]struct{}) []string {
+	var violations []string
+	checkVariant := func(variant string, usage string) {
+		if _, exists := variantNames[variant]; !exists {
+			violations = append(violations, fmt.Sprintf("%s references undefined variant '%s'", usage, variant))
+		}
+	}
+
+	if a.DefaultWhenDisabled != "" {
+		checkVariant(a.DefaultWhenDisabled, "allocation 'default_when_disabled'")
+	}
+	if a.DefaultWhenEnabled != "" {
+		checkVariant(a.DefaultWhenEnabled, "allocation 'default_when_enabled'")
+	}
+	for _, user := range a.User {
+		checkVariant(user.Variant, "user allocation")
+	}
+	for _, group := range a.Group {
+		checkVariant(group.Variant, "group allocation")
+	}
+
+	percentiles := make([]PercentileAllocation, 0, len(a.Percentile))
+	for _, percentile := range a.Percentile {
+		checkVariant(percentile.Variant, "percentile allocation")
+		if percentile.From < 0 || percentile.To > 100 || percentile.From > percentile.To {
+			violations = append(violations, fmt.Sprintf("percentile allocation of variant '%s' has invalid range [%g, %g], expected 0 <= from <= to <= 100",
+				percentile.Variant, percentile.From, percentile.To))
+			continue
+		}
+		percentiles = append(percentiles, percentile)
+	}
+
+	sort.SliceStable(percentiles, func(i, j int) bool {
+		return percentiles[i].From < percentiles[j].From
+	})
+	for i := 1; i < len(percentiles); i++ {
+		if percentiles[i].From < percentiles[i-1].To {
+			violations = append(violations, fmt.Sprintf("percentile allocations of variants '%s' [%g, %g] and '%s' [%g, %g] overlap",
+				percentiles[i-1].Variant, percentiles[i-1].From, percentiles[i-1].To,
+				percentiles[i].Variant, percentiles[i].From, percentiles[i].To))
+		}
+	}
+
+	return violations
+}
+
+// validateFilterParameters checks the parameters of the built-in feature filters,
+// the parameters of custom filters are validated by the filters themselves during evaluation
+func validateFilterParameters(filter ClientFilter) error {
+	switch filter.Name {
+	case TargetingFilterName:
+		var params targetingFilterParameters
+		if err := decodeParameters(filter.Parameters, &params); err != nil {
+			return err
+		}
+		return params.Audience.validate()
+	case TimeWindowFilterName:
+		var params timeWindowFilterParameters
+		if err := decodeParameters(filter.Parameters, &params); err != nil {
+			return err
+		}
+		return params.validate()
+	case PercentageFilterName:
+		var params percentageFilterParameters
+		if err := decodeParameters(filter.Parameters, &params); err != nil {
+			return err
+		}
+		if params.Value < 0 || params.Value > 100 {
+			return fmt.Errorf("the 'Value' parameter must be between 0 and 100")
+		}
+	}
+
+	return nil
+}
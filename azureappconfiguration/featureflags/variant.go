@@ -0,0 +1,109 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package featureflags
+
+import (
+	"fmt"
+
+	decoder "github.com/go-viper/mapstructure/v2"
+)
+
+// Variant is a variant of a feature flag assigned to a user
+type Variant struct {
+	// Name is the name of the variant
+	Name string
+	// ConfigurationValue is the configuration value of the variant as decoded from JSON
+	ConfigurationValue any
+	// Reason describes why the variant was assigned
+	Reason VariantAssignmentReason
+}
+
+// DecodeConfigurationValue decodes the configuration value of the variant into the value pointed to by target.
+// Fields in the target struct are matched using json struct tags, in the same way as AzureAppConfiguration.Unmarshal.
+//
+// Parameters:
+//   - target: A pointer to the value to populate with the configuration value
+//
+// Returns:
+//   - An error if the configuration value cannot be converted to the target type
+func (v *Variant) DecodeConfigurationValue(target any) error {
+	config := &decoder.DecoderConfig{
+		Result:           target,
+		WeaklyTypedInput: true,
+		TagName:          "json",
+		DecodeHook: decoder.ComposeDecodeHookFunc(
+			decoder.StringToTimeDurationHookFunc(),
+			decoder.StringToSliceHookFunc(","),
+		),
+	}
+
+	decoder, err := decoder.NewDecoder(config)
+	if err != nil {
+		return err
+	}
+
+	if err := decoder.Decode(v.ConfigurationValue); err != nil {
+		return fmt.Errorf("failed to decode configuration value of variant '%s': %w", v.Name, err)
+	}
+
+	return nil
+}
+
+// assignVariant picks the variant definition of the feature flag for the targeting context.
+// The order of allocation matches the other Microsoft feature management libraries.
+func assignVariant(featureFlag FeatureFlag, enabled bool, targetingContext TargetingContext) (*VariantDefinition, VariantAssignmentReason, error) {
+	allocation := featureFlag.Allocation
+	if !enabled {
+		if allocation != nil && allocation.DefaultWhenDisabled != "" {
+			return findVariant(featureFlag.Variants, allocation.DefaultWhenDisabled), VariantAssignmentReasonDefaultWhenDisabled, nil
+		}
+
+		return nil, VariantAssignmentReasonDefaultWhenDisabled, nil
+	}
+
+	if allocation != nil {
+		for _, userAllocation := range allocation.User {
+			if isTargetedUser(targetingContext.UserID, userAllocation.Users) {
+				return findVariant(featureFlag.Variants, userAllocation.Variant), VariantAssignmentReasonUser, nil
+			}
+		}
+
+		for _, groupAllocation := range allocation.Group {
+			if isTargetedGroup(targetingContext.Groups, groupAllocation.Groups) {
+				return findVariant(featureFlag.Variants, groupAllocation.Variant), VariantAssignmentReasonGroup, nil
+			}
+		}
+
+		seed := allocation.Seed
+		if seed == "" {
+			seed = "allocation\n" + featureFlag.ID
+		}
+		for _, percentileAllocation := range allocation.Percentile {
+			if percentileAllocation.From < 0 || percentileAllocation.To > 100 || percentileAllocation.From > percentileAllocation.To {
+				return nil, VariantAssignmentReasonNone, fmt.Errorf("invalid percentile range [%v, %v) of variant '%s' in feature flag '%s'",
+					percentileAllocation.From, percentileAllocation.To, percentileAllocation.Variant, featureFlag.ID)
+			}
+
+			if isTargetedPercentile(targetingContext.UserID+"\n"+seed, percentileAllocation.From, percentileAllocation.To) {
+				return findVariant(featureFlag.Variants, percentileAllocation.Variant), VariantAssignmentReasonPercentile, nil
+			}
+		}
+
+		if allocation.DefaultWhenEnabled != "" {
+			return findVariant(featureFlag.Variants, allocation.DefaultWhenEnabled), VariantAssignmentReasonDefaultWhenEnabled, nil
+		}
+	}
+
+	return nil, VariantAssignmentReasonDefaultWhenEnabled, nil
+}
+
+func findVariant(variants []VariantDefinition, name string) *VariantDefinition {
+	for i := range variants {
+		if variants[i].Name == name {
+			return &variants[i]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,173 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package featureflags
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func variantTestFeatureFlag() FeatureFlag {
+	return FeatureFlag{
+		ID:      "VariantFeature",
+		Enabled: true,
+		Variants: []VariantDefinition{
+			{Name: "Small", ConfigurationValue: map[string]any{"size": 100, "timeout": "5s"}},
+			{Name: "Big", ConfigurationValue: map[string]any{"size": 500, "timeout": "1m"}},
+			{Name: "Off", StatusOverride: StatusOverrideDisabled},
+		},
+		Allocation: &VariantAllocation{
+			DefaultWhenDisabled: "Small",
+			DefaultWhenEnabled:  "Small",
+			User:                []UserAllocation{{Variant: "Big", Users: []string{"Jeff"}}},
+			Group:               []GroupAllocation{{Variant: "Off", Groups: []string{"Ring0"}}},
+			// "Marsha\n1234" falls into the 25th percentile, "Alice\n1234" into the 89th
+			Percentile: []PercentileAllocation{{Variant: "Big", From: 0, To: 50}},
+			Seed:       "1234",
+		},
+	}
+}
+
+func TestEvaluator_GetVariant(t *testing.T) {
+	tests := []struct {
+		name             string
+		modify           func(*FeatureFlag)
+		targetingContext TargetingContext
+		expectedVariant  string
+		expectedReason   VariantAssignmentReason
+		expectedEnabled  bool
+	}{
+		{
+			name:             "user allocation",
+			targetingContext: TargetingContext{UserID: "Jeff", Groups: []string{"Ring0"}},
+			expectedVariant:  "Big",
+			expectedReason:   VariantAssignmentReasonUser,
+			expectedEnabled:  true,
+		},
+		{
+			name:             "group allocation with status override",
+			targetingContext: TargetingContext{UserID: "Alice", Groups: []string{"Ring0"}},
+			expectedVariant:  "Off",
+			expectedReason:   VariantAssignmentReasonGroup,
+			expectedEnabled:  false,
+		},
+		{
+			name:             "seeded percentile allocation",
+			targetingContext: TargetingContext{UserID: "Marsha"},
+			expectedVariant:  "Big",
+			expectedReason:   VariantAssignmentReasonPercentile,
+			expectedEnabled:  true,
+		},
+		{
+			name:             "default when enabled",
+			targetingContext: TargetingContext{UserID: "Alice"},
+			expectedVariant:  "Small",
+			expectedReason:   VariantAssignmentReasonDefaultWhenEnabled,
+			expectedEnabled:  true,
+		},
+		{
+			name:             "default when disabled",
+			modify:           func(f *FeatureFlag) { f.Enabled = false },
+			targetingContext: TargetingContext{UserID: "Jeff"},
+			expectedVariant:  "Small",
+			expectedReason:   VariantAssignmentReasonDefaultWhenDisabled,
+			expectedEnabled:  false,
+		},
+		{
+			name: "status override is ignored for disabled flags",
+			modify: func(f *FeatureFlag) {
+				f.Enabled = false
+				f.Variants[0].StatusOverride = StatusOverrideEnabled
+			},
+			targetingContext: TargetingContext{UserID: "Jeff"},
+			expectedVariant:  "Small",
+			expectedReason:   VariantAssignmentReasonDefaultWhenDisabled,
+			expectedEnabled:  false,
+		},
+		{
+			name: "default percentile seed",
+			modify: func(f *FeatureFlag) {
+				// "Alice\nallocation\nVariantFeature" falls into the 46th percentile
+				f.Allocation.Seed = ""
+			},
+			targetingContext: TargetingContext{UserID: "Alice"},
+			expectedVariant:  "Big",
+			expectedReason:   VariantAssignmentReasonPercentile,
+			expectedEnabled:  true,
+		},
+	}
+
+	evaluator := newTestEvaluator(t, nil)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			featureFlag := variantTestFeatureFlag()
+			if test.modify != nil {
+				test.modify(&featureFlag)
+			}
+
+			variant, err := evaluator.GetVariant(context.Background(), featureFlag, test.targetingContext)
+			assert.NoError(t, err)
+			assert.NotNil(t, variant)
+			assert.Equal(t, test.expectedVariant, variant.Name)
+			assert.Equal(t, test.expectedReason, variant.Reason)
+
+			enabled, err := evaluator.IsEnabled(context.Background(), featureFlag, test.targetingContext)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedEnabled, enabled)
+		})
+	}
+}
+
+func TestEvaluator_GetVariant_NoVariant(t *testing.T) {
+	evaluator := newTestEvaluator(t, nil)
+
+	// No variants defined
+	variant, err := evaluator.GetVariant(context.Background(), FeatureFlag{ID: "Beta", Enabled: true}, TargetingContext{})
+	assert.NoError(t, err)
+	assert.Nil(t, variant)
+
+	// No default variant
+	featureFlag := FeatureFlag{ID: "Beta", Enabled: false, Variants: []VariantDefinition{{Name: "A"}}}
+	variant, err = evaluator.GetVariant(context.Background(), featureFlag, TargetingContext{})
+	assert.NoError(t, err)
+	assert.Nil(t, variant)
+
+	// Allocation references an undefined variant
+	featureFlag = FeatureFlag{ID: "Beta", Enabled: true, Variants: []VariantDefinition{{Name: "A"}}, Allocation: &VariantAllocation{DefaultWhenEnabled: "B"}}
+	variant, err = evaluator.GetVariant(context.Background(), featureFlag, TargetingContext{})
+	assert.NoError(t, err)
+	assert.Nil(t, variant)
+}
+
+func TestEvaluator_GetVariant_InvalidPercentile(t *testing.T) {
+	featureFlag := variantTestFeatureFlag()
+	featureFlag.Allocation.Percentile = []PercentileAllocation{{Variant: "Big", From: 50, To: 120}}
+
+	_, err := newTestEvaluator(t, nil).GetVariant(context.Background(), featureFlag, TargetingContext{UserID: "Alice"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid percentile range")
+}
+
+func TestVariant_DecodeConfigurationValue(t *testing.T) {
+	variant, err := newTestEvaluator(t, nil).GetVariant(context.Background(), variantTestFeatureFlag(), TargetingContext{UserID: "Jeff"})
+	assert.NoError(t, err)
+
+	type Settings struct {
+		Size    int           `json:"size"`
+		Timeout time.Duration `json:"timeout"`
+	}
+
+	var settings Settings
+	assert.NoError(t, variant.DecodeConfigurationValue(&settings))
+	assert.Equal(t, 500, settings.Size)
+	assert.Equal(t, time.Minute, settings.Timeout)
+
+	var size int
+	err = (&Variant{Name: "Bad", ConfigurationValue: map[string]any{"size": 1}}).DecodeConfigurationValue(&size)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "'Bad'")
+}
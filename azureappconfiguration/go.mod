@@ -2,12 +2,19 @@ module github.com/Azure/AppConfiguration-GoProvider/azureappconfiguration
 
 go 1.24.0
 
-require github.com/Azure/azure-sdk-for-go/sdk/data/azappconfig/v2 v2.1.0
+require (
+	github.com/Azure/azure-sdk-for-go/sdk/data/azappconfig/v2 v2.1.0
+	go.opentelemetry.io/otel v1.40.0
+	go.opentelemetry.io/otel/metric v1.40.0
+	go.opentelemetry.io/otel/trace v1.40.0
+)
 
 require (
 	github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/internal v1.2.0 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/rogpeppe/go-internal v1.14.1 // indirect
 	github.com/stretchr/objx v0.5.2 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
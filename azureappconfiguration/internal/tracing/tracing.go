@@ -7,6 +7,7 @@ import (
 	"context"
 	"net/http"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -64,9 +65,25 @@ const (
 
 	DelimiterPlus            = "+"
 	DelimiterComma           = ","
+	DelimiterEqual           = "="
 	CorrelationContextHeader = "Correlation-Context"
 )
 
+// reservedKeys are the keys of the tags built by CreateCorrelationContextHeader, which custom tags cannot use
+var reservedKeys = []string{
+	RequestTypeKey,
+	HostTypeKey,
+	KeyVaultConfiguredTag,
+	KeyVaultRefreshConfiguredTag,
+	FeaturesKey,
+	FailoverRequestTag,
+	ReplicaCountKey,
+	FMGoVerKey,
+	FeatureFilterTypeKey,
+	FFFeaturesKey,
+	FFMaxVariantsKey,
+}
+
 type Options struct {
 	Enabled                          bool
 	InitialLoadFinished              bool
@@ -81,6 +98,7 @@ type Options struct {
 	IsLoadBalancingEnabled           bool
 	FeatureFlagTracing               *FeatureFlagTracing
 	FMVersion                        string
+	CustomTags                       []string // application-defined tags in the form of "key=value" or "key"
 }
 
 func GetHostType() HostType {
@@ -98,6 +116,54 @@ func GetHostType() HostType {
 	return ""
 }
 
+// IsReservedKey checks if the key is used by a tag built by CreateCorrelationContextHeader
+func IsReservedKey(key string) bool {
+	for _, reservedKey := range reservedKeys {
+		if strings.EqualFold(key, reservedKey) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsValidTagValue checks if the value can be used in a tag of the Correlation-Context header,
+// which allows printable ASCII characters other than the delimiters of the header
+func IsValidTagValue(value string) bool {
+	for _, r := range value {
+		if r <= ' ' || r > '~' || strings.ContainsRune(DelimiterComma+DelimiterEqual+";", r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// FormatCustomTags formats the application-defined tags in the order of their keys,
+// omitting the value of a tag with an empty value
+func FormatCustomTags(tags map[string]string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	formatted := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if tags[key] == "" {
+			formatted = append(formatted, key)
+		} else {
+			formatted = append(formatted, key+DelimiterEqual+tags[key])
+		}
+	}
+
+	return formatted
+}
+
 func GetFeatureManagementVersion() string {
 	return os.Getenv(FMGoVerEnv)
 }
@@ -170,6 +236,8 @@ func CreateCorrelationContextHeader(ctx context.Context, options Options) http.H
 		}
 	}
 
+	output = append(output, options.CustomTags...)
+
 	header.Add(CorrelationContextHeader, strings.Join(output, DelimiterComma))
 
 	return header
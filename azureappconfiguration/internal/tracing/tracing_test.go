@@ -254,6 +254,38 @@ func TestCreateCorrelationContextHeader(t *testing.T) {
 		parts := strings.Split(corrContext, DelimiterComma)
 		assert.Len(t, parts, 3, "Should have 3 parts separated by commas")
 	})
+
+	t.Run("with custom tags", func(t *testing.T) {
+		options := Options{
+			Host:       HostTypeKubernetes,
+			CustomTags: FormatCustomTags(map[string]string{"Version": "1.2.0", "Service": "checkout", "Canary": ""}),
+		}
+
+		header := CreateCorrelationContextHeader(context.Background(), options)
+
+		// Custom tags are appended after the built-in tags in the order of their keys
+		corrContext := header.Get(CorrelationContextHeader)
+		assert.Equal(t, RequestTypeKey+"="+string(RequestTypeStartUp)+DelimiterComma+
+			HostTypeKey+"="+string(HostTypeKubernetes)+DelimiterComma+
+			"Canary,Service=checkout,Version=1.2.0", corrContext)
+	})
+}
+
+func TestIsReservedKey(t *testing.T) {
+	assert.True(t, IsReservedKey(HostTypeKey))
+	assert.True(t, IsReservedKey("features"))
+	assert.True(t, IsReservedKey(KeyVaultConfiguredTag))
+	assert.False(t, IsReservedKey("Service"))
+}
+
+func TestIsValidTagValue(t *testing.T) {
+	assert.True(t, IsValidTagValue(""))
+	assert.True(t, IsValidTagValue("checkout-v1.2_0"))
+	assert.False(t, IsValidTagValue("a,b"))
+	assert.False(t, IsValidTagValue("a=b"))
+	assert.False(t, IsValidTagValue("a;b"))
+	assert.False(t, IsValidTagValue("a b"))
+	assert.False(t, IsValidTagValue("café"))
 }
 
 func TestFeatureFlagTracing_UpdateFeatureFilterTracing(t *testing.T) {
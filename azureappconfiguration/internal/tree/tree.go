@@ -43,6 +43,24 @@ func (t *Tree) Insert(parts []string, value any) {
 	}
 }
 
+// Merge merges the other tree on top of the tree. A leaf of the other tree replaces the subtree at the same path,
+// and the other children are merged into the subtree at the same path.
+func (t *Tree) Merge(other *Tree) {
+	for part, otherChild := range other.children {
+		if t.children == nil {
+			t.children = make(map[string]*Tree)
+		}
+
+		child, ok := t.children[part]
+		if !ok || len(otherChild.children) == 0 {
+			t.children[part] = otherChild
+			continue
+		}
+
+		child.Merge(otherChild)
+	}
+}
+
 func (t *Tree) Build() map[string]any {
 	result := make(map[string]any)
 	for k, v := range t.children {
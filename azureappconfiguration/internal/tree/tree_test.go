@@ -180,3 +180,44 @@ func TestTree_MixedTypes(t *testing.T) {
 		t.Errorf("Expected %v, got %v", expected, result)
 	}
 }
+
+func TestTree_Merge(t *testing.T) {
+	// Test merging a tree on top of another tree
+	tree := &Tree{}
+	tree.Insert(strings.Split("config.db.host", "."), "default host")
+	tree.Insert(strings.Split("config.db.port", "."), 5432)
+	tree.Insert(strings.Split("config.cache.size", "."), 64)
+	tree.Insert(strings.Split("config.array.0", "."), "default item 1")
+	tree.Insert(strings.Split("config.array.1", "."), "default item 2")
+	tree.Insert(strings.Split("config.name", "."), "default name")
+
+	other := &Tree{}
+	other.Insert(strings.Split("config.db", "."), map[string]interface{}{"host": "host"})
+	other.Insert(strings.Split("config.cache", "."), "no cache")
+	other.Insert(strings.Split("config.array.0", "."), "item 1")
+	other.Insert(strings.Split("config.name.first", "."), "first name")
+
+	tree.Merge(other)
+	result := tree.Build()
+
+	expected := map[string]interface{}{
+		"config": map[string]interface{}{
+			"db": map[string]interface{}{
+				"host": "host",
+				"port": 5432,
+			},
+			"cache": "no cache",
+			"array": []interface{}{
+				"item 1",
+				"default item 2",
+			},
+			"name": map[string]interface{}{
+				"first": "first name",
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+}
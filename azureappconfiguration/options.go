@@ -6,12 +6,19 @@ package azureappconfiguration
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/url"
 	"sort"
+	"strings"
 	"time"
 
+	"github.com/Azure/AppConfiguration-GoProvider/azureappconfiguration/featureflags"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/data/azappconfig/v2"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Options contains optional parameters to configure the behavior of an Azure App Configuration provider.
@@ -42,12 +49,137 @@ type Options struct {
 	// It defaults to true, which allows the provider to discover and use replicas for improved availability.
 	ReplicaDiscoveryEnabled *bool
 
+	// ReplicaEndpoints specifies the endpoints of replicas of the Azure App Configuration store, e.g. "https://contoso-eastus.example.com".
+	// The replicas are used for failover and load balancing in addition to the replicas discovered via DNS SRV records,
+	// which is useful when replica discovery is not possible, e.g. behind Private Link or with custom domains.
+	ReplicaEndpoints []string
+
+	// TrustedDomains specifies additional domains, e.g. "example.com", whose hosts are trusted as replicas discovered via DNS SRV records.
+	// By default, only hosts in the same ".azconfig." or ".appconfig." domain as the endpoint of the store are trusted.
+	TrustedDomains []string
+
+	// ReplicaDiscoveryOptions contains optional parameters to configure the discovery of replicas via DNS SRV records.
+	ReplicaDiscoveryOptions ReplicaDiscoveryOptions
+
 	// LoadBalancingEnabled specifies whether to enable load balancing across multiple replicas of the Azure App Configuration service.
 	// It defaults to false.
 	LoadBalancingEnabled bool
 
+	// ReplicaSelector specifies the strategy to determine the order in which the endpoints of the store are tried,
+	// e.g. NewLatencyReplicaSelector to prefer the nearest healthy replica. It takes precedence over LoadBalancingEnabled.
+	// If not provided, the origin endpoint is tried first, or the endpoints are used in turn if LoadBalancingEnabled is true.
+	ReplicaSelector ReplicaSelector
+
+	// CircuitBreakerOptions configures the circuit breaker of each endpoint, which replaces the exponential backoff of failed endpoints.
+	CircuitBreakerOptions CircuitBreakerOptions
+
 	// StartupOptions is used when initially loading data into the configuration provider.
 	StartupOptions StartupOptions
+
+	// OperationTimeouts specifies the time allowed for each request to an endpoint and for each refresh.
+	OperationTimeouts OperationTimeouts
+
+	// TracerProvider specifies the OpenTelemetry tracer provider used to create spans for load, refresh, listing the settings
+	// of each selector, loading snapshots, resolving Key Vault references and replica discovery.
+	// If not provided, no spans are created.
+	TracerProvider trace.TracerProvider
+
+	// MeterProvider specifies the OpenTelemetry meter provider used to record metrics such as the load duration,
+	// the refresh outcomes, the number of changed keys, the failover count, the replica count and the configuration staleness.
+	// If not provided, no metrics are recorded.
+	MeterProvider metric.MeterProvider
+
+	// TracingOptions configures the request tracing which reports the usage of the provider to Azure App Configuration.
+	TracingOptions TracingOptions
+
+	// Logger receives the internal messages of the provider with structured attributes such as the key, label, endpoint and error,
+	// e.g. when a JSON value cannot be parsed or replica discovery fails. If not provided, slog.Default() will be used.
+	// Use slog.New(slog.DiscardHandler) to discard the messages.
+	Logger *slog.Logger
+
+	// Defaults specifies the defaults of the application, either as a hierarchical map[string]any or as a struct value
+	// whose fields are mapped with their json tags as in Unmarshal. The defaults are flattened with the separator of
+	// the ConstructionOptions and merged underneath the loaded key-values, including the keys nested in JSON values, so that a key missing from
+	// Azure App Configuration takes its default value in Unmarshal and GetBytes, and GetSource reports ValueSourceDefault for it.
+	// Numbers are kept as json.Number, so that large integers do not lose precision.
+	Defaults any
+}
+
+// ReplicaDiscoveryOptions contains optional parameters to configure the discovery of replicas via DNS SRV records.
+type ReplicaDiscoveryOptions struct {
+	// Resolver specifies the resolver used to look up the DNS SRV records of the replicas.
+	// If not provided, net.DefaultResolver will be used.
+	Resolver SRVResolver
+
+	// Timeout specifies the amount of time allowed to discover the replicas.
+	// If not provided, the default timeout 10 seconds will be used.
+	Timeout time.Duration
+
+	// RefreshInterval specifies how long the discovered replicas are used before they are discovered again.
+	// Must be greater than 30 seconds. If not provided, the default interval 1 hour will be used.
+	RefreshInterval time.Duration
+}
+
+// SRVResolver is an interface to look up DNS SRV records, which is implemented by *net.Resolver.
+// Implement this interface to discover replicas through a custom resolver.
+type SRVResolver interface {
+	// LookupSRV looks up the SRV records of the service, see net.Resolver.LookupSRV.
+	//
+	// Parameters:
+	//   - ctx: The context for the operation
+	//   - service: The service name, e.g. "origin" or "alt0"
+	//   - proto: The protocol, which is always "tcp"
+	//   - name: The host name to look up
+	//
+	// Returns:
+	//   - The canonical name of the host
+	//   - The SRV records of the service
+	//   - An error if the lookup fails, which should be a *net.DNSError with IsNotFound set if there is no record
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+// CircuitBreakerOptions contains optional parameters to configure the circuit breaker of each endpoint of the store.
+// The circuit of an endpoint opens after consecutive failed requests, which excludes the endpoint from requests.
+// Once the open duration elapses, the circuit becomes half-open and a lightweight probe request is sent to the endpoint
+// before any other request. The circuit closes if the probe succeeds, or opens again if the probe fails.
+type CircuitBreakerOptions struct {
+	// Enabled specifies whether the circuit breaker is used instead of the exponential backoff of failed endpoints.
+	Enabled bool
+
+	// FailureThreshold specifies the number of consecutive failed requests which opens the circuit.
+	// If not provided, the default threshold 3 will be used.
+	FailureThreshold int
+
+	// OpenDuration specifies how long the circuit stays open before it becomes half-open.
+	// If not provided, the exponential backoff duration of the consecutive failures will be used.
+	OpenDuration time.Duration
+
+	// OnStateChange is called synchronously whenever the circuit of an endpoint changes its state, e.g. to raise alerts.
+	OnStateChange func(change CircuitStateChange)
+}
+
+// CircuitState is the state of the circuit breaker of an endpoint
+type CircuitState string
+
+const (
+	// CircuitStateClosed indicates that requests are sent to the endpoint
+	CircuitStateClosed CircuitState = "Closed"
+	// CircuitStateOpen indicates that the endpoint is excluded from requests
+	CircuitStateOpen CircuitState = "Open"
+	// CircuitStateHalfOpen indicates that a probe request is sent to the endpoint to check if it is available
+	CircuitStateHalfOpen CircuitState = "HalfOpen"
+)
+
+// CircuitStateChange describes a state transition of the circuit breaker of an endpoint
+type CircuitStateChange struct {
+	// Endpoint is the URL of the endpoint
+	Endpoint string
+	// From is the previous state of the circuit
+	From CircuitState
+	// To is the new state of the circuit
+	To CircuitState
+	// Error is the error of the request which opened the circuit, which is nil for other transitions
+	Error error
 }
 
 // AuthenticationOptions contains parameters for authenticating with the Azure App Configuration service.
@@ -188,6 +320,56 @@ type FeatureFlagOptions struct {
 	// Refresh interval must be greater than 1 second. If not provided, the default interval 30 seconds will be used
 	// All loaded feature flags will be automatically watched when feature flags refresh is enabled.
 	RefreshOptions RefreshOptions
+
+	// Filters specifies the custom feature filters used to evaluate feature flags, in addition to the built-in
+	// "Microsoft.Targeting", "Microsoft.TimeWindow" and "Microsoft.Percentage" filters.
+	Filters []featureflags.FeatureFilter
+
+	// MissingFilterPolicy specifies how a client filter without a registered implementation is handled during evaluation.
+	// If not provided, featureflags.MissingFilterPolicyError will be used.
+	MissingFilterPolicy featureflags.MissingFilterPolicy
+
+	// TelemetryPublisher receives an evaluation event whenever a feature flag with telemetry enabled is evaluated
+	// by IsEnabled or GetVariant. Use featureflags.NewMemoryTelemetryPublisher or
+	// featureflags.NewJSONLinesTelemetryPublisher, or provide a custom implementation.
+	TelemetryPublisher featureflags.TelemetryPublisher
+
+	// ValidationPolicy specifies how feature flags which violate the feature flag schema are handled.
+	// The violations are available through AzureAppConfiguration.FeatureFlagDiagnostics regardless of the policy.
+	// If not provided, FeatureFlagValidationPolicyWarn will be used.
+	ValidationPolicy FeatureFlagValidationPolicy
+}
+
+// FeatureFlagValidationPolicy specifies how feature flags which violate the feature flag schema are handled
+type FeatureFlagValidationPolicy string
+
+const (
+	// FeatureFlagValidationPolicyWarn keeps invalid feature flags and reports the violations as diagnostics.
+	// Feature flags which are not valid JSON or cannot be decoded into the feature flag schema are always dropped.
+	FeatureFlagValidationPolicyWarn FeatureFlagValidationPolicy = "Warn"
+	// FeatureFlagValidationPolicyDrop excludes invalid feature flags from the configuration and from evaluation
+	FeatureFlagValidationPolicyDrop FeatureFlagValidationPolicy = "Drop"
+	// FeatureFlagValidationPolicyFail fails the load or refresh of feature flags if any feature flag is invalid
+	FeatureFlagValidationPolicyFail FeatureFlagValidationPolicy = "Fail"
+)
+
+// FeatureFlagDiagnostic describes a feature flag setting which violates the feature flag schema
+type FeatureFlagDiagnostic struct {
+	// Key is the key of the feature flag setting
+	Key string
+	// Label is the label of the feature flag setting
+	Label string
+	// FeatureID is the ID of the feature flag, which is empty if the setting is not valid JSON
+	FeatureID string
+	// Violations describes each violation of the feature flag schema
+	Violations []string
+	// Dropped indicates whether the feature flag was excluded from the configuration
+	Dropped bool
+}
+
+// Error returns all the violations of the feature flag setting in a single message
+func (d FeatureFlagDiagnostic) Error() string {
+	return fmt.Sprintf("invalid feature flag setting: key=%s, label=%s, violations=%s", d.Key, d.Label, strings.Join(d.Violations, "; "))
 }
 
 // RefreshOptions contains optional parameters to configure the behavior of refresh
@@ -206,10 +388,102 @@ type ConstructionOptions struct {
 	// Supported values: '.', ',', ';', '-', '_', '__', '/', ':'.
 	// If not provided, the default separator "." will be used.
 	Separator string
+
+	// RedactSecrets specifies whether values resolved from Key Vault references or tagged with "sensitive=true"
+	// should be replaced with "[REDACTED]" in the constructed configuration.
+	// It is useful when the configuration is dumped to logs or exposed through debug endpoints.
+	RedactSecrets bool
+}
+
+// ValueSource is the source which the value of a key is taken from in Unmarshal and GetBytes
+type ValueSource string
+
+const (
+	// ValueSourceOverride indicates that the value is set at runtime with SetOverride
+	ValueSourceOverride ValueSource = "override"
+	// ValueSourceLoaded indicates that the value is loaded from Azure App Configuration, including the secrets
+	// resolved from Key Vault, or taken from StartupOptions.InitialKeyValues until the first load started by LoadAsync succeeds
+	ValueSourceLoaded ValueSource = "loaded"
+	// ValueSourceDefault indicates that the value is taken from Options.Defaults
+	ValueSourceDefault ValueSource = "default"
+)
+
+// OverrideOptions contains optional parameters for runtime overrides.
+type OverrideOptions struct {
+	// Duration specifies how long the override is in effect.
+	// If not provided, the override is in effect until ClearOverrides is called.
+	Duration time.Duration
 }
 
 // StartupOptions is used when initially loading data into the configuration provider.
 type StartupOptions struct {
 	// Timeout specifies the amount of time allowed to load data from Azure App Configuration on startup.
 	Timeout time.Duration
+
+	// Backoff specifies how long to wait before the next attempt after the attempt failed with a retriable error,
+	// given the number of attempts so far and the time elapsed since the startup.
+	// If not provided, a fixed backoff duration is used within the first 10 minutes, followed by an exponential backoff.
+	Backoff func(attempt int, elapsed time.Duration) time.Duration
+
+	// MaxAttempts specifies the maximum number of attempts to load data, including the first attempt.
+	// If not provided, the load is retried until Timeout is reached.
+	MaxAttempts int
+
+	// FailFast specifies whether the load fails on the first error without retrying, e.g. for command-line tools.
+	FailFast bool
+
+	// IsRetriable specifies whether the load is retried after an error.
+	// If not provided, network errors, throttling, server errors and authentication errors are retried.
+	IsRetriable func(err error) bool
+
+	// OnAttemptFailed is called after each failed attempt to load data, e.g. to log the startup progress.
+	OnAttemptFailed func(attempt StartupAttempt)
+
+	// InitialKeyValues specifies the key-values served by LoadAsync until the first load succeeds,
+	// e.g. the defaults of the application or the data cached from a previous run.
+	// The keys are the keys after any prefixes in TrimKeyPrefixes are trimmed.
+	InitialKeyValues map[string]any
+}
+
+// TracingOptions configures the request tracing, which reports the features in use, the type of the host
+// and application-defined tags to Azure App Configuration in the Correlation-Context header of each request.
+type TracingOptions struct {
+	// Disabled specifies whether to disable request tracing. Request tracing is also disabled
+	// if the AZURE_APP_CONFIGURATION_TRACING_DISABLED environment variable is set to true.
+	Disabled bool
+
+	// HostType overrides the type of the host detected from the environment variables, e.g. "Kubernetes".
+	HostType string
+
+	// Tags specifies application-defined tags appended to the Correlation-Context header, e.g. the name and version of the service.
+	// Keys and values can only contain printable ASCII characters other than ',', '=' and ';', and a tag with an empty value
+	// is sent as its key only. Keys cannot be any of the keys reported by the provider, such as "Host" or "Features".
+	Tags map[string]string
+}
+
+// OperationTimeouts specifies the time allowed for the operations against Azure App Configuration,
+// in addition to the deadline of the context passed to Load and Refresh.
+type OperationTimeouts struct {
+	// Request specifies the time allowed for each attempt against an endpoint of the store, including resolving the Key Vault
+	// references of the loaded key-values. An attempt which exceeds it fails over to the next endpoint, so that a slow replica
+	// does not consume the whole deadline of the load or refresh. If not provided, each attempt is only limited by the context.
+	Request time.Duration
+
+	// Refresh specifies the time allowed for each call to Refresh, including the failover between endpoints.
+	// If not provided, each refresh is only limited by the context passed to Refresh.
+	Refresh time.Duration
+}
+
+// StartupAttempt describes a failed attempt to load data from Azure App Configuration on startup
+type StartupAttempt struct {
+	// Attempt is the number of the attempt, starting from 1
+	Attempt int
+	// Elapsed is the time elapsed since the startup
+	Elapsed time.Duration
+	// Error is the error of the attempt
+	Error error
+	// Retriable indicates whether the error is retriable
+	Retriable bool
+	// NextRetryDelay is how long to wait before the next attempt, which is zero if the load is not retried
+	NextRetryDelay time.Duration
 }
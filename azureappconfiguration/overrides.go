@@ -0,0 +1,175 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package azureappconfiguration
+
+import (
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// overrideLayer holds key-values and feature flag states set at runtime, which take precedence over
+// the configuration loaded from Azure App Configuration and are kept across refreshes.
+// The zero value is an empty layer, and it is safe for concurrent use by multiple goroutines.
+type overrideLayer struct {
+	mu        sync.RWMutex
+	keyValues map[string]keyValueOverride
+	features  map[string]featureOverride
+}
+
+type keyValueOverride struct {
+	value     any
+	expiresAt time.Time // zero if the override does not expire
+}
+
+type featureOverride struct {
+	enabled   bool
+	expiresAt time.Time // zero if the override does not expire
+}
+
+func (l *overrideLayer) setKeyValue(key string, value any, options *OverrideOptions) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.removeExpired(now)
+	if l.keyValues == nil {
+		l.keyValues = make(map[string]keyValueOverride)
+	}
+	l.keyValues[key] = keyValueOverride{value: value, expiresAt: getExpiresAt(now, options)}
+}
+
+func (l *overrideLayer) setFeature(id string, enabled bool, options *OverrideOptions) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.removeExpired(now)
+	if l.features == nil {
+		l.features = make(map[string]featureOverride)
+	}
+	l.features[id] = featureOverride{enabled: enabled, expiresAt: getExpiresAt(now, options)}
+}
+
+func (l *overrideLayer) clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.keyValues = nil
+	l.features = nil
+}
+
+// getKeyValues returns the key-value overrides which are in effect
+func (l *overrideLayer) getKeyValues() map[string]any {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	now := time.Now()
+	result := make(map[string]any, len(l.keyValues))
+	for key, override := range l.keyValues {
+		if isOverrideActive(now, override.expiresAt) {
+			result[key] = override.value
+		}
+	}
+
+	return result
+}
+
+// getFeature returns the overridden state of a feature flag, or false if the feature flag is not overridden
+func (l *overrideLayer) getFeature(id string) (enabled bool, ok bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	override, exists := l.features[id]
+	if !exists || !isOverrideActive(time.Now(), override.expiresAt) {
+		return false, false
+	}
+
+	return override.enabled, true
+}
+
+// getFeatures returns the overridden states of the feature flags which are in effect
+func (l *overrideLayer) getFeatures() map[string]bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	now := time.Now()
+	result := make(map[string]bool, len(l.features))
+	for id, override := range l.features {
+		if isOverrideActive(now, override.expiresAt) {
+			result[id] = override.enabled
+		}
+	}
+
+	return result
+}
+
+// removeExpired removes the expired overrides, the caller must hold the write lock
+func (l *overrideLayer) removeExpired(now time.Time) {
+	maps.DeleteFunc(l.keyValues, func(_ string, override keyValueOverride) bool {
+		return !isOverrideActive(now, override.expiresAt)
+	})
+	maps.DeleteFunc(l.features, func(_ string, override featureOverride) bool {
+		return !isOverrideActive(now, override.expiresAt)
+	})
+}
+
+func getExpiresAt(now time.Time, options *OverrideOptions) time.Time {
+	if options == nil || options.Duration <= 0 {
+		return time.Time{}
+	}
+
+	return now.Add(options.Duration)
+}
+
+func isOverrideActive(now time.Time, expiresAt time.Time) bool {
+	return expiresAt.IsZero() || now.Before(expiresAt)
+}
+
+// applyFeatureOverrides returns a copy of the "feature_management" section with the overridden feature flags
+// enabled or disabled unconditionally. Overridden feature flags which were not loaded are appended.
+func applyFeatureOverrides(featureFlags map[string]any, overrides map[string]bool) map[string]any {
+	var loaded []any
+	if section, ok := featureFlags[featureManagementSectionKey].(map[string]any); ok {
+		loaded, _ = section[featureFlagSectionKey].([]any)
+	}
+
+	result := make([]any, 0, len(loaded)+len(overrides))
+	applied := make(map[string]struct{}, len(overrides))
+	for _, featureFlag := range loaded {
+		featureFlagMap, ok := featureFlag.(map[string]any)
+		if !ok {
+			result = append(result, featureFlag)
+			continue
+		}
+
+		id, _ := featureFlagMap["id"].(string)
+		enabled, overridden := overrides[id]
+		if !overridden {
+			result = append(result, featureFlag)
+			continue
+		}
+
+		overriddenFlag := maps.Clone(featureFlagMap)
+		overriddenFlag[enabledKey] = enabled
+		delete(overriddenFlag, conditionsKeyName)
+		result = append(result, overriddenFlag)
+		applied[id] = struct{}{}
+	}
+
+	// Append the overridden feature flags which were not loaded in a stable order
+	ids := slices.Sorted(maps.Keys(overrides))
+	for _, id := range ids {
+		if _, ok := applied[id]; !ok {
+			result = append(result, map[string]any{"id": id, enabledKey: overrides[id]})
+		}
+	}
+
+	return map[string]any{
+		featureManagementSectionKey: map[string]any{
+			featureFlagSectionKey: result,
+		},
+	}
+}
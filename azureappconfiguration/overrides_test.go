@@ -0,0 +1,250 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package azureappconfiguration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/AppConfiguration-GoProvider/azureappconfiguration/featureflags"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azappconfig/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetOverride(t *testing.T) {
+	ctx := context.Background()
+	mockClient := new(mockSettingsClient)
+	mockClient.On("getSettings", ctx).Return(&settingsResponse{
+		settings: []azappconfig.Setting{
+			{Key: toPtr("Server.Timeout"), Value: toPtr("30")},
+			{Key: toPtr("Server.Host"), Value: toPtr("localhost")},
+		},
+	}, nil)
+
+	azappcfg := &AzureAppConfiguration{
+		kvSelectors:  deduplicateSelectors([]Selector{}),
+		keyValues:    make(map[string]any),
+		featureFlags: make(map[string]any),
+	}
+	assert.NoError(t, azappcfg.loadKeyValues(ctx, mockClient))
+
+	azappcfg.SetOverride("Server.Timeout", "5", nil)
+	azappcfg.SetOverride("Server.Port", 8080, nil)
+
+	type server struct {
+		Timeout int
+		Host    string
+		Port    int
+	}
+	var config struct {
+		Server server
+	}
+	assert.NoError(t, azappcfg.Unmarshal(&config, nil))
+	assert.Equal(t, server{Timeout: 5, Host: "localhost", Port: 8080}, config.Server)
+
+	// Overrides are kept when the key-values are reloaded
+	assert.NoError(t, azappcfg.loadKeyValues(ctx, mockClient))
+	data, err := azappcfg.GetBytes(nil)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"Server": {"Timeout": "5", "Host": "localhost", "Port": 8080}}`, string(data))
+
+	// The loaded key-values are not modified by overrides
+	assert.Equal(t, "30", *azappcfg.keyValues["Server.Timeout"].(*string))
+
+	azappcfg.ClearOverrides()
+	data, err = azappcfg.GetBytes(nil)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"Server": {"Timeout": "30", "Host": "localhost"}}`, string(data))
+}
+
+func TestSetOverride_NestedInJSONObject(t *testing.T) {
+	ctx := context.Background()
+	mockClient := new(mockSettingsClient)
+	mockClient.On("getSettings", ctx).Return(&settingsResponse{
+		settings: []azappconfig.Setting{
+			{Key: toPtr("db"), Value: toPtr(`{"host": "loaded-host", "port": 5432}`), ContentType: toPtr("application/json")},
+			{Key: toPtr("cache.size"), Value: toPtr("64")},
+		},
+	}, nil)
+
+	azappcfg := &AzureAppConfiguration{
+		kvSelectors:  deduplicateSelectors([]Selector{}),
+		keyValues:    make(map[string]any),
+		featureFlags: make(map[string]any),
+	}
+	assert.NoError(t, azappcfg.loadKeyValues(ctx, mockClient))
+
+	azappcfg.SetOverride("db.host", "override-host", nil)
+	azappcfg.SetOverride("cache", "disabled", nil)
+
+	// The override of a key nested in a loaded JSON object takes precedence regardless of the map order,
+	// and an override replaces the loaded key-values nested under its key
+	for range 100 {
+		data, err := azappcfg.GetBytes(nil)
+		assert.NoError(t, err)
+		if !assert.JSONEq(t, `{"db": {"host": "override-host", "port": 5432}, "cache": "disabled"}`, string(data)) {
+			return
+		}
+	}
+}
+
+func TestSetOverride_Expiration(t *testing.T) {
+	azappcfg := &AzureAppConfiguration{
+		keyValues: map[string]any{"Timeout": "30"},
+	}
+
+	azappcfg.SetOverride("Timeout", "5", &OverrideOptions{Duration: 50 * time.Millisecond})
+	azappcfg.SetOverride("Retries", "3", nil)
+
+	data, err := azappcfg.GetBytes(nil)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"Timeout": "5", "Retries": "3"}`, string(data))
+
+	time.Sleep(100 * time.Millisecond)
+
+	data, err = azappcfg.GetBytes(nil)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"Timeout": "30", "Retries": "3"}`, string(data))
+}
+
+func TestSetOverride_RedactSecrets(t *testing.T) {
+	azappcfg := &AzureAppConfiguration{
+		keyValues:  map[string]any{"Password": "p@ss"},
+		secretKeys: map[string]struct{}{"Password": {}},
+	}
+
+	azappcfg.SetOverride("Password", "override", nil)
+
+	data, err := azappcfg.GetBytes(&ConstructionOptions{RedactSecrets: true})
+	assert.NoError(t, err)
+	assert.JSONEq(t, fmt.Sprintf(`{"Password": "%s"}`, redactedValue), string(data))
+}
+
+func TestOverrideFeature(t *testing.T) {
+	ctx := context.Background()
+	mockClient := new(mockSettingsClient)
+	mockClient.On("getSettings", ctx).Return(&settingsResponse{
+		settings: []azappconfig.Setting{
+			{
+				Key:         toPtr(".appconfig.featureflag/Beta"),
+				Value:       toPtr(`{"id": "Beta", "enabled": false, "variants": [{"name": "On"}, {"name": "Off"}], "allocation": {"default_when_enabled": "On", "default_when_disabled": "Off"}}`),
+				ContentType: toPtr(featureFlagContentType),
+			},
+			{
+				Key:         toPtr(".appconfig.featureflag/Targeted"),
+				Value:       toPtr(`{"id": "Targeted", "enabled": true, "conditions": {"client_filters": [{"name": "Microsoft.Targeting", "parameters": {"Audience": {"Users": ["Jeff"]}}}]}}`),
+				ContentType: toPtr(featureFlagContentType),
+			},
+		},
+	}, nil)
+
+	azappcfg := &AzureAppConfiguration{
+		ffEnabled:    true,
+		ffSelectors:  getFeatureFlagSelectors([]Selector{}),
+		featureFlags: make(map[string]any),
+	}
+	assert.NoError(t, azappcfg.loadFeatureFlags(ctx, mockClient))
+
+	azappcfg.OverrideFeature("Beta", true, nil)
+	azappcfg.OverrideFeature("Targeted", true, nil)
+	azappcfg.OverrideFeature("Missing", true, nil)
+
+	// Overrides are kept when the feature flags are reloaded
+	assert.NoError(t, azappcfg.loadFeatureFlags(ctx, mockClient))
+
+	for _, id := range []string{"Beta", "Targeted", "Missing"} {
+		enabled, err := azappcfg.IsEnabled(ctx, id, featureflags.TargetingContext{UserID: "Anne"})
+		assert.NoError(t, err)
+		assert.True(t, enabled, id)
+	}
+
+	variant, err := azappcfg.GetVariant(ctx, "Beta", featureflags.TargetingContext{})
+	assert.NoError(t, err)
+	assert.Equal(t, "On", variant.Name)
+
+	// An overridden feature flag which was not loaded is enabled, but has no variants
+	variant, err = azappcfg.GetVariant(ctx, "Missing", featureflags.TargetingContext{})
+	assert.NoError(t, err)
+	assert.Nil(t, variant)
+
+	data, err := azappcfg.GetBytes(nil)
+	assert.NoError(t, err)
+	var config struct {
+		FeatureManagement featureflags.FeatureManagement `json:"feature_management"`
+	}
+	assert.NoError(t, json.Unmarshal(data, &config))
+	assert.Len(t, config.FeatureManagement.FeatureFlags, 3)
+	assert.Equal(t, "Beta", config.FeatureManagement.FeatureFlags[0].ID)
+	assert.True(t, config.FeatureManagement.FeatureFlags[0].Enabled)
+	assert.Equal(t, "Targeted", config.FeatureManagement.FeatureFlags[1].ID)
+	assert.Nil(t, config.FeatureManagement.FeatureFlags[1].Conditions)
+	assert.Equal(t, "Missing", config.FeatureManagement.FeatureFlags[2].ID)
+
+	// The loaded feature flags are not modified by overrides
+	beta, _ := azappcfg.FeatureFlag("Beta")
+	assert.False(t, beta.Enabled)
+
+	azappcfg.OverrideFeature("Targeted", false, nil)
+	enabled, err := azappcfg.IsEnabled(ctx, "Targeted", featureflags.TargetingContext{UserID: "Jeff"})
+	assert.NoError(t, err)
+	assert.False(t, enabled)
+
+	azappcfg.ClearOverrides()
+	enabled, err = azappcfg.IsEnabled(ctx, "Beta", featureflags.TargetingContext{})
+	assert.NoError(t, err)
+	assert.False(t, enabled)
+	variant, err = azappcfg.GetVariant(ctx, "Beta", featureflags.TargetingContext{})
+	assert.NoError(t, err)
+	assert.Equal(t, "Off", variant.Name)
+}
+
+func TestOverrideFeature_Expiration(t *testing.T) {
+	azappcfg := &AzureAppConfiguration{
+		featureFlagsByID: map[string]featureflags.FeatureFlag{"Beta": {ID: "Beta", Enabled: true}},
+		featureFlagIDs:   []string{"Beta"},
+	}
+
+	azappcfg.OverrideFeature("Beta", false, &OverrideOptions{Duration: 50 * time.Millisecond})
+	enabled, err := azappcfg.IsEnabled(context.Background(), "Beta", featureflags.TargetingContext{})
+	assert.NoError(t, err)
+	assert.False(t, enabled)
+
+	time.Sleep(100 * time.Millisecond)
+
+	enabled, err = azappcfg.IsEnabled(context.Background(), "Beta", featureflags.TargetingContext{})
+	assert.NoError(t, err)
+	assert.True(t, enabled)
+}
+
+func TestOverrides_Concurrency(t *testing.T) {
+	azappcfg := &AzureAppConfiguration{
+		keyValues: map[string]any{"Timeout": "30"},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			azappcfg.SetOverride(fmt.Sprintf("Key%d", i), i, nil)
+			azappcfg.OverrideFeature(fmt.Sprintf("Feature%d", i), true, nil)
+		}(i)
+		go func() {
+			defer wg.Done()
+			_, err := azappcfg.GetBytes(nil)
+			assert.NoError(t, err)
+			_, err = azappcfg.IsEnabled(context.Background(), "Feature0", featureflags.TargetingContext{})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	enabled, err := azappcfg.IsEnabled(context.Background(), "Feature9", featureflags.TargetingContext{})
+	assert.NoError(t, err)
+	assert.True(t, enabled)
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"net/url"
 	"sync"
 	"testing"
@@ -14,6 +15,7 @@ import (
 
 	"github.com/Azure/AppConfiguration-GoProvider/azureappconfiguration/internal/refresh"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/data/azappconfig/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -779,3 +781,35 @@ func TestRefreshFeatureFlags_LoaderError(t *testing.T) {
 	assert.Equal(t, 1, mockLoader.getCallCount, "Loader should be called when changes detected")
 	assert.False(t, mockTimer.resetCalled, "Timer should not be reset when error occurs")
 }
+
+// blockingTransport blocks each request until the context of the request is done
+type blockingTransport struct{}
+
+func (blockingTransport) Do(req *http.Request) (*http.Response, error) {
+	<-req.Context().Done()
+	return nil, req.Context().Err()
+}
+
+func TestPageETagsClient_CheckIfETagChanged_HonorsContext(t *testing.T) {
+	client, err := azappconfig.NewClientFromConnectionString("Endpoint=https://store.azconfig.io;Id=test-id;Secret=dGVzdA==", &azappconfig.ClientOptions{
+		ClientOptions: azcore.ClientOptions{
+			Transport: blockingTransport{},
+			Retry:     policy.RetryOptions{MaxRetries: -1},
+		},
+	})
+	require.NoError(t, err)
+
+	eTag := azcore.ETag("etag")
+	eTagsClient := &pageETagsClient{
+		client:    client,
+		pageETags: map[comparableSelector][]*azcore.ETag{{KeyFilter: "*"}: {&eTag}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	changed, err := eTagsClient.checkIfETagChanged(ctx)
+
+	assert.False(t, changed)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,135 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package azureappconfiguration
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"time"
+)
+
+// ReplicaSelector is an interface to determine the order in which the endpoints of the Azure App Configuration store
+// are tried for a request. The first endpoint is tried first and the request fails over to the next endpoint
+// when the endpoint is unavailable. Implement this interface to provide a custom selection strategy.
+type ReplicaSelector interface {
+	// Select orders the endpoints which are not in backoff.
+	//
+	// Parameters:
+	//   - replicas: The status of the endpoints which are not in backoff, in the order of the origin endpoint,
+	//     the replicas declared in Options.ReplicaEndpoints and the replicas discovered via DNS SRV records
+	//
+	// Returns:
+	//   - The endpoints in the order in which they are tried. Endpoints which are omitted are tried after the returned endpoints
+	Select(replicas []ReplicaEndpointStatus) []ReplicaEndpointStatus
+}
+
+// NewPrimaryFirstReplicaSelector creates a ReplicaSelector which always tries the origin endpoint first,
+// followed by the replicas declared in Options.ReplicaEndpoints and the replicas discovered via DNS SRV records.
+func NewPrimaryFirstReplicaSelector() ReplicaSelector {
+	return primaryFirstReplicaSelector{}
+}
+
+// NewRoundRobinReplicaSelector creates a ReplicaSelector which distributes requests across the endpoints
+// by trying the endpoint after the one which served the last successful request first.
+func NewRoundRobinReplicaSelector() ReplicaSelector {
+	return roundRobinReplicaSelector{}
+}
+
+// NewLeastRecentFailureReplicaSelector creates a ReplicaSelector which tries the endpoints which never failed first,
+// followed by the other endpoints in the order of their last failure, the least recent first.
+func NewLeastRecentFailureReplicaSelector() ReplicaSelector {
+	return leastRecentFailureReplicaSelector{}
+}
+
+// NewLatencyReplicaSelector creates a ReplicaSelector which tries the endpoints in the order of their latency,
+// which is the exponentially weighted moving average of the duration of the successful requests to the endpoint.
+// Endpoints without any successful request are tried after the measured endpoints, so that an endpoint which keeps failing
+// is not preferred each time its backoff ends. Their latency is measured once a request fails over to them.
+func NewLatencyReplicaSelector() ReplicaSelector {
+	return latencyReplicaSelector{}
+}
+
+type primaryFirstReplicaSelector struct{}
+
+func (primaryFirstReplicaSelector) Select(replicas []ReplicaEndpointStatus) []ReplicaEndpointStatus {
+	return replicas
+}
+
+type roundRobinReplicaSelector struct{}
+
+func (roundRobinReplicaSelector) Select(replicas []ReplicaEndpointStatus) []ReplicaEndpointStatus {
+	lastUsedIndex := -1
+	for i, replica := range replicas {
+		if !replica.LastSuccessTime.IsZero() &&
+			(lastUsedIndex < 0 || replica.LastSuccessTime.After(replicas[lastUsedIndex].LastSuccessTime)) {
+			lastUsedIndex = i
+		}
+	}
+
+	if lastUsedIndex < 0 {
+		return replicas
+	}
+
+	next := (lastUsedIndex + 1) % len(replicas)
+	return append(slices.Clone(replicas[next:]), replicas[:next]...)
+}
+
+type leastRecentFailureReplicaSelector struct{}
+
+func (leastRecentFailureReplicaSelector) Select(replicas []ReplicaEndpointStatus) []ReplicaEndpointStatus {
+	selected := slices.Clone(replicas)
+	slices.SortStableFunc(selected, func(a, b ReplicaEndpointStatus) int {
+		return a.LastFailureTime.Compare(b.LastFailureTime)
+	})
+
+	return selected
+}
+
+type latencyReplicaSelector struct{}
+
+func (latencyReplicaSelector) Select(replicas []ReplicaEndpointStatus) []ReplicaEndpointStatus {
+	selected := slices.Clone(replicas)
+	slices.SortStableFunc(selected, func(a, b ReplicaEndpointStatus) int {
+		return cmp.Compare(getSelectionLatency(a), getSelectionLatency(b))
+	})
+
+	return selected
+}
+
+// getSelectionLatency returns the latency of the endpoint, or the maximum duration if the endpoint has no successful request
+func getSelectionLatency(replica ReplicaEndpointStatus) time.Duration {
+	if replica.Latency == 0 {
+		return math.MaxInt64
+	}
+
+	return replica.Latency
+}
+
+// selectClients orders the clients with the replica selector
+func selectClients(selector ReplicaSelector, clients []*configurationClientWrapper, now time.Time) []*configurationClientWrapper {
+	replicas := make([]ReplicaEndpointStatus, 0, len(clients))
+	clientsByEndpoint := make(map[string]*configurationClientWrapper, len(clients))
+	for _, clientWrapper := range clients {
+		replicas = append(replicas, clientWrapper.getStatus(now))
+		clientsByEndpoint[clientWrapper.endpoint] = clientWrapper
+	}
+
+	selected := make([]*configurationClientWrapper, 0, len(clients))
+	for _, replica := range selector.Select(replicas) {
+		if clientWrapper, ok := clientsByEndpoint[replica.Endpoint]; ok {
+			selected = append(selected, clientWrapper)
+			delete(clientsByEndpoint, replica.Endpoint)
+		}
+	}
+
+	// Try the clients omitted by the selector after the selected ones
+	for _, clientWrapper := range clients {
+		if _, ok := clientsByEndpoint[clientWrapper.endpoint]; ok {
+			selected = append(selected, clientWrapper)
+		}
+	}
+
+	return selected
+}
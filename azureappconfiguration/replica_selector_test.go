@@ -0,0 +1,202 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package azureappconfiguration
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azappconfig/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func getEndpoints(replicas []ReplicaEndpointStatus) []string {
+	endpoints := make([]string, 0, len(replicas))
+	for _, replica := range replicas {
+		endpoints = append(endpoints, replica.Endpoint)
+	}
+
+	return endpoints
+}
+
+func TestPrimaryFirstReplicaSelector(t *testing.T) {
+	now := time.Now()
+	replicas := []ReplicaEndpointStatus{
+		{Endpoint: "https://primary.azconfig.io", LastFailureTime: now},
+		{Endpoint: "https://replica1.azconfig.io", Latency: time.Millisecond},
+		{Endpoint: "https://replica2.azconfig.io", LastSuccessTime: now},
+	}
+
+	selected := NewPrimaryFirstReplicaSelector().Select(replicas)
+
+	assert.Equal(t, []string{"https://primary.azconfig.io", "https://replica1.azconfig.io", "https://replica2.azconfig.io"}, getEndpoints(selected))
+}
+
+func TestRoundRobinReplicaSelector(t *testing.T) {
+	now := time.Now()
+	replicas := []ReplicaEndpointStatus{
+		{Endpoint: "https://primary.azconfig.io", LastSuccessTime: now.Add(-time.Minute)},
+		{Endpoint: "https://replica1.azconfig.io", LastSuccessTime: now},
+		{Endpoint: "https://replica2.azconfig.io"},
+	}
+
+	selector := NewRoundRobinReplicaSelector()
+	selected := selector.Select(replicas)
+	assert.Equal(t, []string{"https://replica2.azconfig.io", "https://primary.azconfig.io", "https://replica1.azconfig.io"}, getEndpoints(selected))
+
+	// The replicas are not reordered in place
+	assert.Equal(t, "https://primary.azconfig.io", replicas[0].Endpoint)
+
+	// The last used endpoint wraps around to the first one
+	replicas[2].LastSuccessTime = now.Add(time.Second)
+	selected = selector.Select(replicas)
+	assert.Equal(t, []string{"https://primary.azconfig.io", "https://replica1.azconfig.io", "https://replica2.azconfig.io"}, getEndpoints(selected))
+
+	// No endpoint has been used yet
+	selected = selector.Select([]ReplicaEndpointStatus{{Endpoint: "https://primary.azconfig.io"}, {Endpoint: "https://replica1.azconfig.io"}})
+	assert.Equal(t, []string{"https://primary.azconfig.io", "https://replica1.azconfig.io"}, getEndpoints(selected))
+}
+
+func TestLeastRecentFailureReplicaSelector(t *testing.T) {
+	now := time.Now()
+	replicas := []ReplicaEndpointStatus{
+		{Endpoint: "https://primary.azconfig.io", LastFailureTime: now},
+		{Endpoint: "https://replica1.azconfig.io", LastFailureTime: now.Add(-time.Hour)},
+		{Endpoint: "https://replica2.azconfig.io"},
+		{Endpoint: "https://replica3.azconfig.io"},
+	}
+
+	selected := NewLeastRecentFailureReplicaSelector().Select(replicas)
+
+	assert.Equal(t, []string{
+		"https://replica2.azconfig.io",
+		"https://replica3.azconfig.io",
+		"https://replica1.azconfig.io",
+		"https://primary.azconfig.io",
+	}, getEndpoints(selected))
+}
+
+func TestLatencyReplicaSelector(t *testing.T) {
+	replicas := []ReplicaEndpointStatus{
+		{Endpoint: "https://primary.azconfig.io", Latency: 120 * time.Millisecond},
+		{Endpoint: "https://replica1.azconfig.io", Latency: 15 * time.Millisecond},
+		{Endpoint: "https://replica2.azconfig.io"},
+		{Endpoint: "https://replica3.azconfig.io", Latency: 60 * time.Millisecond},
+	}
+
+	selected := NewLatencyReplicaSelector().Select(replicas)
+
+	assert.Equal(t, []string{
+		"https://replica1.azconfig.io",
+		"https://replica3.azconfig.io",
+		"https://primary.azconfig.io",
+		"https://replica2.azconfig.io",
+	}, getEndpoints(selected))
+}
+
+func TestLatencyReplicaSelector_FailingEndpoint(t *testing.T) {
+	clients := []*configurationClientWrapper{
+		{endpoint: "https://primary.azconfig.io"},
+		{endpoint: "https://replica1.azconfig.io"},
+		{endpoint: "https://replica2.azconfig.io"},
+	}
+	clients[1].recordSuccess(80 * time.Millisecond)
+	clients[2].recordSuccess(20 * time.Millisecond)
+
+	// The origin endpoint keeps failing and is tried after the measured endpoints each time its backoff ends
+	for i := 0; i < 3; i++ {
+		clients[0].recordFailure(&net.DNSError{Err: "no such host", Name: "primary.azconfig.io"})
+		selected := selectClients(NewLatencyReplicaSelector(), clients, clients[0].getStatus(time.Now()).BackoffEndTime.Add(time.Second))
+
+		require.Len(t, selected, 3)
+		assert.Same(t, clients[2], selected[0])
+		assert.Same(t, clients[1], selected[1])
+		assert.Same(t, clients[0], selected[2])
+	}
+}
+
+func TestRecordSuccess_Latency(t *testing.T) {
+	clientWrapper := &configurationClientWrapper{endpoint: "https://primary.azconfig.io"}
+
+	clientWrapper.recordSuccess(100 * time.Millisecond)
+	assert.Equal(t, 100*time.Millisecond, clientWrapper.latency)
+
+	clientWrapper.recordSuccess(200 * time.Millisecond)
+	assert.Equal(t, 130*time.Millisecond, clientWrapper.latency)
+}
+
+// orderedReplicaSelector selects the configured endpoints only, in the configured order
+type orderedReplicaSelector struct {
+	endpoints []string
+}
+
+func (s orderedReplicaSelector) Select(replicas []ReplicaEndpointStatus) []ReplicaEndpointStatus {
+	selected := make([]ReplicaEndpointStatus, 0, len(s.endpoints))
+	for _, endpoint := range s.endpoints {
+		selected = append(selected, ReplicaEndpointStatus{Endpoint: endpoint})
+	}
+
+	return selected
+}
+
+func TestSelectClients(t *testing.T) {
+	clients := []*configurationClientWrapper{
+		{endpoint: "https://primary.azconfig.io"},
+		{endpoint: "https://replica1.azconfig.io"},
+		{endpoint: "https://replica2.azconfig.io"},
+	}
+
+	// Unknown and duplicate endpoints are ignored and omitted endpoints are tried last
+	selector := orderedReplicaSelector{endpoints: []string{"https://replica2.azconfig.io", "https://unknown.azconfig.io", "https://replica2.azconfig.io"}}
+	selected := selectClients(selector, clients, time.Now())
+
+	require.Len(t, selected, 3)
+	assert.Same(t, clients[2], selected[0])
+	assert.Same(t, clients[0], selected[1])
+	assert.Same(t, clients[1], selected[2])
+}
+
+func TestExecuteFailoverPolicy_ReplicaSelector(t *testing.T) {
+	mockClientManager := new(mockClientManager)
+
+	client1 := &azappconfig.Client{}
+	client2 := &azappconfig.Client{}
+	client3 := &azappconfig.Client{}
+
+	clientWrappers := []*configurationClientWrapper{
+		{endpoint: "https://primary.azconfig.io", client: client1, latency: 100 * time.Millisecond},
+		{endpoint: "https://replica1.azconfig.io", client: client2, latency: 10 * time.Millisecond},
+		{endpoint: "https://replica2.azconfig.io", client: client3, latency: 50 * time.Millisecond},
+	}
+
+	mockClientManager.On("getClients", mock.Anything).Return(clientWrappers, nil)
+
+	azappcfg := &AzureAppConfiguration{
+		clientManager:        mockClientManager,
+		replicaSelector:      NewLatencyReplicaSelector(),
+		loadBalancingEnabled: true,
+	}
+
+	calledClients := make([]*azappconfig.Client, 0)
+	operation := func(ctx context.Context, client *azappconfig.Client) error {
+		calledClients = append(calledClients, client)
+		if client == client2 {
+			return &net.DNSError{Err: "no such host", Name: "replica1.azconfig.io"}
+		}
+		return nil
+	}
+
+	err := azappcfg.executeFailoverPolicy(context.Background(), operation)
+
+	require.NoError(t, err)
+	assert.Equal(t, []*azappconfig.Client{client2, client3}, calledClients)
+	assert.Equal(t, "https://replica2.azconfig.io", azappcfg.ReplicaStatus().LastUsedEndpoint)
+	assert.Less(t, clientWrappers[2].latency, 50*time.Millisecond)
+	assert.False(t, clientWrappers[1].lastFailureTime.IsZero())
+	mockClientManager.AssertExpectations(t)
+}
@@ -8,13 +8,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 
 	"github.com/Azure/AppConfiguration-GoProvider/azureappconfiguration/internal/tracing"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/data/azappconfig/v2"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type settingsResponse struct {
@@ -27,6 +28,7 @@ type selectorSettingsClient struct {
 	selectors      []Selector
 	client         *azappconfig.Client
 	tracingOptions tracing.Options
+	telemetry      *telemetry
 }
 
 type watchedSettingClient struct {
@@ -34,6 +36,7 @@ type watchedSettingClient struct {
 	eTags           map[WatchedSetting]*azcore.ETag
 	client          *azappconfig.Client
 	tracingOptions  tracing.Options
+	logger          *slog.Logger
 }
 
 type pageETagsClient struct {
@@ -75,21 +78,14 @@ func (s *selectorSettingsClient) getSettings(ctx context.Context) (*settingsResp
 				Fields:      azappconfig.AllSettingFields(),
 			}
 
-			pager := s.client.NewListSettingsPager(selector, nil)
-			eTags := make([]*azcore.ETag, 0)
-			for pager.More() {
-				page, err := pager.NextPage(ctx)
-				if err != nil {
-					return nil, err
-				} else if page.Settings != nil {
-					settings = append(settings, page.Settings...)
-					eTags = append(eTags, page.ETag)
-				}
+			eTags, err := s.listSettings(ctx, selector, &settings)
+			if err != nil {
+				return nil, err
 			}
 
 			pageETags[filter.comparableKey()] = eTags
 		} else {
-			snapshotSettings, err := loadSnapshotSettings(ctx, s.client, filter.SnapshotName)
+			snapshotSettings, err := loadSnapshotSettings(ctx, s.client, filter.SnapshotName, s.telemetry)
 			if err != nil {
 				return nil, err
 			}
@@ -103,6 +99,31 @@ func (s *selectorSettingsClient) getSettings(ctx context.Context) (*settingsResp
 	}, nil
 }
 
+// listSettings pages through the settings of the selector, appending them to the settings and returning the ETags of the pages
+func (s *selectorSettingsClient) listSettings(ctx context.Context, selector azappconfig.SettingSelector, settings *[]azappconfig.Setting) (eTags []*azcore.ETag, err error) {
+	ctx, span := s.telemetry.startSpan(ctx, listSettingsSpanName,
+		attribute.String(keyFilterAttributeKey, *selector.KeyFilter),
+		attribute.String(labelFilterAttributeKey, *selector.LabelFilter))
+	defer func() {
+		span.SetAttributes(attribute.Int(pageCountAttributeKey, len(eTags)))
+		endSpan(span, err)
+	}()
+
+	pager := s.client.NewListSettingsPager(selector, nil)
+	eTags = make([]*azcore.ETag, 0)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		} else if page.Settings != nil {
+			*settings = append(*settings, page.Settings...)
+			eTags = append(eTags, page.ETag)
+		}
+	}
+
+	return eTags, nil
+}
+
 func (c *watchedSettingClient) getSettings(ctx context.Context) (*settingsResponse, error) {
 	if c.tracingOptions.Enabled {
 		ctx = policy.WithHTTPHeader(ctx, tracing.CreateCorrelationContextHeader(ctx, c.tracingOptions))
@@ -120,7 +141,7 @@ func (c *watchedSettingClient) getSettings(ctx context.Context) (*settingsRespon
 					label = "no"
 				}
 				// If the watched setting is not found, log and continue
-				log.Printf("Watched key '%s' with %s label does not exist", watchedSetting.Key, label)
+				getLogger(c.logger).Warn("Watched setting does not exist", "key", watchedSetting.Key, "label", label)
 				continue
 			}
 			return nil, err
@@ -184,7 +205,7 @@ func (c *pageETagsClient) checkIfETagChanged(ctx context.Context) (bool, error)
 		pageCount := 0
 		for pager.More() {
 			pageCount++
-			page, err := pager.NextPage(context.Background())
+			page, err := pager.NextPage(ctx)
 			if err != nil {
 				return false, err
 			}
@@ -202,8 +223,13 @@ func (c *pageETagsClient) checkIfETagChanged(ctx context.Context) (bool, error)
 	return false, nil
 }
 
-func loadSnapshotSettings(ctx context.Context, client *azappconfig.Client, snapshotName string) ([]azappconfig.Setting, error) {
-	settings := make([]azappconfig.Setting, 0)
+func loadSnapshotSettings(ctx context.Context, client *azappconfig.Client, snapshotName string, telemetry *telemetry) (settings []azappconfig.Setting, err error) {
+	ctx, span := telemetry.startSpan(ctx, loadSnapshotSpanName, attribute.String(snapshotNameAttributeKey, snapshotName))
+	defer func() {
+		endSpan(span, err)
+	}()
+
+	settings = make([]azappconfig.Setting, 0)
 	snapshot, err := client.GetSnapshot(ctx, snapshotName, nil)
 	if err != nil {
 		var respErr *azcore.ResponseError
@@ -482,7 +482,7 @@ func TestLoadSettingsFromSnapshotRefs_ResolvesReference(t *testing.T) {
 	kvSettings := make(map[string]any)
 	keyVaultRefs := make(map[string]string)
 
-	err := azappcfg.loadSettingsFromSnapshotRefs(context.Background(), mockLoader, snapshotRefs, kvSettings, keyVaultRefs)
+	err := azappcfg.loadSettingsFromSnapshotRefs(context.Background(), mockLoader, snapshotRefs, kvSettings, keyVaultRefs, make(map[string]struct{}))
 	assert.NoError(t, err)
 	assert.Equal(t, &settingValue, kvSettings["key1"])
 }
@@ -508,7 +508,7 @@ func TestLoadSettingsFromSnapshotRefs_OverridesExistingKeys(t *testing.T) {
 	}
 	keyVaultRefs := make(map[string]string)
 
-	err := azappcfg.loadSettingsFromSnapshotRefs(context.Background(), mockLoader, snapshotRefs, kvSettings, keyVaultRefs)
+	err := azappcfg.loadSettingsFromSnapshotRefs(context.Background(), mockLoader, snapshotRefs, kvSettings, keyVaultRefs, make(map[string]struct{}))
 	assert.NoError(t, err)
 	assert.Equal(t, &snapshotValue, kvSettings["key1"])
 }
@@ -537,7 +537,7 @@ func TestLoadSettingsFromSnapshotRefs_IgnoresFeatureFlags(t *testing.T) {
 	kvSettings := make(map[string]any)
 	keyVaultRefs := make(map[string]string)
 
-	err := azappcfg.loadSettingsFromSnapshotRefs(context.Background(), mockLoader, snapshotRefs, kvSettings, keyVaultRefs)
+	err := azappcfg.loadSettingsFromSnapshotRefs(context.Background(), mockLoader, snapshotRefs, kvSettings, keyVaultRefs, make(map[string]struct{}))
 	assert.NoError(t, err)
 	assert.NotContains(t, kvSettings, ".appconfig.featureflag/Feature1")
 	assert.Equal(t, &regularValue, kvSettings["regular-key"])
@@ -562,7 +562,7 @@ func TestLoadSettingsFromSnapshotRefs_CollectsKeyVaultRefs(t *testing.T) {
 	kvSettings := make(map[string]any)
 	keyVaultRefs := make(map[string]string)
 
-	err := azappcfg.loadSettingsFromSnapshotRefs(context.Background(), mockLoader, snapshotRefs, kvSettings, keyVaultRefs)
+	err := azappcfg.loadSettingsFromSnapshotRefs(context.Background(), mockLoader, snapshotRefs, kvSettings, keyVaultRefs, make(map[string]struct{}))
 	assert.NoError(t, err)
 	assert.Equal(t, kvRefValue, keyVaultRefs["secret-key"])
 	assert.NotContains(t, kvSettings, "secret-key")
@@ -580,7 +580,7 @@ func TestLoadSettingsFromSnapshotRefs_NonExistentSnapshot(t *testing.T) {
 	kvSettings := make(map[string]any)
 	keyVaultRefs := make(map[string]string)
 
-	err := azappcfg.loadSettingsFromSnapshotRefs(context.Background(), mockLoader, snapshotRefs, kvSettings, keyVaultRefs)
+	err := azappcfg.loadSettingsFromSnapshotRefs(context.Background(), mockLoader, snapshotRefs, kvSettings, keyVaultRefs, make(map[string]struct{}))
 	assert.NoError(t, err)
 	assert.Empty(t, kvSettings)
 }
@@ -598,7 +598,7 @@ func TestLoadSettingsFromSnapshotRefs_InvalidFormat(t *testing.T) {
 	kvSettings := make(map[string]any)
 	keyVaultRefs := make(map[string]string)
 
-	err := azappcfg.loadSettingsFromSnapshotRefs(context.Background(), mockLoader, snapshotRefs, kvSettings, keyVaultRefs)
+	err := azappcfg.loadSettingsFromSnapshotRefs(context.Background(), mockLoader, snapshotRefs, kvSettings, keyVaultRefs, make(map[string]struct{}))
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid format for Snapshot reference setting")
 }
@@ -622,7 +622,7 @@ func TestLoadSettingsFromSnapshotRefs_HandlesNilContentType(t *testing.T) {
 	kvSettings := make(map[string]any)
 	keyVaultRefs := make(map[string]string)
 
-	err := azappcfg.loadSettingsFromSnapshotRefs(context.Background(), mockLoader, snapshotRefs, kvSettings, keyVaultRefs)
+	err := azappcfg.loadSettingsFromSnapshotRefs(context.Background(), mockLoader, snapshotRefs, kvSettings, keyVaultRefs, make(map[string]struct{}))
 	assert.NoError(t, err)
 	assert.Equal(t, &regularValue, kvSettings["plain-key"])
 }
@@ -647,7 +647,7 @@ func TestLoadSettingsFromSnapshotRefs_AppliesTrimPrefix(t *testing.T) {
 	kvSettings := make(map[string]any)
 	keyVaultRefs := make(map[string]string)
 
-	err := azappcfg.loadSettingsFromSnapshotRefs(context.Background(), mockLoader, snapshotRefs, kvSettings, keyVaultRefs)
+	err := azappcfg.loadSettingsFromSnapshotRefs(context.Background(), mockLoader, snapshotRefs, kvSettings, keyVaultRefs, make(map[string]struct{}))
 	assert.NoError(t, err)
 	// Should be stored with trimmed key
 	assert.Equal(t, &settingValue, kvSettings["name"])
@@ -673,7 +673,7 @@ func TestLoadSettingsFromSnapshotRefs_HandlesJsonContentType(t *testing.T) {
 	kvSettings := make(map[string]any)
 	keyVaultRefs := make(map[string]string)
 
-	err := azappcfg.loadSettingsFromSnapshotRefs(context.Background(), mockLoader, snapshotRefs, kvSettings, keyVaultRefs)
+	err := azappcfg.loadSettingsFromSnapshotRefs(context.Background(), mockLoader, snapshotRefs, kvSettings, keyVaultRefs, make(map[string]struct{}))
 	assert.NoError(t, err)
 	assert.Equal(t, map[string]any{"nested": "value"}, kvSettings["json-key"])
 }
@@ -0,0 +1,226 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package azureappconfiguration
+
+import (
+	"context"
+	"net/url"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
+)
+
+// telemetry creates the OpenTelemetry spans and records the metrics of an AzureAppConfiguration instance.
+// A nil telemetry is valid and does nothing, which is the case if no TracerProvider or MeterProvider is configured.
+type telemetry struct {
+	tracer trace.Tracer
+
+	// Attributes identifying the Azure App Configuration store, added to every span and metric
+	attributes []attribute.KeyValue
+
+	loadDuration  metric.Float64Histogram
+	refreshCount  metric.Int64Counter
+	changedKeys   metric.Int64Counter
+	failoverCount metric.Int64Counter
+	replicaCount  metric.Int64Gauge
+
+	// Registration of the callback observing the staleness, unregistered when the instance is closed
+	stalenessRegistration metric.Registration
+
+	// Time of the last successful load or refresh in Unix nanoseconds, used to observe the staleness
+	lastSyncTime atomic.Int64
+}
+
+// newTelemetry creates the instruments of the tracer provider and the meter provider in the options,
+// and returns nil if neither of them is configured
+func newTelemetry(options *Options, endpoint string) (*telemetry, error) {
+	if options.TracerProvider == nil && options.MeterProvider == nil {
+		return nil, nil
+	}
+
+	tracerProvider := options.TracerProvider
+	if tracerProvider == nil {
+		tracerProvider = tracenoop.NewTracerProvider()
+	}
+
+	meterProvider := options.MeterProvider
+	if meterProvider == nil {
+		meterProvider = metricnoop.NewMeterProvider()
+	}
+
+	t := &telemetry{
+		tracer: tracerProvider.Tracer(instrumentationName),
+	}
+
+	if endpointURL, err := url.Parse(endpoint); err == nil && endpointURL.Host != "" {
+		t.attributes = []attribute.KeyValue{attribute.String(serverAddressAttributeKey, endpointURL.Host)}
+	}
+
+	meter := meterProvider.Meter(instrumentationName)
+	var err error
+	if t.loadDuration, err = meter.Float64Histogram(loadDurationMetricName,
+		metric.WithDescription("Duration of loading the configuration on startup, including retries"),
+		metric.WithUnit("s")); err != nil {
+		return nil, err
+	}
+
+	if t.refreshCount, err = meter.Int64Counter(refreshCountMetricName,
+		metric.WithDescription("Number of refreshes by outcome"),
+		metric.WithUnit("{refresh}")); err != nil {
+		return nil, err
+	}
+
+	if t.changedKeys, err = meter.Int64Counter(changedKeysMetricName,
+		metric.WithDescription("Number of keys added, updated or removed by refreshes"),
+		metric.WithUnit("{key}")); err != nil {
+		return nil, err
+	}
+
+	if t.failoverCount, err = meter.Int64Counter(failoverCountMetricName,
+		metric.WithDescription("Number of requests failed over from an endpoint to the next one"),
+		metric.WithUnit("{failover}")); err != nil {
+		return nil, err
+	}
+
+	if t.replicaCount, err = meter.Int64Gauge(replicaCountMetricName,
+		metric.WithDescription("Number of known replicas of the store, excluding the origin endpoint"),
+		metric.WithUnit("{replica}")); err != nil {
+		return nil, err
+	}
+
+	staleness, err := meter.Float64ObservableGauge(stalenessMetricName,
+		metric.WithDescription("Time since the configuration was last loaded or refreshed successfully"),
+		metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+
+	if t.stalenessRegistration, err = meter.RegisterCallback(func(ctx context.Context, observer metric.Observer) error {
+		if stalenessDuration, ok := t.getStaleness(time.Now()); ok {
+			observer.ObserveFloat64(staleness, stalenessDuration.Seconds(), metric.WithAttributes(t.attributes...))
+		}
+		return nil
+	}, staleness); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// close unregisters the callback observing the staleness, so that the meter provider no longer references the instance
+func (t *telemetry) close() error {
+	if t == nil {
+		return nil
+	}
+
+	return t.stalenessRegistration.Unregister()
+}
+
+// startSpan starts a span with the attributes of the store, and returns a no-op span if telemetry is not enabled
+func (t *telemetry) startSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	if t == nil {
+		return ctx, tracenoop.Span{}
+	}
+
+	return t.tracer.Start(ctx, name, trace.WithAttributes(append(attributes, t.attributes...)...))
+}
+
+// endSpan ends the span, recording the error of the operation if any
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+func (t *telemetry) recordLoad(ctx context.Context, duration time.Duration, err error) {
+	if t == nil {
+		return
+	}
+
+	outcome := outcomeSucceeded
+	if err != nil {
+		outcome = outcomeFailed
+	} else {
+		t.markSynced(time.Now())
+	}
+
+	t.loadDuration.Record(ctx, duration.Seconds(), t.withAttributes(attribute.String(outcomeAttributeKey, outcome)))
+}
+
+func (t *telemetry) recordRefresh(ctx context.Context, outcome string, changedKeys int) {
+	if t == nil {
+		return
+	}
+
+	if outcome != outcomeFailed {
+		t.markSynced(time.Now())
+	}
+
+	t.refreshCount.Add(ctx, 1, t.withAttributes(attribute.String(outcomeAttributeKey, outcome)))
+	if changedKeys > 0 {
+		t.changedKeys.Add(ctx, int64(changedKeys), t.withAttributes())
+	}
+}
+
+func (t *telemetry) recordFailover(ctx context.Context, endpoint string) {
+	if t == nil {
+		return
+	}
+
+	t.failoverCount.Add(ctx, 1, t.withAttributes(attribute.String(endpointAttributeKey, endpoint)))
+}
+
+func (t *telemetry) recordReplicaCount(ctx context.Context, count int) {
+	if t == nil {
+		return
+	}
+
+	t.replicaCount.Record(ctx, int64(count), t.withAttributes())
+}
+
+func (t *telemetry) markSynced(now time.Time) {
+	t.lastSyncTime.Store(now.UnixNano())
+}
+
+// getStaleness returns the time since the last successful load or refresh, which is not available before the first load
+func (t *telemetry) getStaleness(now time.Time) (time.Duration, bool) {
+	lastSyncTime := t.lastSyncTime.Load()
+	if lastSyncTime == 0 {
+		return 0, false
+	}
+
+	return now.Sub(time.Unix(0, lastSyncTime)), true
+}
+
+func (t *telemetry) withAttributes(attributes ...attribute.KeyValue) metric.MeasurementOption {
+	return metric.WithAttributes(append(attributes, t.attributes...)...)
+}
+
+// countChangedKeys counts the keys which are added, updated or removed in the current key-values
+func countChangedKeys(previous, current map[string]any) int {
+	changed := 0
+	for key, value := range current {
+		if previousValue, ok := previous[key]; !ok || !reflect.DeepEqual(previousValue, value) {
+			changed++
+		}
+	}
+
+	for key := range previous {
+		if _, ok := current[key]; !ok {
+			changed++
+		}
+	}
+
+	return changed
+}
@@ -0,0 +1,425 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package azureappconfiguration
+
+import (
+	"context"
+	"maps"
+	"net"
+	"net/url"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azappconfig/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
+)
+
+// recordingTracerProvider records the spans which are ended, so that the tests only depend on the OpenTelemetry API
+type recordingTracerProvider struct {
+	tracenoop.TracerProvider
+
+	mu    sync.Mutex
+	ended []*recordedSpan
+}
+
+func (p *recordingTracerProvider) Tracer(name string, options ...trace.TracerOption) trace.Tracer {
+	return &recordingTracer{provider: p}
+}
+
+func (p *recordingTracerProvider) endedSpans() []*recordedSpan {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.ended)
+}
+
+type recordingTracer struct {
+	tracenoop.Tracer
+	provider *recordingTracerProvider
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	config := trace.NewSpanStartConfig(options...)
+	span := &recordedSpan{
+		provider:   t.provider,
+		name:       name,
+		attributes: config.Attributes(),
+	}
+
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+type recordedSpan struct {
+	tracenoop.Span
+	provider *recordingTracerProvider
+
+	name       string
+	attributes []attribute.KeyValue
+	statusCode codes.Code
+}
+
+func (s *recordedSpan) SetStatus(code codes.Code, description string) {
+	s.statusCode = code
+}
+
+func (s *recordedSpan) End(options ...trace.SpanEndOption) {
+	s.provider.mu.Lock()
+	defer s.provider.mu.Unlock()
+	s.provider.ended = append(s.provider.ended, s)
+}
+
+// measurement is a value recorded or observed by an instrument of the recordingMeterProvider
+type measurement struct {
+	value      float64
+	attributes attribute.Set
+}
+
+// recordingMeterProvider records the measurements of the instruments by name and observes the registered callbacks on collect
+type recordingMeterProvider struct {
+	metricnoop.MeterProvider
+
+	mu           sync.Mutex
+	measurements map[string][]measurement
+	observations map[string][]measurement
+	callbacks    map[*recordingRegistration]metric.Callback
+}
+
+func newRecordingMeterProvider() *recordingMeterProvider {
+	return &recordingMeterProvider{
+		measurements: make(map[string][]measurement),
+		callbacks:    make(map[*recordingRegistration]metric.Callback),
+	}
+}
+
+func (p *recordingMeterProvider) Meter(name string, options ...metric.MeterOption) metric.Meter {
+	return &recordingMeter{provider: p}
+}
+
+func (p *recordingMeterProvider) record(name string, value float64, attributes attribute.Set) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.measurements[name] = append(p.measurements[name], measurement{value: value, attributes: attributes})
+}
+
+// collect observes the registered callbacks and returns the measurements of the instrument with the name
+func (p *recordingMeterProvider) collect(name string) []measurement {
+	p.mu.Lock()
+	p.observations = make(map[string][]measurement)
+	callbacks := slices.Collect(maps.Values(p.callbacks))
+	p.mu.Unlock()
+
+	for _, callback := range callbacks {
+		_ = callback(context.Background(), &recordingObserver{provider: p})
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append(slices.Clone(p.measurements[name]), p.observations[name]...)
+}
+
+func (p *recordingMeterProvider) registeredCallbacks() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.callbacks)
+}
+
+type recordingMeter struct {
+	metricnoop.Meter
+	provider *recordingMeterProvider
+}
+
+func (m *recordingMeter) Float64Histogram(name string, options ...metric.Float64HistogramOption) (metric.Float64Histogram, error) {
+	return &recordingFloat64Histogram{provider: m.provider, name: name}, nil
+}
+
+func (m *recordingMeter) Int64Counter(name string, options ...metric.Int64CounterOption) (metric.Int64Counter, error) {
+	return &recordingInt64Counter{provider: m.provider, name: name}, nil
+}
+
+func (m *recordingMeter) Int64Gauge(name string, options ...metric.Int64GaugeOption) (metric.Int64Gauge, error) {
+	return &recordingInt64Gauge{provider: m.provider, name: name}, nil
+}
+
+func (m *recordingMeter) Float64ObservableGauge(name string, options ...metric.Float64ObservableGaugeOption) (metric.Float64ObservableGauge, error) {
+	return &recordingFloat64ObservableGauge{name: name}, nil
+}
+
+func (m *recordingMeter) RegisterCallback(callback metric.Callback, instruments ...metric.Observable) (metric.Registration, error) {
+	registration := &recordingRegistration{provider: m.provider}
+	m.provider.mu.Lock()
+	defer m.provider.mu.Unlock()
+	m.provider.callbacks[registration] = callback
+	return registration, nil
+}
+
+type recordingFloat64Histogram struct {
+	metricnoop.Float64Histogram
+	provider *recordingMeterProvider
+	name     string
+}
+
+func (h *recordingFloat64Histogram) Record(ctx context.Context, value float64, options ...metric.RecordOption) {
+	h.provider.record(h.name, value, metric.NewRecordConfig(options).Attributes())
+}
+
+type recordingInt64Counter struct {
+	metricnoop.Int64Counter
+	provider *recordingMeterProvider
+	name     string
+}
+
+func (c *recordingInt64Counter) Add(ctx context.Context, value int64, options ...metric.AddOption) {
+	c.provider.record(c.name, float64(value), metric.NewAddConfig(options).Attributes())
+}
+
+type recordingInt64Gauge struct {
+	metricnoop.Int64Gauge
+	provider *recordingMeterProvider
+	name     string
+}
+
+func (g *recordingInt64Gauge) Record(ctx context.Context, value int64, options ...metric.RecordOption) {
+	g.provider.record(g.name, float64(value), metric.NewRecordConfig(options).Attributes())
+}
+
+type recordingFloat64ObservableGauge struct {
+	metricnoop.Float64ObservableGauge
+	name string
+}
+
+type recordingObserver struct {
+	metricnoop.Observer
+	provider *recordingMeterProvider
+}
+
+func (o *recordingObserver) ObserveFloat64(instrument metric.Float64Observable, value float64, options ...metric.ObserveOption) {
+	gauge, ok := instrument.(*recordingFloat64ObservableGauge)
+	if !ok {
+		return
+	}
+
+	o.provider.mu.Lock()
+	defer o.provider.mu.Unlock()
+	o.provider.observations[gauge.name] = append(o.provider.observations[gauge.name],
+		measurement{value: value, attributes: metric.NewObserveConfig(options).Attributes()})
+}
+
+type recordingRegistration struct {
+	metricnoop.Registration
+	provider *recordingMeterProvider
+}
+
+func (r *recordingRegistration) Unregister() error {
+	r.provider.mu.Lock()
+	defer r.provider.mu.Unlock()
+	delete(r.provider.callbacks, r)
+	return nil
+}
+
+func newTestTelemetry(t *testing.T) (*telemetry, *recordingTracerProvider, *recordingMeterProvider) {
+	tracerProvider := &recordingTracerProvider{}
+	meterProvider := newRecordingMeterProvider()
+	options := &Options{
+		TracerProvider: tracerProvider,
+		MeterProvider:  meterProvider,
+	}
+
+	telemetry, err := newTelemetry(options, "https://store.azconfig.io")
+	require.NoError(t, err)
+	require.NotNil(t, telemetry)
+
+	return telemetry, tracerProvider, meterProvider
+}
+
+func TestNewTelemetry_NotConfigured(t *testing.T) {
+	telemetry, err := newTelemetry(&Options{}, "https://store.azconfig.io")
+	require.NoError(t, err)
+	assert.Nil(t, telemetry)
+
+	// A nil telemetry does nothing
+	assert.NotPanics(t, func() {
+		_, span := telemetry.startSpan(context.Background(), loadSpanName)
+		endSpan(span, nil)
+		telemetry.recordLoad(context.Background(), time.Second, nil)
+		telemetry.recordRefresh(context.Background(), outcomeChanged, 1)
+		telemetry.recordFailover(context.Background(), "https://store.azconfig.io")
+		telemetry.recordReplicaCount(context.Background(), 1)
+		assert.NoError(t, telemetry.close())
+	})
+}
+
+func TestTelemetry_Load(t *testing.T) {
+	telemetry, tracerProvider, meterProvider := newTestTelemetry(t)
+	azappcfg := &AzureAppConfiguration{telemetry: telemetry}
+
+	// No staleness is observed before the first load
+	assert.Empty(t, meterProvider.collect(stalenessMetricName))
+
+	err := azappcfg.startup(context.Background(), StartupOptions{}, func(ctx context.Context) error {
+		return nil
+	})
+	require.NoError(t, err)
+
+	spans := tracerProvider.endedSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, loadSpanName, spans[0].name)
+	assert.Contains(t, spans[0].attributes, attribute.String(serverAddressAttributeKey, "store.azconfig.io"))
+
+	loadDuration := meterProvider.collect(loadDurationMetricName)
+	require.Len(t, loadDuration, 1)
+	outcome, _ := loadDuration[0].attributes.Value(attribute.Key(outcomeAttributeKey))
+	assert.Equal(t, outcomeSucceeded, outcome.AsString())
+
+	staleness := meterProvider.collect(stalenessMetricName)
+	require.Len(t, staleness, 1)
+	serverAddress, _ := staleness[0].attributes.Value(attribute.Key(serverAddressAttributeKey))
+	assert.Equal(t, "store.azconfig.io", serverAddress.AsString())
+}
+
+func TestTelemetry_LoadFailure(t *testing.T) {
+	telemetry, tracerProvider, _ := newTestTelemetry(t)
+	azappcfg := &AzureAppConfiguration{telemetry: telemetry}
+
+	err := azappcfg.startup(context.Background(), StartupOptions{FailFast: true}, func(ctx context.Context) error {
+		return errNoClientAvailable
+	})
+	require.Error(t, err)
+
+	spans := tracerProvider.endedSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].statusCode)
+	_, synced := telemetry.getStaleness(time.Now())
+	assert.False(t, synced)
+}
+
+func TestTelemetry_Refresh(t *testing.T) {
+	telemetry, tracerProvider, meterProvider := newTestTelemetry(t)
+
+	mockClientManager := new(mockClientManager)
+	mockClientManager.On("getClients", mock.Anything).Return([]*configurationClientWrapper{
+		{endpoint: "https://store.azconfig.io", client: &azappconfig.Client{}},
+	}, nil)
+
+	secretURL, _ := url.Parse("https://myvault.vault.azure.net/secrets/s1")
+	mockResolver := new(mockSecretResolver)
+	mockResolver.On("ResolveSecret", mock.Anything, *secretURL).Return("new-secret", nil)
+
+	azappcfg := &AzureAppConfiguration{
+		clientManager:      mockClientManager,
+		keyValues:          map[string]any{"secret": "old-secret", "app": "unchanged"},
+		secretRefreshTimer: &mockRefreshCondition{shouldRefresh: true},
+		keyVaultRefs:       map[string]string{"secret": `{"uri":"https://myvault.vault.azure.net/secrets/s1"}`},
+		resolver: &keyVaultReferenceResolver{
+			clients:        sync.Map{},
+			secretResolver: mockResolver,
+		},
+		telemetry: telemetry,
+	}
+
+	err := azappcfg.Refresh(context.Background())
+	require.NoError(t, err)
+
+	spanNames := make([]string, 0)
+	for _, span := range tracerProvider.endedSpans() {
+		spanNames = append(spanNames, span.name)
+	}
+	assert.ElementsMatch(t, []string{resolveSecretSpanName, refreshSpanName}, spanNames)
+
+	refreshCount := meterProvider.collect(refreshCountMetricName)
+	require.Len(t, refreshCount, 1)
+	outcome, _ := refreshCount[0].attributes.Value(attribute.Key(outcomeAttributeKey))
+	assert.Equal(t, outcomeChanged, outcome.AsString())
+
+	changedKeys := meterProvider.collect(changedKeysMetricName)
+	require.Len(t, changedKeys, 1)
+	assert.Equal(t, float64(1), changedKeys[0].value)
+}
+
+func TestTelemetry_Failover(t *testing.T) {
+	telemetry, _, meterProvider := newTestTelemetry(t)
+
+	client1 := &azappconfig.Client{}
+	client2 := &azappconfig.Client{}
+	mockClientManager := new(mockClientManager)
+	mockClientManager.On("getClients", mock.Anything).Return([]*configurationClientWrapper{
+		{endpoint: "https://store.azconfig.io", client: client1},
+		{endpoint: "https://store-eastus.azconfig.io", client: client2},
+	}, nil)
+
+	azappcfg := &AzureAppConfiguration{
+		clientManager: mockClientManager,
+		telemetry:     telemetry,
+	}
+
+	err := azappcfg.executeFailoverPolicy(context.Background(), func(ctx context.Context, client *azappconfig.Client) error {
+		if client == client1 {
+			return &net.DNSError{Err: "no such host", Name: "store.azconfig.io"}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	failoverCount := meterProvider.collect(failoverCountMetricName)
+	require.Len(t, failoverCount, 1)
+	assert.Equal(t, float64(1), failoverCount[0].value)
+	endpoint, _ := failoverCount[0].attributes.Value(attribute.Key(endpointAttributeKey))
+	assert.Equal(t, "https://store.azconfig.io", endpoint.AsString())
+}
+
+func TestTelemetry_Close(t *testing.T) {
+	meterProvider := newRecordingMeterProvider()
+	options := &Options{MeterProvider: meterProvider}
+
+	sources := make([]*AzureAppConfiguration, 2)
+	for i := range sources {
+		telemetry, err := newTelemetry(options, "https://store.azconfig.io")
+		require.NoError(t, err)
+		telemetry.markSynced(time.Now())
+		sources[i] = &AzureAppConfiguration{telemetry: telemetry}
+	}
+	assert.Equal(t, 2, meterProvider.registeredCallbacks())
+	assert.Len(t, meterProvider.collect(stalenessMetricName), 2)
+
+	// Closing a source unregisters the callback observing its staleness
+	require.NoError(t, sources[0].Close())
+	assert.Equal(t, 1, meterProvider.registeredCallbacks())
+	assert.Len(t, meterProvider.collect(stalenessMetricName), 1)
+
+	// Closing a composite closes every source, including those which are already closed
+	composite := newCompositeAzureAppConfiguration(sources)
+	require.NoError(t, composite.Close())
+	assert.Zero(t, meterProvider.registeredCallbacks())
+	assert.Empty(t, meterProvider.collect(stalenessMetricName))
+
+	// An instance without telemetry has nothing to close
+	assert.NoError(t, (&AzureAppConfiguration{}).Close())
+}
+
+func TestCountChangedKeys(t *testing.T) {
+	previous := map[string]any{
+		"unchanged": "value",
+		"updated":   "old",
+		"removed":   "value",
+		"nested":    map[string]any{"a": float64(1)},
+	}
+	current := map[string]any{
+		"unchanged": "value",
+		"updated":   "new",
+		"added":     "value",
+		"nested":    map[string]any{"a": float64(1)},
+	}
+
+	assert.Equal(t, 3, countChangedKeys(previous, current))
+	assert.Equal(t, 0, countChangedKeys(current, current))
+	assert.Equal(t, 4, countChangedKeys(nil, current))
+}
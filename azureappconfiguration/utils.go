@@ -4,11 +4,22 @@
 package azureappconfiguration
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"log/slog"
+	"net/url"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Azure/AppConfiguration-GoProvider/azureappconfiguration/internal/tracing"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azappconfig/v2"
 )
 
 func verifyAuthenticationOptions(authOptions AuthenticationOptions) error {
@@ -51,6 +62,47 @@ func verifyOptions(options *Options) error {
 		}
 	}
 
+	for _, endpoint := range options.ReplicaEndpoints {
+		if u, err := url.Parse(endpoint); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("invalid replica endpoint '%s'", endpoint)
+		}
+	}
+
+	if err := verifyTracingOptions(options.TracingOptions); err != nil {
+		return err
+	}
+
+	if options.OperationTimeouts.Request < 0 || options.OperationTimeouts.Refresh < 0 {
+		return fmt.Errorf("operation timeouts cannot be negative")
+	}
+
+	if options.StartupOptions.MaxAttempts < 0 {
+		return fmt.Errorf("startup max attempts cannot be negative")
+	}
+
+	if options.CircuitBreakerOptions.FailureThreshold < 0 {
+		return fmt.Errorf("circuit breaker failure threshold cannot be negative")
+	}
+
+	if options.CircuitBreakerOptions.OpenDuration < 0 {
+		return fmt.Errorf("circuit breaker open duration cannot be negative")
+	}
+
+	for _, domain := range options.TrustedDomains {
+		if strings.Trim(domain, ".") == "" {
+			return fmt.Errorf("trusted domain cannot be empty")
+		}
+	}
+
+	if options.ReplicaDiscoveryOptions.Timeout < 0 {
+		return fmt.Errorf("replica discovery timeout cannot be negative")
+	}
+
+	if options.ReplicaDiscoveryOptions.RefreshInterval != 0 &&
+		options.ReplicaDiscoveryOptions.RefreshInterval < minimalClientRefreshInterval {
+		return fmt.Errorf("replica discovery refresh interval cannot be less than %s", minimalClientRefreshInterval)
+	}
+
 	if options.KeyVaultOptions.RefreshOptions.Enabled {
 		if options.KeyVaultOptions.RefreshOptions.Interval != 0 &&
 			options.KeyVaultOptions.RefreshOptions.Interval < minimalKeyVaultRefreshInterval {
@@ -69,6 +121,34 @@ func verifyOptions(options *Options) error {
 				return fmt.Errorf("feature flag refresh interval cannot be less than %s", minimalRefreshInterval)
 			}
 		}
+
+		switch options.FeatureFlagOptions.ValidationPolicy {
+		case "", FeatureFlagValidationPolicyWarn, FeatureFlagValidationPolicyDrop, FeatureFlagValidationPolicyFail:
+		default:
+			return fmt.Errorf("invalid feature flag validation policy '%s'", options.FeatureFlagOptions.ValidationPolicy)
+		}
+	}
+
+	return nil
+}
+
+func verifyTracingOptions(options TracingOptions) error {
+	if !tracing.IsValidTagValue(options.HostType) {
+		return fmt.Errorf("invalid tracing host type '%s'", options.HostType)
+	}
+
+	for key, value := range options.Tags {
+		if key == "" || !tracing.IsValidTagValue(key) {
+			return fmt.Errorf("invalid tracing tag key '%s'", key)
+		}
+
+		if tracing.IsReservedKey(key) {
+			return fmt.Errorf("tracing tag key '%s' is reserved", key)
+		}
+
+		if !tracing.IsValidTagValue(value) {
+			return fmt.Errorf("invalid value of tracing tag '%s'", key)
+		}
 	}
 
 	return nil
@@ -142,6 +222,129 @@ func verifySeparator(separator string) error {
 	return nil
 }
 
+// normalizeConstructionOptions validates the construction options and fills in the default separator.
+func normalizeConstructionOptions(options *ConstructionOptions) (ConstructionOptions, error) {
+	if options == nil {
+		return ConstructionOptions{Separator: defaultSeparator}, nil
+	}
+
+	result := *options
+	if result.Separator == "" {
+		result.Separator = defaultSeparator
+	} else if err := verifySeparator(result.Separator); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// getLogger returns the logger, or the default logger of the slog package if no logger is configured
+func getLogger(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return slog.Default()
+	}
+
+	return logger
+}
+
+// getSettingLabel returns the label of the setting, which is empty if the setting has no label
+func getSettingLabel(setting azappconfig.Setting) string {
+	if setting.Label == nil {
+		return ""
+	}
+
+	return *setting.Label
+}
+
+// withOptionalTimeout returns a copy of the context with the timeout, or the context itself if the timeout is not positive
+func withOptionalTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
+
+// normalizeDefaults converts the defaults of the application, which are either a hierarchical map or a struct,
+// to a hierarchical map. The fields of a struct are mapped with their json tags as in Unmarshal.
+func normalizeDefaults(defaults any) (map[string]any, error) {
+	if defaults == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(defaults)
+	if err != nil {
+		return nil, err
+	}
+
+	// Numbers are decoded as json.Number, so that integers beyond the precision of float64 are kept
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var normalized map[string]any
+	if err := decoder.Decode(&normalized); err != nil {
+		return nil, fmt.Errorf("defaults must be a map or a struct, got %T", defaults)
+	}
+
+	return normalized, nil
+}
+
+// flattenDefaults flattens the hierarchical defaults to key-values whose keys are joined with the separator
+func flattenDefaults(defaults map[string]any, separator string) map[string]any {
+	flattened := make(map[string]any)
+	var flatten func(prefix string, section map[string]any)
+	flatten = func(prefix string, section map[string]any) {
+		for k, v := range section {
+			key := k
+			if prefix != "" {
+				key = prefix + separator + k
+			}
+
+			if nested, ok := v.(map[string]any); ok && len(nested) > 0 {
+				flatten(key, nested)
+				continue
+			}
+
+			flattened[key] = v
+		}
+	}
+
+	flatten("", defaults)
+	return flattened
+}
+
+// isSensitiveSetting checks if a setting is tagged with "sensitive=true"
+func isSensitiveSetting(setting azappconfig.Setting) bool {
+	value, ok := setting.Tags[sensitiveTagName]
+	return ok && value != nil && strings.EqualFold(strings.TrimSpace(*value), "true")
+}
+
+// jsonErrorMessage returns the message of a JSON parsing error which is safe to log.
+// Syntax errors may quote fragments of the value, so the details are omitted for sensitive settings.
+func jsonErrorMessage(err error, sensitive bool) string {
+	if sensitive {
+		return "invalid JSON, details are omitted for sensitive setting"
+	}
+
+	return err.Error()
+}
+
+// newFeatureFlagDiagnostic creates the diagnostic of an invalid feature flag setting
+func newFeatureFlagDiagnostic(setting azappconfig.Setting, featureID string, violations []string, dropped bool) FeatureFlagDiagnostic {
+	diagnostic := FeatureFlagDiagnostic{
+		FeatureID:  featureID,
+		Violations: violations,
+		Dropped:    dropped,
+	}
+	if setting.Key != nil {
+		diagnostic.Key = *setting.Key
+	}
+	if setting.Label != nil {
+		diagnostic.Label = *setting.Label
+	}
+
+	return diagnostic
+}
+
 func isJsonContentType(contentType *string) bool {
 	if contentType == nil {
 		return false
@@ -182,3 +385,124 @@ func hasProfile(contentType, profileValue string) bool {
 
 	return false
 }
+
+// generateFeatureFlagReference returns the URL of a feature flag setting, which is
+// the origin of the configuration store endpoint followed by the key and the label of the setting
+func generateFeatureFlagReference(endpoint string, setting azappconfig.Setting) string {
+	origin := strings.TrimSuffix(endpoint, "/")
+	if endpointURL, err := url.Parse(endpoint); err == nil && endpointURL.Scheme != "" && endpointURL.Host != "" {
+		origin = endpointURL.Scheme + "://" + endpointURL.Host
+	}
+
+	reference := origin + "/kv/" + *setting.Key
+	if setting.Label != nil && strings.TrimSpace(*setting.Label) != "" {
+		reference += "?label=" + *setting.Label
+	}
+
+	return reference
+}
+
+// generateAllocationID computes a stable ID of the allocation of a feature flag, which only changes
+// when the seed, the allocated percentiles or the allocated variants change.
+// It returns an empty string if the feature flag has no seed and no allocated variants.
+func generateAllocationID(featureFlag map[string]any) string {
+	var rawAllocationID strings.Builder
+	allocatedVariants := make(map[string]struct{})
+
+	allocation, hasAllocation := featureFlag[allocationKeyName].(map[string]any)
+	seed := ""
+	if hasAllocation {
+		seed, _ = allocation[seedKeyName].(string)
+		rawAllocationID.WriteString("seed=" + seed + "\n" + defaultWhenEnabledKey + "=")
+		if defaultWhenEnabled, ok := allocation[defaultWhenEnabledKey].(string); ok && defaultWhenEnabled != "" {
+			allocatedVariants[defaultWhenEnabled] = struct{}{}
+			rawAllocationID.WriteString(defaultWhenEnabled)
+		}
+
+		rawAllocationID.WriteString("\npercentiles=")
+		if percentiles, ok := allocation[percentileKeyName].([]any); ok {
+			type percentileRange struct {
+				from, to float64
+				variant  string
+			}
+			ranges := make([]percentileRange, 0, len(percentiles))
+			for _, percentile := range percentiles {
+				percentileMap, ok := percentile.(map[string]any)
+				if !ok {
+					continue
+				}
+				from, _ := percentileMap[fromKeyName].(float64)
+				to, _ := percentileMap[toKeyName].(float64)
+				variant, _ := percentileMap[variantKeyName].(string)
+				if from == to {
+					continue
+				}
+				ranges = append(ranges, percentileRange{from: from, to: to, variant: variant})
+			}
+			sort.SliceStable(ranges, func(i, j int) bool {
+				return ranges[i].from < ranges[j].from
+			})
+
+			percentileAllocations := make([]string, 0, len(ranges))
+			for _, r := range ranges {
+				allocatedVariants[r.variant] = struct{}{}
+				percentileAllocations = append(percentileAllocations, fmt.Sprintf("%s,%s,%s",
+					strconv.FormatFloat(r.from, 'f', -1, 64),
+					base64.StdEncoding.EncodeToString([]byte(r.variant)),
+					strconv.FormatFloat(r.to, 'f', -1, 64)))
+			}
+			rawAllocationID.WriteString(strings.Join(percentileAllocations, ";"))
+		}
+	}
+
+	if len(allocatedVariants) == 0 && seed == "" {
+		return ""
+	}
+
+	rawAllocationID.WriteString("\n" + variantsKeyName + "=")
+	if variants, ok := featureFlag[variantsKeyName].([]any); ok && len(allocatedVariants) > 0 {
+		type variantConfiguration struct {
+			name, value string
+		}
+		configurations := make([]variantConfiguration, 0, len(variants))
+		for _, variant := range variants {
+			variantMap, ok := variant.(map[string]any)
+			if !ok {
+				continue
+			}
+			name, _ := variantMap[nameKey].(string)
+			if _, allocated := allocatedVariants[name]; name == "" || !allocated {
+				continue
+			}
+			configuration := variantConfiguration{name: name}
+			if value, exists := variantMap[configurationValueKey]; exists {
+				configuration.value = marshalConfigurationValue(value)
+			}
+			configurations = append(configurations, configuration)
+		}
+		sort.SliceStable(configurations, func(i, j int) bool {
+			return configurations[i].name < configurations[j].name
+		})
+
+		variantConfigurations := make([]string, 0, len(configurations))
+		for _, c := range configurations {
+			variantConfigurations = append(variantConfigurations, base64.StdEncoding.EncodeToString([]byte(c.name))+","+c.value)
+		}
+		rawAllocationID.WriteString(strings.Join(variantConfigurations, ";"))
+	}
+
+	hash := sha256.Sum256([]byte(rawAllocationID.String()))
+	return base64.RawURLEncoding.EncodeToString(hash[:15])
+}
+
+// marshalConfigurationValue returns the compact JSON of a variant configuration value with sorted object keys
+func marshalConfigurationValue(value any) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return ""
+	}
+
+	return strings.TrimSuffix(buf.String(), "\n")
+}
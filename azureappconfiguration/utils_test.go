@@ -4,8 +4,12 @@
 package azureappconfiguration
 
 import (
+	"encoding/json"
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azappconfig/v2"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -89,6 +93,44 @@ func TestVerifyOptions(t *testing.T) {
 			},
 			expectedError: true,
 		},
+		{
+			name: "valid tracing options",
+			options: &Options{
+				TracingOptions: TracingOptions{
+					HostType: "Kubernetes",
+					Tags:     map[string]string{"Service": "checkout", "Version": "1.2.0", "Canary": ""},
+				},
+			},
+			expectedError: false,
+		},
+		{
+			name: "tracing host type with delimiter",
+			options: &Options{
+				TracingOptions: TracingOptions{HostType: "Host,Features=x"},
+			},
+			expectedError: true,
+		},
+		{
+			name: "empty tracing tag key",
+			options: &Options{
+				TracingOptions: TracingOptions{Tags: map[string]string{"": "checkout"}},
+			},
+			expectedError: true,
+		},
+		{
+			name: "reserved tracing tag key",
+			options: &Options{
+				TracingOptions: TracingOptions{Tags: map[string]string{"Host": "custom"}},
+			},
+			expectedError: true,
+		},
+		{
+			name: "tracing tag value with delimiter",
+			options: &Options{
+				TracingOptions: TracingOptions{Tags: map[string]string{"Service": "a=b"}},
+			},
+			expectedError: true,
+		},
 	}
 
 	for _, test := range tests {
@@ -714,6 +756,58 @@ func TestVerifyRefreshOptions(t *testing.T) {
 			},
 			expectedError: "feature flag refresh interval cannot be less than",
 		},
+		{
+			name: "invalid feature flag validation policy",
+			options: &Options{
+				FeatureFlagOptions: FeatureFlagOptions{
+					Enabled:          true,
+					ValidationPolicy: "Ignore",
+				},
+			},
+			expectedError: "invalid feature flag validation policy 'Ignore'",
+		},
+		{
+			name: "invalid replica endpoint",
+			options: &Options{
+				ReplicaEndpoints: []string{"contoso-eastus.example.com"},
+			},
+			expectedError: "invalid replica endpoint 'contoso-eastus.example.com'",
+		},
+		{
+			name: "replica discovery refresh interval too short",
+			options: &Options{
+				ReplicaDiscoveryOptions: ReplicaDiscoveryOptions{RefreshInterval: time.Second},
+			},
+			expectedError: "replica discovery refresh interval cannot be less than 30s",
+		},
+		{
+			name: "negative circuit breaker failure threshold",
+			options: &Options{
+				CircuitBreakerOptions: CircuitBreakerOptions{Enabled: true, FailureThreshold: -1},
+			},
+			expectedError: "circuit breaker failure threshold cannot be negative",
+		},
+		{
+			name: "empty trusted domain",
+			options: &Options{
+				TrustedDomains: []string{"."},
+			},
+			expectedError: "trusted domain cannot be empty",
+		},
+		{
+			name: "negative startup max attempts",
+			options: &Options{
+				StartupOptions: StartupOptions{MaxAttempts: -1},
+			},
+			expectedError: "startup max attempts cannot be negative",
+		},
+		{
+			name: "negative operation timeout",
+			options: &Options{
+				OperationTimeouts: OperationTimeouts{Request: -time.Second},
+			},
+			expectedError: "operation timeouts cannot be negative",
+		},
 		{
 			name: "valid feature flag selectors",
 			options: &Options{
@@ -844,7 +938,149 @@ func TestGetFeatureFlagSelectors(t *testing.T) {
 	}
 }
 
+func TestIsSensitiveSetting(t *testing.T) {
+	tests := []struct {
+		name     string
+		tags     map[string]*string
+		expected bool
+	}{
+		{name: "no tags", tags: nil, expected: false},
+		{name: "sensitive true", tags: map[string]*string{"sensitive": strPtr("true")}, expected: true},
+		{name: "sensitive true with different case", tags: map[string]*string{"sensitive": strPtr("TRUE")}, expected: true},
+		{name: "sensitive false", tags: map[string]*string{"sensitive": strPtr("false")}, expected: false},
+		{name: "sensitive nil value", tags: map[string]*string{"sensitive": nil}, expected: false},
+		{name: "other tag", tags: map[string]*string{"env": strPtr("true")}, expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, isSensitiveSetting(azappconfig.Setting{Tags: test.tags}))
+		})
+	}
+}
+
+func TestJsonErrorMessage(t *testing.T) {
+	err := errors.New("invalid character 's' looking for beginning of value")
+
+	assert.Equal(t, err.Error(), jsonErrorMessage(err, false))
+	assert.NotContains(t, jsonErrorMessage(err, true), "'s'")
+}
+
 // Helper function to create string pointers for tests
 func strPtr(s string) *string {
 	return &s
 }
+
+func TestGenerateAllocationID(t *testing.T) {
+	tests := []struct {
+		name        string
+		featureFlag string
+		expected    string
+	}{
+		{
+			name: "seed, default and percentiles",
+			featureFlag: `{"id":"A","allocation":{"seed":"123","default_when_enabled":"Big","percentile":[
+				{"variant":"Small","from":50,"to":100},{"variant":"Big","from":0,"to":50},{"variant":"Off","from":100,"to":100}]},
+				"variants":[{"name":"Small","configuration_value":{"z":1,"a":"<b>","n":[1.5,true]}},
+				{"name":"Big","configuration_value":"300px"},{"name":"Off"}]}`,
+			expected: "zX2UVh67sKfjvQvmgjWG",
+		},
+		{
+			name:        "seed only",
+			featureFlag: `{"id":"B","allocation":{"seed":"abc"}}`,
+			expected:    "DSx_SQuHmJAouZZpGxNq",
+		},
+		{
+			name:        "null configuration value",
+			featureFlag: `{"id":"C","allocation":{"default_when_enabled":"Off"},"variants":[{"name":"Off","configuration_value":null}]}`,
+			expected:    "nzM97aOt_qJpT9sR85zx",
+		},
+		{
+			name:        "no allocation",
+			featureFlag: `{"id":"D","variants":[{"name":"x"}]}`,
+			expected:    "",
+		},
+		{
+			name:        "fractional percentile",
+			featureFlag: `{"id":"E","allocation":{"percentile":[{"variant":"Big","from":0,"to":33.5}]},"variants":[{"name":"Big"}]}`,
+			expected:    "w_3W4a0T2fYIa-mNF_oI",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var featureFlag map[string]any
+			assert.NoError(t, json.Unmarshal([]byte(test.featureFlag), &featureFlag))
+			assert.Equal(t, test.expected, generateAllocationID(featureFlag))
+		})
+	}
+}
+
+func TestGenerateFeatureFlagReference(t *testing.T) {
+	key := ".appconfig.featureflag/Beta"
+	label := "dev"
+	blankLabel := " "
+
+	assert.Equal(t, "https://test.azconfig.io/kv/.appconfig.featureflag/Beta",
+		generateFeatureFlagReference("https://test.azconfig.io/", azappconfig.Setting{Key: &key}))
+	assert.Equal(t, "https://test.azconfig.io/kv/.appconfig.featureflag/Beta?label=dev",
+		generateFeatureFlagReference("https://test.azconfig.io", azappconfig.Setting{Key: &key, Label: &label}))
+	assert.Equal(t, "https://test.azconfig.io/kv/.appconfig.featureflag/Beta",
+		generateFeatureFlagReference("https://test.azconfig.io", azappconfig.Setting{Key: &key, Label: &blankLabel}))
+}
+
+func TestNormalizeDefaults(t *testing.T) {
+	type database struct {
+		Host string `json:"host"`
+		Port int
+	}
+
+	type defaults struct {
+		Timeout  string
+		MaxSize  int64
+		Database database `json:"db"`
+	}
+
+	normalized, err := normalizeDefaults(defaults{Timeout: "10s", MaxSize: 1<<53 + 1, Database: database{Host: "localhost", Port: 5432}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"Timeout": "10s",
+		"MaxSize": json.Number("9007199254740993"),
+		"db":      map[string]any{"host": "localhost", "Port": json.Number("5432")},
+	}, normalized)
+
+	normalized, err = normalizeDefaults(map[string]any{"App": map[string]any{"Name": "checkout"}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"App": map[string]any{"Name": "checkout"}}, normalized)
+
+	normalized, err = normalizeDefaults(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, normalized)
+
+	_, err = normalizeDefaults([]string{"App"})
+	assert.EqualError(t, err, "defaults must be a map or a struct, got []string")
+}
+
+func TestFlattenDefaults(t *testing.T) {
+	defaults := map[string]any{
+		"App": map[string]any{
+			"Name":  "checkout",
+			"Hosts": []any{"a", "b"},
+			"Db": map[string]any{
+				"Port": float64(5432),
+			},
+			"Tags": map[string]any{},
+		},
+		"Debug": false,
+	}
+
+	assert.Equal(t, map[string]any{
+		"App:Name":    "checkout",
+		"App:Hosts":   []any{"a", "b"},
+		"App:Db:Port": float64(5432),
+		"App:Tags":    map[string]any{},
+		"Debug":       false,
+	}, flattenDefaults(defaults, ":"))
+
+	assert.Empty(t, flattenDefaults(nil, "."))
+}